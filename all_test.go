@@ -17,11 +17,13 @@
 package main
 
 import (
+	"bytes"
 	"go/ast"
 	"go/constant"
 	"go/token"
 	"math/big"
 	r "reflect"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -76,6 +78,40 @@ func TestFast(t *testing.T) {
 	}
 }
 
+// ReplCase evaluates each line of input as the REPL does, including REPL commands,
+// and compares the printed output. If check is not nil, it is used instead of comparing
+type ReplCase struct {
+	name   string
+	input  string
+	output string
+	check  func(output string) bool
+}
+
+func TestFastRepl(t *testing.T) {
+	ir := fast.New()
+	var buf bytes.Buffer
+	g := &ir.Comp.Globals
+	g.Stdout, g.Stderr = &buf, &buf
+	g.Options |= OptShowEval | OptShowEvalType
+	for _, test := range replcases {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			buf.Reset()
+			for _, line := range strings.Split(test.input, "\n") {
+				ir.ParseEvalPrint(line)
+			}
+			actual := buf.String()
+			if test.check != nil {
+				if !test.check(actual) {
+					t.Errorf("unexpected output %q", actual)
+				}
+			} else if actual != test.output {
+				t.Errorf("expecting output %q, found %q", test.output, actual)
+			}
+		})
+	}
+}
+
 type shouldpanic struct{}
 
 func (shouldpanic) String() string {
@@ -788,6 +824,9 @@ var testcases = []TestCase{
 	TestCase{F, "multiple_values_6", `fmt.Sprintf("foo")`, "foo", nil},
 	TestCase{A, "multiple_values_7", `func args() (string, interface{}, interface{}) { return "%v %v", 5, 6 }; nil`, nil, nil},
 	TestCase{A, "multiple_values_8", `fmt.Sprintf(args())`, "5 6", nil},
+	TestCase{F, "multiple_values_9", "var f3 float32; _, f3 = twins(7.0); f3", float32(8.0), nil},
+	TestCase{F, "multiple_values_10", "f4, f5 := twins(9.0); f4, _ = f5, f4; f4", float32(10.0), nil},
+	TestCase{F, "multiple_values_11", "fm[1], _, fm[2] = f5, f4, f5; fm", map[int]float32{1: 10.0, 2: 10.0}, nil},
 
 	TestCase{A, "pred_bool_1", "false==false && true==true && true!=false", true, nil},
 	TestCase{A, "pred_bool_2", "false!=false || true!=true || true==false", false, nil},
@@ -989,6 +1028,14 @@ var testcases = []TestCase{
 	TestCase{A, "macro", "~macro second_arg(a,b,c interface{}) interface{} { return b }; v = 98; v", uint32(98), nil},
 	TestCase{A, "macro_call", "second_arg;1;v;3", uint32(98), nil},
 	TestCase{A, "macro_nested", "second_arg;1;{second_arg;2;3;4};5", 3, nil},
+	TestCase{F, "macro_expansion_1", `func count_args(a ...int) int { return len(a) }
+		~macro call_count_args() interface{} { return ~quote{count_args(5)} }
+		~macro decl_type() interface{} { return ~quote{type DeclType int} }`, nil, none},
+	TestCase{F, "macro_expansion_call", `call_count_args`, 1, nil},
+	TestCase{F, "macro_expansion_type", `decl_type; func (DeclType) String() string { return "" }; DeclType(7)`, 7, nil},
+	TestCase{F, "macro_splice_1", `~macro decl_pair() interface{} { return ~quote{var splice_a = 1; var splice_b = splice_a + 1}.List }`, nil, none},
+	TestCase{F, "macro_splice_2", `decl_pair; splice_a + splice_b`, 3, nil},
+	TestCase{F, "macro_splice_3", `func splice_f() int { decl_pair; return splice_b }; splice_f()`, 2, nil},
 	TestCase{C, "values", "Values(3,4,5)", nil, []interface{}{3, 4, 5}},
 	TestCase{A, "eval", "Eval(~quote{1+2})", 3, nil},
 	TestCase{C, "eval_quote", "Eval(~quote{Values(3,4,5)})", nil, []interface{}{3, 4, 5}},
@@ -1181,6 +1228,115 @@ var testcases = []TestCase{
 		template[] for[1] type Fib [1]int
 		template[] for[0] type Fib [0]int
 		const Fib30 = len((*Fib#[30])(nil)); Fib30`, 832040, nil},
+
+	TestCase{F, "const_func_1", `const func sq(n int) int { return n * n }; const S = sq(4); S`, 16, nil},
+	TestCase{F, "const_func_2", `x := 5; sq(x)`, 25, nil},
+	TestCase{F, "const_func_recursive", `const func fib(n int) int { if n < 2 { return n }; return fib(n-1) + fib(n-2) }; const F = fib(20); F`, 6765, nil},
+	TestCase{F, "const_func_array_len", `var arr [sq(3)]int; arr`, [9]int{}, nil},
+	TestCase{F, "const_func_template_arg", `template[T,N] type Vec [N]T; var vec Vec#[int, sq(2)]; vec`, [4]int{}, nil},
+	TestCase{F, "const_func_print", `const func bad() int { println(1); return 0 }`, panics, nil},
+	TestCase{F, "const_func_var", `var cfv int; const func bad() int { return cfv }`, panics, nil},
+	TestCase{F, "const_func_chan", `const func bad(ch int) int { c := make(chan int, 1); c <- ch; return ch }`, panics, nil},
+	TestCase{F, "const_func_go", `const func bad() int { go sq(1); return 0 }`, panics, nil},
+	TestCase{F, "const_func_package", `import "os"; const func bad() string { return os.Getenv("HOME") }`, panics, nil},
+	TestCase{F, "const_func_call", `func notconst() int { return 1 }; const func bad() int { return notconst() }`, panics, nil},
+	TestCase{F, "const_func_loop_forever", `const func bad(n int) int { for { n++ }; return n }`, panics, nil},
+	TestCase{F, "const_func_loop_1", `const func loop(n int) int { for n > 0 { n++ }; return n }; loop(-1)`, -1, nil},
+	TestCase{F, "const_func_loop_2", `const L = loop(1)`, panics, nil},
+	TestCase{F, "const_func_goto", `const func again(n int) int { start: n++; goto start }; const A = again(1)`, panics, nil},
+	TestCase{F, "const_func_deep", `const func deep(n int) int { if n < 0 { return 0 }; return deep(n+1) }; const D = deep(1)`, panics, nil},
+	TestCase{F, "const_func_after_deep", `const S2 = sq(6); S2`, 36, nil},
+
+	TestCase{F, "defsyntax_swap_1", `~defsyntax swap { ~pattern { $a, $b } => { $a, $b = $b, $a } }`, nil, none},
+	TestCase{F, "defsyntax_swap_2", `sa, sb := 1, 2; swap; sa; sb; sa`, 2, nil},
+	TestCase{F, "defsyntax_swap_mismatch", `swap; { sa }; sb`, panics, nil},
+	TestCase{F, "defsyntax_type_1", `~defsyntax zero { ~pattern { $t:type } => { *new($t) } }`, nil, none},
+	TestCase{F, "defsyntax_type_2", `zero; float32`, float32(0), nil},
+	TestCase{F, "defsyntax_repeat_1", `~defsyntax first { ~pattern { list($x, $rest...) } => { $x } }`, nil, none},
+	TestCase{F, "defsyntax_repeat_2", `first; list(int8(7), 8, 9)`, int8(7), nil},
+	TestCase{F, "defsyntax_block_1", `~defsyntax twice { ~pattern { { $body:stmt... } } => { $body...; $body... } }`, nil, none},
+	TestCase{F, "defsyntax_block_2", `tw := 0; twice; { tw++ }; tw`, 2, nil},
+	TestCase{F, "defsyntax_duplicate", `~defsyntax bad { ~pattern { $a, $a } => { $a } }`, panics, nil},
+	TestCase{F, "defsyntax_arrow", `~defsyntax bad { ~pattern { $a } = > { $a } }`, panics, nil},
+	TestCase{F, "defsyntax_dollar", `$x := 1`, panics, nil},
+
+	TestCase{F, "macros_library", `import ( "fmt"; "strconv"; "strings"; "time" ); import ~"github.com/cosmos72/gomacro/macros"`, nil, none},
+	TestCase{F, "macros_recovered", `func recovered(f func()) (msg string) { defer func() { msg = fmt.Sprint(recover()) }(); f(); return }`, nil, none},
+	TestCase{F, "macros_when", `v := 0; when; v == 0; { v = 1 }; unless; v == 1; { v = 2 }; v`, 1, nil},
+	TestCase{F, "macros_when_mismatch", `strings.Contains(recovered(func() { Eval(Parse("when; v == 1; 3")) }), "no pattern matches")`, true, nil},
+	TestCase{F, "macros_unless_mismatch", `strings.Contains(recovered(func() { Eval(Parse("unless; v == 1; 3")) }), "no pattern matches")`, true, nil},
+	TestCase{F, "macros_assert", `recovered(func() { assert; v == 1 }) + ", " + recovered(func() { assert; v == 2 })`, "<nil>, assertion failed: v == 2", nil},
+	TestCase{F, "macros_assert_mismatch", `recovered(func() { Eval(Parse("assert; {}")) })`, "assert: invalid argument { }, expecting a boolean expression", nil},
+	TestCase{F, "macros_timeit", `timeit; v = 5; v`, 5, nil},
+	TestCase{F, "macros_timeit_mismatch", `strings.Contains(recovered(func() { Eval(Parse("timeit")) }), "not enough arguments")`, true, nil},
+	TestCase{F, "macros_must", `must; v = strconv.Atoi("42"); v`, 42, nil},
+	TestCase{F, "macros_must_mismatch", `recovered(func() { Eval(Parse("must; v + 1")) })`,
+		"must: invalid argument v + 1, expecting a function call or an assignment X, Y := CALL", nil},
+	TestCase{F, "macros_try", `func macros_try(s string) (n int, err error) { defer_err; "macros_try"; try; n = strconv.Atoi(s); return }
+		_, e := macros_try("x"); e.Error()`, `macros_try: strconv.Atoi: parsing "x": invalid syntax`, nil},
+	TestCase{F, "macros_try_mismatch", `recovered(func() { Eval(Parse("try; v += 1")) })`,
+		"try: invalid argument v += 1, expecting an assignment X, Y := CALL or X, Y = CALL", nil},
+	TestCase{F, "macros_defer_err_mismatch", `recovered(func() { Eval(Parse("defer_err; {}")) })`,
+		"defer_err: invalid argument { }, expecting a string expression", nil},
+	TestCase{F, "macros_enum", `enum; Suit; { Spades; Hearts }; s, _ := ParseSuit("Hearts"); s == Hearts && int(Hearts) == 1`, true, nil},
+	TestCase{F, "macros_enum_mismatch", `recovered(func() { Eval(Parse("enum; Color; { 1 }")) })`, "enum: invalid argument 1, expecting an identifier", nil},
+	TestCase{F, "macros_foreach", `v = 0; foreach; x; []int{1, 2, 3}; { v = v*10 + x }; v`, 123, nil},
+	TestCase{F, "macros_foreach_mismatch", `recovered(func() { Eval(Parse("foreach; 1; {}; {}")) })`, "foreach: invalid argument 1, expecting an identifier", nil},
+}
+
+var replcases = []ReplCase{
+	ReplCase{"forget_func", "func forget_f() int { return 1 }\nfunc forget_g() int { return forget_f() }\n:forget forget_f\nforget_g()",
+		"function forget_f was forgotten\n", nil},
+	ReplCase{"forget_var", "var forget_v = []int{1}\nfunc forget_h() []int { return forget_v }\n:forget forget_v\nforget_h() == nil",
+		"true\t// bool\n", nil},
+	ReplCase{"forget_missing", ":forget forget_missing", "// not found: forget_missing\n", nil},
+	// macroexpansion keeps the source positions of subtrees without macro calls:
+	// :source shows the original text of the whole function, which is available if Debugger is set
+	ReplCase{"source_original", ":options Debugger\nfunc source_f(a int) int { return a  *  2 /* double */ }\n:source source_f\n:options Debugger",
+		"func source_f(a int) int { return a  *  2 /* double */ }\n", nil},
+	// the result history does not overwrite variables declared by the user
+	ReplCase{"history_user_var", "var __ = 5\n1 + 1\n__\n:forget __\n3\n__",
+		"// warning: redefined identifier: __\n2\t// int\n// warning: not keeping results in __: name already declared\n5\t// int\n3\t// int\n3\t// int\n", nil},
+	ReplCase{"forget_redeclared", "func undo_f() int { return 1 }\nfunc undo_g() int { return undo_f() }\nfunc undo_f() int { return 2 }\nundo_g()",
+		"// warning: redefined identifier: undo_f\n2\t// int\n", nil},
+	ReplCase{"undo_func", ":undo\nundo_g()", "// undone: undo_f\n1\t// int\n", nil},
+	ReplCase{"undo_var", "undo_x := 7\nundo_x := \"s\"\n:undo\nundo_x", "// warning: redefined identifier: undo_x\n// undone: undo_x\n7\t// int\n", nil},
+	ReplCase{"undo_new", ":undo\nundo_x", "// undone: undo_x\nrepl.go:1:1: undefined identifier: undo_x\n", nil},
+	ReplCase{"undo_ints", "undo_i := 5\nundo_i = 6\nundo_i := 9\n:undo\nundo_i", "// warning: redefined identifier: undo_i\n// undone: undo_i\n6\t// int\n", nil},
+	ReplCase{"undo_vals", "undo_s := []int{1}\nundo_s := []int{2, 3}\n:undo\nundo_s", "// warning: redefined identifier: undo_s\n// undone: undo_s\n[1]\t// []int\n", nil},
+	// golden output of each macroexpansion step
+	ReplCase{"macrostep_define", "~macro mstep_inc(x interface{}) interface{} { return ~\"{~,x + 1} }\n~macro mstep_pair(x interface{}) interface{} { return ~\"{mstep_inc; ~,x; mstep_inc; 2 * ~,x} }", "", nil},
+	ReplCase{"macroexpand1", ":macroexpand1 mstep_pair; 5", "// step 1\n{\n\tmstep_inc\n\t5\n\tmstep_inc\n\t2 * 5\n}\n", nil},
+	ReplCase{"macroexpand", ":macroexpand mstep_inc; 5", "// step 1\n5 + 1\n", nil},
+	ReplCase{"macroexpand_all", ":macroexpand-all mstep_pair; 5",
+		"// step 1\n{\n\tmstep_inc\n\t5\n\tmstep_inc\n\t2 * 5\n}\n// step 2\n{\n\t5 + 1\n\t2*5 + 1\n}\n", nil},
+	ReplCase{"macroexpand_diff", ":macroexpand-all -diff mstep_pair; 5",
+		"--- step 0\n+++ step 1\n@@ -1,2 +1,6 @@\n-mstep_pair\n-5\n+{\n+\tmstep_inc\n+\t5\n+\tmstep_inc\n+\t2 * 5\n+}\n" +
+			"--- step 1\n+++ step 2\n@@ -1,6 +1,4 @@\n {\n-\tmstep_inc\n-\t5\n-\tmstep_inc\n-\t2 * 5\n+\t5 + 1\n+\t2*5 + 1\n }\n", nil},
+	ReplCase{"macroexpand_nothing", ":macroexpand1 1 + 2", "// macroexpand1: nothing to expand\n", nil},
+	ReplCase{"hygiene_on", ":options Macro.Hygiene\nvar hyg_n = 3\nfunc hyg_f() int { return hyg_n * 2 }\n~macro hyg_m() interface{} { return ~\"{ return hyg_f() + hyg_n } }", "", nil},
+	// the template refers to the globals hyg_f and hyg_n, even if the caller shadows them
+	ReplCase{"hygiene_shadow", "func hyg_g() int { hyg_f, hyg_n := 1, 2; _, _ = hyg_f, hyg_n; hyg_m }\nhyg_g()", "9\t// int\n", nil},
+	ReplCase{"hygiene_update", "hyg_n = 4\nhyg_g()", "12\t// int\n", nil},
+	// the expansion of enum uses the library's import "fmt": the caller did not import it
+	ReplCase{"hygiene_library", "import ~\"github.com/cosmos72/gomacro/macros\"\nenum; Hue; { Red; Green }\n_, hyg_e := ParseHue(\"blue\"); hyg_e.Error()",
+		"invalid Hue: \"blue\"\t// string\n", nil},
+	ReplCase{"hygiene_env", ":env", "", func(out string) bool {
+		return strings.Contains(out, "ParseHue") && !strings.Contains(out, StrGensym)
+	}},
+	ReplCase{"dump_struct", "type dump_T struct { A int; B []string }\ndump_v := dump_T{1, []string{\"x\"}}\n:dump dump_v",
+		"dump_T{\n\tA: 1,\n\tB: []string{\"x\"},\n}\n", nil},
+	ReplCase{"dump_map", ":dump map[string]int{\"k\": 2}", "map[string]int{\n\t\"k\": 2,\n}\n", nil},
+	// tracing does not require recompiling the traced functions.
+	// indentation depends on the call depth of the REPL, which depends on the previous tests: ignore it
+	ReplCase{"trace_declared_before", "func trace_a(n int) int { return n + 1 }\n:trace trace_a\ntrace_a(1)\n:untrace trace_a\ntrace_a(2)", "", func(out string) bool {
+		out = strings.Replace(out, "  ", "", -1)
+		return strings.HasPrefix(out, "-> main.trace_a(n=1)\n<- main.trace_a = 2 [") && strings.HasSuffix(out, "]\n2\t// int\n3\t// int\n")
+	}},
+	ReplCase{"trace_declared_after", ":trace trace_b\nfunc trace_b() int { return 2 }\ntrace_b()\n:untrace trace_b", "", func(out string) bool {
+		out = strings.Replace(out, "  ", "", -1)
+		return strings.HasPrefix(out, "-> main.trace_b()\n<- main.trace_b = 2 [") && strings.HasSuffix(out, "]\n2\t// int\n")
+	}},
 }
 
 func (c *TestCase) compareResults(t *testing.T, actual []r.Value) {
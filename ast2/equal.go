@@ -0,0 +1,144 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * equal.go
+ *
+ *  Created on Oct 19, 2026
+ *      Author agent
+ */
+
+package ast2
+
+import (
+	"go/token"
+	"hash/fnv"
+	"io"
+	r "reflect"
+	"strconv"
+)
+
+// Clone returns a deep copy of form.
+// Source positions are copied too
+func Clone(form Ast) Ast {
+	return copyAst(form, nil)
+}
+
+// Equal returns true if a and b have the same structure, ignoring source positions and comments.
+// A missing list is equal to an empty one
+func Equal(a, b Ast) bool {
+	m := matcher{}
+	return m.match(a, b)
+}
+
+// Hash returns a hash of form that ignores source positions and comments:
+// if Equal(a, b) then Hash(a) == Hash(b)
+func Hash(form Ast) uint64 {
+	h := fnv.New64a()
+	hashAst(h, form)
+	return h.Sum64()
+}
+
+// deep copy form. if b is not nil, replace wildcards with their bindings
+func copyAst(form Ast, b Bindings) Ast {
+	if isNilAst(form) {
+		return form
+	}
+	if b != nil {
+		if w := wildcardOf(form); w != nil {
+			if w.repeat {
+				errorf("repeated wildcard $%s... in template must be an element of a list", w.name)
+			}
+			return Clone(b.lookup(w))
+		}
+	}
+	out := form.New()
+	n := form.Size()
+	if list, ok := out.(AstWithSlice); ok {
+		for i := 0; i < n; i++ {
+			child := form.Get(i)
+			if b != nil {
+				if w := wildcardOf(child); w != nil && w.repeat {
+					elts := b.lookup(w).(AstSlice)
+					for _, elt := range elts.X {
+						list = list.Append(Clone(elt))
+					}
+					continue
+				}
+			}
+			list = list.Append(copyAst(child, b))
+		}
+		return list
+	}
+	for i := 0; i < n; i++ {
+		out.Set(i, copyAst(form.Get(i), b))
+	}
+	return out
+}
+
+func hashAst(h io.Writer, form Ast) {
+	if isNilAst(form) || (isList(form) && form.Size() == 0) {
+		// a missing list is equal to an empty one
+		h.Write([]byte{0})
+		return
+	}
+	n := form.Size()
+	io.WriteString(h, r.TypeOf(form).Name())
+	io.WriteString(h, strconv.Itoa(int(form.Op())))
+	io.WriteString(h, attrs(form))
+	io.WriteString(h, strconv.Itoa(n))
+	for i := 0; i < n; i++ {
+		hashAst(h, form.Get(i))
+	}
+}
+
+// return the attributes of form that are neither children nor source positions
+func attrs(form Ast) string {
+	switch form := form.(type) {
+	case Ident:
+		return form.X.Name
+	case BasicLit:
+		return form.X.Value
+	case CallExpr:
+		if form.X.Ellipsis.IsValid() {
+			return "..."
+		}
+	case ChanType:
+		return strconv.Itoa(int(form.X.Dir))
+	case RangeStmt:
+		if form.X.Tok != token.ILLEGAL {
+			return form.X.Tok.String()
+		}
+	case File:
+		if form.X.Name != nil {
+			return form.X.Name.Name
+		}
+	case Package:
+		return form.X.Name
+	}
+	return ""
+}
+
+// return true if a and b have the same type, operator and attributes
+func sameNode(a, b Ast) bool {
+	return r.TypeOf(a) == r.TypeOf(b) && a.Op() == b.Op() && attrs(a) == attrs(b)
+}
+
+func isNilAst(form Ast) bool {
+	return form == nil || form.Interface() == nil
+}
+
+// return true if form is a list, as []ast.Expr or []ast.Stmt, but not a node as *ast.BlockStmt
+func isList(form Ast) bool {
+	if _, ok := form.(AstWithNode); ok || form == nil {
+		return false
+	}
+	_, ok := form.(AstWithSlice)
+	return ok
+}
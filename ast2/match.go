@@ -0,0 +1,436 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * match.go
+ *
+ *  Created on Oct 19, 2026
+ *      Author agent
+ */
+
+package ast2
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/printer"
+	"go/token"
+	"strings"
+
+	"github.com/cosmos72/gomacro/parser"
+	mt "github.com/cosmos72/gomacro/token"
+)
+
+// Structural pattern matching and rewriting.
+//
+// A pattern is any Ast, usually obtained by parsing source code with ParsePattern.
+// It can contain wildcards, i.e. identifiers starting with '$' that only ParsePattern accepts:
+//
+//	$name         any node
+//	$name:expr    an expression
+//	$name:ident   an identifier
+//	$name:type    a type
+//	$name:stmt    a statement
+//	$name...      zero or more elements of a list: call arguments, statements of a block...
+//	$name:kind... zero or more elements of a list, each of the given kind
+//	$_            any node, without binding it
+//
+// The other nodes in the pattern must match exactly, ignoring source positions and comments.
+// A wildcard that appears multiple times must match equal nodes each time.
+
+// Bindings maps the name of each wildcard, without '$', to the node it matched.
+// A repeated wildcard $name... is bound to an AstSlice containing the elements it matched
+type Bindings map[string]Ast
+
+// Rule rewrites the nodes that match Pattern into Template,
+// where each wildcard is replaced by the node it matched
+type Rule struct {
+	Pattern  Ast
+	Template Ast
+}
+
+type wildcardKind uint8
+
+const (
+	wildcardAny wildcardKind = iota
+	wildcardExpr
+	wildcardIdent
+	wildcardType
+	wildcardStmt
+)
+
+var wildcardKinds = map[string]wildcardKind{
+	"expr":  wildcardExpr,
+	"ident": wildcardIdent,
+	"type":  wildcardType,
+	"stmt":  wildcardStmt,
+}
+
+var wildcardKindDescs = [...]string{
+	wildcardAny:   "a node",
+	wildcardExpr:  "an expression",
+	wildcardIdent: "an identifier",
+	wildcardType:  "a type",
+	wildcardStmt:  "a statement",
+}
+
+type wildcard struct {
+	text   string // as written in source code
+	name   string // without '$', ':kind' and '...'
+	kind   wildcardKind
+	repeat bool
+}
+
+// ParsePattern parses src and returns the pattern it contains.
+// If src contains multiple declarations, statements or expressions, they are returned as a NodeSlice
+func ParsePattern(src string) (Ast, error) {
+	var p parser.Parser
+	p.Configure(parser.ParseMetaVars, '~')
+	p.Init(mt.NewFileSet(), "pattern", 0, []byte(src))
+	nodes, err := p.Parse()
+	if err != nil {
+		return nil, err
+	}
+	switch len(nodes) {
+	case 0:
+		return nil, errors.New("empty pattern")
+	case 1:
+		return ToAst(nodes[0]), nil
+	default:
+		return NodeSlice{nodes}, nil
+	}
+}
+
+// Match returns true and the wildcard bindings if form matches pattern
+func Match(pattern, form Ast) (Bindings, bool) {
+	m := matcher{b: make(Bindings), wild: true}
+	if !m.match(pattern, form) {
+		return nil, false
+	}
+	return m.b, true
+}
+
+// Matcher is a configurable version of Match, that also describes why a form does not match
+type Matcher struct {
+	// Default is the kind of wildcards written without ':kind'.
+	// It can be "" for any node, or one of: "expr" "ident" "type" "stmt"
+	Default string
+	// Text formats the nodes shown in mismatch descriptions. If nil, go/printer is used
+	Text func(ast.Node) string
+}
+
+// Match returns the wildcard bindings if form matches pattern,
+// otherwise an error describing the first mismatch found
+func (mm *Matcher) Match(pattern, form Ast) (Bindings, error) {
+	m := matcher{b: make(Bindings), wild: true, explain: true, text: mm.Text}
+	if mm.Default != "" {
+		kind, ok := wildcardKinds[mm.Default]
+		if !ok {
+			errorf("invalid Matcher.Default %q, expecting one of: expr ident type stmt", mm.Default)
+		}
+		m.def = kind
+	}
+	if !m.match(pattern, form) {
+		if m.reason == "" {
+			m.reason = "form does not match pattern"
+		}
+		return nil, errors.New(m.reason)
+	}
+	return m.b, nil
+}
+
+// Substitute returns a copy of template where each wildcard is replaced by a copy of the node bound to it.
+// A repeated wildcard $name... must be an element of a list, and it is replaced by the elements bound to it.
+// Panics if template contains a wildcard not present in b
+func Substitute(template Ast, b Bindings) Ast {
+	if b == nil {
+		b = Bindings{}
+	}
+	return copyAst(template, b)
+}
+
+// Rewrite visits form in post-order and replaces each node that matches a rule
+// with the substituted template of the first such rule. Replacement nodes are not visited again.
+// The tree is modified in place: use Clone to preserve the original.
+// Returns the rewritten form and true if at least one rule matched
+func Rewrite(form Ast, rules ...Rule) (Ast, bool) {
+	rewritten := false
+	form = PostOrder(form, func(node Ast) Ast {
+		for _, rule := range rules {
+			if b, ok := Match(rule.Pattern, node); ok {
+				rewritten = true
+				return Substitute(rule.Template, b)
+			}
+		}
+		return node
+	})
+	return form, rewritten
+}
+
+// return the node bound to wildcard w
+func (b Bindings) lookup(w *wildcard) Ast {
+	form, ok := b[w.name]
+	if !ok {
+		errorf("unbound wildcard $%s in template", w.name)
+	}
+	if _, list := form.(AstSlice); list != w.repeat {
+		if w.repeat {
+			errorf("wildcard $%s... in template is not repeated in pattern", w.name)
+		}
+		errorf("wildcard $%s in template is repeated in pattern, expecting $%s...", w.name, w.name)
+	}
+	return form
+}
+
+// parse a wildcard $name, $name:kind, $name... or $name:kind...
+// return nil if form is not a wildcard. form may be wrapped in an *ast.ExprStmt
+func wildcardOf(form Ast) *wildcard {
+	form = unwrapExprStmt(form)
+	ident, ok := form.(Ident)
+	if !ok || ident.X == nil {
+		return nil
+	}
+	s := ident.X.Name
+	if len(s) < 2 || s[0] != '$' {
+		return nil
+	}
+	w := &wildcard{text: s}
+	if strings.HasSuffix(s, "...") {
+		w.repeat = true
+		s = s[:len(s)-3]
+	}
+	if i := strings.IndexByte(s, ':'); i >= 0 {
+		kind, ok := wildcardKinds[s[i+1:]]
+		if !ok {
+			errorf("invalid wildcard %s: unknown kind %q, expecting one of: expr ident type stmt", ident.X.Name, s[i+1:])
+		}
+		w.kind = kind
+		s = s[:i]
+	}
+	w.name = s[1:]
+	return w
+}
+
+// an expression pattern matches an expression wrapped in *ast.ExprStmt, and vice-versa
+func unwrapExprStmt(form Ast) Ast {
+	if stmt, ok := form.(ExprStmt); ok && stmt.X != nil {
+		return stmt.Get(0)
+	}
+	return form
+}
+
+// matcher matches a pattern against a form. if wild is false, wildcards are ordinary identifiers
+type matcher struct {
+	b       Bindings
+	wild    bool
+	def     wildcardKind // kind of wildcards written without ':kind'
+	explain bool         // if true, store in reason why the match failed
+	reason  string
+	text    func(ast.Node) string
+}
+
+// record why the match failed. always returns false
+func (m *matcher) fail(format string, args ...interface{}) bool {
+	if m.explain && m.reason == "" {
+		m.reason = fmt.Sprintf(format, args...)
+	}
+	return false
+}
+
+// return the source code of form, for mismatch descriptions
+func (m *matcher) textOf(form Ast) string {
+	if isNilAst(form) {
+		return "nothing"
+	}
+	if form, ok := form.(AstWithNode); ok {
+		if m.text != nil {
+			return "`" + m.text(form.Node()) + "`"
+		}
+		var buf bytes.Buffer
+		printer.Fprint(&buf, token.NewFileSet(), form.Node())
+		return "`" + buf.String() + "`"
+	}
+	n := form.Size()
+	texts := make([]string, n)
+	for i := 0; i < n; i++ {
+		texts[i] = m.textOf(form.Get(i))
+	}
+	return "[" + strings.Join(texts, ", ") + "]"
+}
+
+// return the wildcard in form, or nil if form is not a wildcard
+func (m *matcher) wildcardOf(form Ast) *wildcard {
+	if !m.wild {
+		return nil
+	}
+	w := wildcardOf(form)
+	if w != nil && w.kind == wildcardAny {
+		w.kind = m.def
+	}
+	return w
+}
+
+func (m *matcher) match(pat, form Ast) bool {
+	if m.wild {
+		if w := m.wildcardOf(pat); w != nil && !w.repeat {
+			return m.bind(w, form)
+		}
+		pat, form = unwrapExprStmt(pat), unwrapExprStmt(form)
+	}
+	pnil, fnil := isNilAst(pat), isNilAst(form)
+	if pnil || fnil {
+		// a missing list is equivalent to an empty one
+		switch {
+		case pnil && fnil:
+			return true
+		case pnil:
+			if isList(form) && form.Size() == 0 {
+				return true
+			}
+		default:
+			if isList(pat) {
+				return m.matchList(pat.(AstWithSlice), nil)
+			}
+		}
+		return m.fail("expecting %s, found %s", m.textOf(pat), m.textOf(form))
+	}
+	if !sameNode(pat, form) {
+		return m.fail("expecting %s, found %s", m.textOf(pat), m.textOf(form))
+	}
+	if list, ok := pat.(AstWithSlice); ok {
+		return m.matchList(list, form)
+	}
+	for i, n := 0, pat.Size(); i < n; i++ {
+		if !m.match(pat.Get(i), form.Get(i)) {
+			return false
+		}
+	}
+	return true
+}
+
+// match a list that may contain one repeated wildcard
+func (m *matcher) matchList(pat AstWithSlice, form Ast) bool {
+	np, nf := pat.Size(), 0
+	if form != nil {
+		nf = form.Size()
+	}
+	rep := -1
+	var w *wildcard
+	for i := 0; i < np; i++ {
+		if w = m.wildcardOf(pat.Get(i)); w != nil && w.repeat {
+			rep = i
+			break
+		}
+	}
+	if rep < 0 {
+		if np != nf {
+			return m.fail("expecting %d elements %s, found %d elements %s", np, m.textOf(pat), nf, m.textOf(form))
+		}
+		for i := 0; i < np; i++ {
+			if !m.match(pat.Get(i), form.Get(i)) {
+				return false
+			}
+		}
+		return true
+	}
+	if nf < np-1 {
+		return m.fail("expecting at least %d elements %s, found %d elements %s", np-1, m.textOf(pat), nf, m.textOf(form))
+	}
+	nafter := np - rep - 1
+	for i := 0; i < rep; i++ {
+		if !m.match(pat.Get(i), form.Get(i)) {
+			return false
+		}
+	}
+	for i := 0; i < nafter; i++ {
+		if !m.match(pat.Get(rep+1+i), form.Get(nf-nafter+i)) {
+			return false
+		}
+	}
+	list := make([]Ast, 0, nf-np+1)
+	for i := rep; i < nf-nafter; i++ {
+		elt, ok := m.check(w, form.Get(i))
+		if !ok {
+			return false
+		}
+		list = append(list, elt)
+	}
+	return m.store(w, AstSlice{list})
+}
+
+// bind wildcard w to form
+func (m *matcher) bind(w *wildcard, form Ast) bool {
+	form, ok := m.check(w, form)
+	return ok && m.store(w, form)
+}
+
+// store the binding of wildcard w. if w is already bound, the new binding must be equal to the old one
+func (m *matcher) store(w *wildcard, form Ast) bool {
+	if w.name == "_" {
+		return true
+	}
+	if old, ok := m.b[w.name]; ok {
+		if !Equal(old, form) {
+			return m.fail("expecting %s equal to %s, found %s", w.text, m.textOf(old), m.textOf(form))
+		}
+		return true
+	}
+	m.b[w.name] = form
+	return true
+}
+
+// check that form has the kind required by wildcard w, and return the node to bind to w
+func (m *matcher) check(w *wildcard, form Ast) (Ast, bool) {
+	if isNilAst(form) {
+		return nil, m.fail("expecting %s for %s, found nothing", wildcardKindDescs[w.kind], w.text)
+	}
+	if w.kind != wildcardStmt {
+		form = unwrapExprStmt(form)
+	}
+	var node ast.Node
+	if form, ok := form.(AstWithNode); ok {
+		node = form.Node()
+	}
+	var ok bool
+	switch w.kind {
+	case wildcardAny:
+		ok = node != nil
+	case wildcardExpr:
+		_, ok = node.(ast.Expr)
+	case wildcardIdent:
+		_, ok = node.(*ast.Ident)
+	case wildcardType:
+		ok = isTypeExpr(node)
+	case wildcardStmt:
+		_, ok = node.(ast.Stmt)
+	}
+	if !ok {
+		return nil, m.fail("expecting %s for %s, found %s", wildcardKindDescs[w.kind], w.text, m.textOf(form))
+	}
+	return form, true
+}
+
+// return true if node is syntactically a type
+func isTypeExpr(node ast.Node) bool {
+	switch node := node.(type) {
+	case *ast.Ident, *ast.SelectorExpr, *ast.ArrayType, *ast.ChanType,
+		*ast.FuncType, *ast.InterfaceType, *ast.MapType, *ast.StructType:
+		return true
+	case *ast.StarExpr:
+		return isTypeExpr(node.X)
+	case *ast.ParenExpr:
+		return isTypeExpr(node.X)
+	case *ast.IndexExpr:
+		// template type instantiation
+		return isTypeExpr(node.X)
+	}
+	return false
+}
@@ -0,0 +1,209 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * match_test.go
+ *
+ *  Created on Oct 19, 2026
+ *      Author agent
+ */
+
+package test
+
+import (
+	"go/ast"
+	"go/token"
+	"testing"
+
+	. "github.com/cosmos72/gomacro/ast2"
+	"github.com/cosmos72/gomacro/base/output"
+	mt "github.com/cosmos72/gomacro/token"
+)
+
+func parse(t *testing.T, src string) Ast {
+	form, err := ParsePattern(src)
+	if err != nil {
+		t.Fatalf("parse %q failed: %v", src, err)
+	}
+	return form
+}
+
+func text(form Ast) string {
+	st := output.Stringer{Fileset: mt.NewFileSet()}
+	if form, ok := form.(AstWithNode); ok {
+		return st.Sprintf("%v", form.Node())
+	}
+	return st.Sprintf("%v", form.Interface())
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Pattern  string
+		Src      string
+		Ok       bool
+		Bindings map[string]string
+	}{
+		{"literal", "a + 1", "a + 1", true, nil},
+		{"literal_mismatch", "a + 1", "a + 2", false, nil},
+		{"op_mismatch", "a + 1", "a - 1", false, nil},
+		{"wildcard", "$x + 0", "f(y) + 0", true, map[string]string{"x": "f(y)"}},
+		{"nonlinear", "$x - $x", "a[i] - a[i]", true, map[string]string{"x": "a[i]"}},
+		{"nonlinear_mismatch", "$x - $x", "a[i] - a[j]", false, nil},
+		{"ignore", "$_ * $_", "a * b", true, map[string]string{}},
+		{"ident", "$f:ident()", "foo()", true, map[string]string{"f": "foo"}},
+		{"ident_mismatch", "$f:ident()", "x.foo()", false, nil},
+		{"type", "make($t:type, 0)", "make([]int, 0)", true, map[string]string{"t": "[]int"}},
+		{"repeat", "f($first, $rest...)", "f(1, 2, 3)", true, map[string]string{"first": "1", "rest": "[2 3]"}},
+		{"repeat_empty", "f($first, $rest...)", "f(1)", true, map[string]string{"first": "1", "rest": "[]"}},
+		{"repeat_short", "f($first, $rest...)", "f()", false, nil},
+		{"ellipsis_mismatch", "append($a, $b)", "append(x, y...)", false, nil},
+		{"block", "if $cond { $body:stmt... }", "if ok { a(); b() }", true, map[string]string{"cond": "ok", "body": "[a() b()]"}},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			b, ok := Match(parse(t, test.Pattern), parse(t, test.Src))
+			if ok != test.Ok {
+				t.Fatalf("Match(%q, %q) returned %v, expecting %v", test.Pattern, test.Src, ok, test.Ok)
+			}
+			if test.Bindings == nil {
+				return
+			}
+			if len(b) != len(test.Bindings) {
+				t.Errorf("Match(%q, %q) returned %d bindings, expecting %d", test.Pattern, test.Src, len(b), len(test.Bindings))
+			}
+			for name, expected := range test.Bindings {
+				if actual := text(b[name]); actual != expected {
+					t.Errorf("Match(%q, %q) bound $%s to %q, expecting %q", test.Pattern, test.Src, name, actual, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestMatcher(t *testing.T) {
+	m := Matcher{Default: "expr"}
+	tests := []struct {
+		Name    string
+		Pattern string
+		Src     string
+		Reason  string
+	}{
+		{"ok", "$x + 1", "a + 1", ""},
+		{"literal", "$x + 1", "a + 2", "expecting `1`, found `2`"},
+		{"default_kind", "{ $s }", "{ x := 1 }", "expecting an expression for $s, found `x := 1`"},
+		{"kind", "$f:ident()", "x.f()", "expecting an identifier for $f:ident, found `x.f`"},
+		{"count", "f($a, $b)", "f(1)", "expecting 2 elements [`$a`, `$b`], found 1 elements [`1`]"},
+		{"repeat_short", "f($a, $b, $rest...)", "f(1)", "expecting at least 2 elements [`$a`, `$b`, `$rest...`], found 1 elements [`1`]"},
+		{"nonlinear", "$x - $x", "a - b", "expecting $x equal to `a`, found `b`"},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			_, err := m.Match(parse(t, test.Pattern), parse(t, test.Src))
+			reason := ""
+			if err != nil {
+				reason = err.Error()
+			}
+			if reason != test.Reason {
+				t.Errorf("Matcher.Match(%q, %q) failed with %q, expecting %q", test.Pattern, test.Src, reason, test.Reason)
+			}
+		})
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	rules := []Rule{
+		{Pattern: parse(t, "$x + 0"), Template: parse(t, "$x")},
+		{Pattern: parse(t, "$x * 1"), Template: parse(t, "$x")},
+		{Pattern: parse(t, "f($args...)"), Template: parse(t, "g(0, $args...)")},
+	}
+	tests := []struct {
+		Name      string
+		Src       string
+		Expected  string
+		Rewritten bool
+	}{
+		{"none", "a - b", "a - b", false},
+		{"simple", "a + 0", "a", true},
+		{"nested", "(a*1 + 0) - b", "(a) - b", true},
+		{"splice", "f(1, 2)", "g(0, 1, 2)", true},
+		{"bottom_up", "f(x + 0)", "g(0, x)", true},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			form, rewritten := Rewrite(parse(t, test.Src), rules...)
+			if actual := text(form); actual != test.Expected || rewritten != test.Rewritten {
+				t.Errorf("Rewrite(%q) returned %q, %v, expecting %q, %v", test.Src, actual, rewritten, test.Expected, test.Rewritten)
+			}
+		})
+	}
+}
+
+func TestCloneEqualHash(t *testing.T) {
+	srcs := []string{
+		"a + b*c",
+		"func f(x int) int { return x }",
+		"for i := range list { f(i...) }",
+		"type T struct { A int `json:\"a\"` }",
+	}
+	for _, src := range srcs {
+		form := parse(t, src)
+		clone := Clone(form)
+		if !Equal(form, clone) || Hash(form) != Hash(clone) {
+			t.Errorf("Clone(%q) returned %q, which is not equal to the original", src, text(clone))
+		}
+		// positions are ignored
+		other := parse(t, "\n\n  "+src)
+		if !Equal(form, other) || Hash(form) != Hash(other) {
+			t.Errorf("Equal(%q) returned false for the same code at a different position", src)
+		}
+		// the clone does not share nodes with the original
+		PreOrder(clone, func(form Ast) (Ast, bool) {
+			if ident, ok := form.(Ident); ok {
+				ident.X.Name += "_"
+			}
+			return form, true
+		})
+		if Equal(form, clone) {
+			t.Errorf("Clone(%q) shares nodes with the original", src)
+		}
+	}
+	if Equal(parse(t, "f(x)"), parse(t, "f(x...)")) {
+		t.Errorf("Equal(f(x), f(x...)) returned true")
+	}
+}
+
+func TestWalk(t *testing.T) {
+	form := parse(t, "a + b*c")
+	var pre, post []string
+	PreOrder(form, func(form Ast) (Ast, bool) {
+		if ident, ok := form.(Ident); ok {
+			pre = append(pre, ident.X.Name)
+		}
+		// do not visit the children of b*c
+		return form, form.Op() != token.MUL
+	})
+	PostOrder(form, func(form Ast) Ast {
+		if ident, ok := form.(Ident); ok {
+			post = append(post, ident.X.Name)
+			// replace each identifier with a literal
+			return BasicLit{X: &ast.BasicLit{Kind: token.INT, Value: "1"}}
+		}
+		return form
+	})
+	if text(form) != "1 + 1*1" {
+		t.Errorf("PostOrder replaced identifiers to %q, expecting %q", text(form), "1 + 1*1")
+	}
+	if len(pre) != 1 || pre[0] != "a" {
+		t.Errorf("PreOrder visited identifiers %v, expecting [a]", pre)
+	}
+	if len(post) != 3 || post[0] != "a" || post[1] != "b" || post[2] != "c" {
+		t.Errorf("PostOrder visited identifiers %v, expecting [a b c]", post)
+	}
+}
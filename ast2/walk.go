@@ -0,0 +1,80 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * walk.go
+ *
+ *  Created on Oct 19, 2026
+ *      Author agent
+ */
+
+package ast2
+
+import (
+	r "reflect"
+)
+
+// PreOrder visits form and its descendants, each one before its children.
+// visit returns the node to use in place of the visited one - possibly the visited one itself -
+// and whether the children of the returned node should be visited.
+// The tree is modified in place. Returns form, or its replacement
+func PreOrder(form Ast, visit func(Ast) (Ast, bool)) Ast {
+	if isNilAst(form) {
+		return form
+	}
+	form, children := visit(form)
+	if children && !isNilAst(form) {
+		walkChildren(form, func(child Ast) Ast {
+			return PreOrder(child, visit)
+		})
+	}
+	return form
+}
+
+// PostOrder visits form and its descendants, each one after its children.
+// visit returns the node to use in place of the visited one - possibly the visited one itself.
+// The tree is modified in place. Returns form, or its replacement
+func PostOrder(form Ast, visit func(Ast) Ast) Ast {
+	if isNilAst(form) {
+		return form
+	}
+	walkChildren(form, func(child Ast) Ast {
+		return PostOrder(child, visit)
+	})
+	return visit(form)
+}
+
+// call f on each non-nil child of form, and replace the child with the result if it is a different one
+func walkChildren(form Ast, f func(Ast) Ast) {
+	for i, n := 0, form.Size(); i < n; i++ {
+		child := form.Get(i)
+		if isNilAst(child) {
+			continue
+		}
+		if replaced := f(child); !sameAst(child, replaced) {
+			form.Set(i, replaced)
+		}
+	}
+}
+
+// return true if a and b wrap the same node, or the same slice
+func sameAst(a, b Ast) bool {
+	if isNilAst(a) || isNilAst(b) {
+		return isNilAst(a) && isNilAst(b)
+	}
+	if an, ok := a.(AstWithNode); ok {
+		bn, ok := b.(AstWithNode)
+		return ok && an.Node() == bn.Node()
+	}
+	if r.TypeOf(a) != r.TypeOf(b) {
+		return false
+	}
+	va, vb := r.ValueOf(a.Interface()), r.ValueOf(b.Interface())
+	return va.Len() == vb.Len() && (va.Len() == 0 || va.Pointer() == vb.Pointer())
+}
@@ -19,6 +19,15 @@ func init() {
 			"AnyToAstWithNode":  r.ValueOf(AnyToAstWithNode),
 			"AnyToAstWithSlice": r.ValueOf(AnyToAstWithSlice),
 			"BlockStmtToExpr":   r.ValueOf(BlockStmtToExpr),
+			"Clone":             r.ValueOf(Clone),
+			"Equal":             r.ValueOf(Equal),
+			"Hash":              r.ValueOf(Hash),
+			"Match":             r.ValueOf(Match),
+			"ParsePattern":      r.ValueOf(ParsePattern),
+			"PostOrder":         r.ValueOf(PostOrder),
+			"PreOrder":          r.ValueOf(PreOrder),
+			"Rewrite":           r.ValueOf(Rewrite),
+			"Substitute":        r.ValueOf(Substitute),
 			"ToAst":             r.ValueOf(ToAst),
 			"ToAst1":            r.ValueOf(ToAst1),
 			"ToAst2":            r.ValueOf(ToAst2),
@@ -54,6 +63,7 @@ func init() {
 			"BadStmt":        r.TypeOf((*BadStmt)(nil)).Elem(),
 			"BasicLit":       r.TypeOf((*BasicLit)(nil)).Elem(),
 			"BinaryExpr":     r.TypeOf((*BinaryExpr)(nil)).Elem(),
+			"Bindings":       r.TypeOf((*Bindings)(nil)).Elem(),
 			"BlockStmt":      r.TypeOf((*BlockStmt)(nil)).Elem(),
 			"BranchStmt":     r.TypeOf((*BranchStmt)(nil)).Elem(),
 			"CallExpr":       r.TypeOf((*CallExpr)(nil)).Elem(),
@@ -88,11 +98,13 @@ func init() {
 			"KeyValueExpr":   r.TypeOf((*KeyValueExpr)(nil)).Elem(),
 			"LabeledStmt":    r.TypeOf((*LabeledStmt)(nil)).Elem(),
 			"MapType":        r.TypeOf((*MapType)(nil)).Elem(),
+			"Matcher":        r.TypeOf((*Matcher)(nil)).Elem(),
 			"NodeSlice":      r.TypeOf((*NodeSlice)(nil)).Elem(),
 			"Package":        r.TypeOf((*Package)(nil)).Elem(),
 			"ParenExpr":      r.TypeOf((*ParenExpr)(nil)).Elem(),
 			"RangeStmt":      r.TypeOf((*RangeStmt)(nil)).Elem(),
 			"ReturnStmt":     r.TypeOf((*ReturnStmt)(nil)).Elem(),
+			"Rule":           r.TypeOf((*Rule)(nil)).Elem(),
 			"SelectStmt":     r.TypeOf((*SelectStmt)(nil)).Elem(),
 			"SelectorExpr":   r.TypeOf((*SelectorExpr)(nil)).Elem(),
 			"SendStmt":       r.TypeOf((*SendStmt)(nil)).Elem(),
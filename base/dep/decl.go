@@ -109,8 +109,10 @@ func (list DeclList) Map() DeclMap {
 	return m
 }
 
+// sort by position. Stable sort: macroexpanded code may contain many declarations
+// or statements with the same position, i.e. the macro call, and their order must be preserved
 func (list DeclList) SortByPos() DeclList {
-	sort.Slice(list, func(i, j int) bool {
+	sort.SliceStable(list, func(i, j int) bool {
 		a, b := list[i], list[j]
 		return a.Pos < b.Pos
 	})
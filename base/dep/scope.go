@@ -177,6 +177,10 @@ func (s *Scope) Consts(node ast.Spec, iota int, defaults *ConstDeps) []string {
 
 // constant
 func (s *Scope) Const(ident *ast.Ident, node ast.Spec, iota int, typ ast.Expr, value ast.Expr, deps []string) *Decl {
+	if _, ok := value.(*ast.FuncLit); ok {
+		// support recursive const funcs. the parser converts them to const NAME = func(PARAMS) RESULT { BODY }
+		deps = remove_item_inplace(ident.Name, dup(deps))
+	}
 	decl := NewDecl(Const, ident.Name, node, ident.Pos(), deps)
 	decl.Extra = &Extra{
 		Ident: ident,
@@ -267,7 +271,7 @@ func (s *Scope) Func(node *ast.FuncDecl) []string {
 	inner := NewScope(s)
 
 	name := node.Name.Name
-	deps := inner.Expr(node.Type)
+	deps := inner.funcType(node.Type)
 
 	kind := Func
 	if node.Recv != nil && len(node.Recv.List) != 0 {
@@ -299,6 +303,18 @@ func (s *Scope) Func(node *ast.FuncDecl) []string {
 	return deps
 }
 
+// compute dependencies for the parameters and results of a function.
+// also declare them in s, because they are visible in the function body
+func (s *Scope) funcType(node *ast.FuncType) []string {
+	var deps []string
+	for _, list := range [...]*ast.FieldList{node.Params, node.Results} {
+		if list != nil {
+			deps = append(deps, s.Expr(list)...)
+		}
+	}
+	return deps
+}
+
 // type
 func (s *Scope) Type(node ast.Spec) []string {
 	var deps []string
@@ -338,10 +354,10 @@ func (s *Scope) AstExpr(in ast2.Ast) []string {
 	var deps []string
 	switch node := in.Interface().(type) {
 	case *ast.FuncLit:
-		deps = append(deps, s.Expr(node.Type)...)
-		in = ast2.BlockStmt{node.Body}
-		// open a new scope
+		// open a new scope, containing the function parameters and results
 		s = NewScope(s)
+		deps = append(deps, s.funcType(node.Type)...)
+		in = ast2.BlockStmt{node.Body}
 	case *ast.BlockStmt, *ast.FuncType, *ast.InterfaceType, *ast.StructType:
 		// open a new scope
 		s = NewScope(s)
@@ -379,13 +395,11 @@ func (s *Scope) AstExpr(in ast2.Ast) []string {
 
 // return true if name refers to a local declaration
 func (s *Scope) isLocal(name string) bool {
-	outer := s.Outer
-	// outer == nil is top-level scope: not local
-	for ; outer != nil; s = outer {
+	// s.Outer == nil is top-level scope: not local
+	for ; s != nil && s.Outer != nil; s = s.Outer {
 		if _, ok := s.Decls[name]; ok {
 			return true
 		}
-		outer = outer.Outer
 	}
 	return false
 }
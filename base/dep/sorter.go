@@ -198,5 +198,7 @@ loop:
 	if len(list) == 0 {
 		return nil
 	}
-	return list.SortByPos()
+	// do not sort statements by position: they must be executed in the order they appear,
+	// and macroexpanded statements may have positions that do not reflect such order
+	return list
 }
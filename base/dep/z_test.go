@@ -18,6 +18,7 @@ package dep
 
 import (
 	"fmt"
+	"go/ast"
 	"io/ioutil"
 	"reflect"
 	"testing"
@@ -100,3 +101,104 @@ func _testSorter(t *testing.T, filename string) {
 		sorted.Print()
 	}
 }
+
+// return the declarations in src, in the order chosen by Sorter
+func _sortDecls(t *testing.T, src string) DeclList {
+	var p parser.Parser
+	p.Init(token.NewFileSet(), "z_test.go", 0, []byte(src))
+	nodes, err := p.Parse()
+	if err != nil {
+		t.Errorf("parse %q failed: %v", src, err)
+		return nil
+	}
+	s := NewSorter()
+	s.LoadNodes(nodes)
+	return s.All()
+}
+
+func _testSortNames(t *testing.T, src string, expect []string) {
+	var names []string
+	for _, decl := range _sortDecls(t, src) {
+		names = append(names, decl.Name)
+	}
+	if !reflect.DeepEqual(names, expect) {
+		t.Errorf("expected %v, actual %v", expect, names)
+	}
+}
+
+// names declared at top level are not local, even when used inside nested blocks
+func TestSorterNestedBlock(t *testing.T) {
+	for _, decl := range _sortDecls(t, `
+		type T int
+		func f() int { if true { return int(T(1)) }; return 0 }`) {
+		if decl.Name == "f" && !reflect.DeepEqual(decl.Deps, []string{"T"}) {
+			t.Errorf("expected dependencies [T], actual %v", decl.Deps)
+		}
+	}
+}
+
+// function parameters are local to the function body, and hide global names
+func TestSorterParams(t *testing.T) {
+	_testSortNames(t, `
+		var a = pair(2)
+		func pair(a int) int { return a }`,
+		[]string{"pair", "a"})
+	_testSortNames(t, `
+		var b = func(b int) int { return b }(3)`,
+		[]string{"b"})
+}
+
+// statements are returned in the order they were loaded, even if their positions are not:
+// macroexpanded statements may have any position
+func TestSorterStmtOrder(t *testing.T) {
+	fset := token.NewFileSet()
+	var nodes []ast.Node
+	// parse the second statement first, so that it has a smaller position
+	for _, src := range []string{"y := x", "x := 1"} {
+		var p parser.Parser
+		p.Init(fset, "z_test.go", 0, []byte(src))
+		list, err := p.Parse()
+		if err != nil {
+			t.Errorf("parse %q failed: %v", src, err)
+			return
+		}
+		nodes = append(list, nodes...)
+	}
+	s := NewSorter()
+	s.LoadNodes(nodes)
+	sorted := s.All()
+	if len(sorted) != len(nodes) {
+		t.Errorf("expected %d statements, actual %d", len(nodes), len(sorted))
+		return
+	}
+	for i, decl := range sorted {
+		if decl.Node != nodes[i] {
+			t.Errorf("statement %d: expected %v, actual %v", i, nodes[i], decl.Node)
+		}
+	}
+}
+
+func TestSortByPosStable(t *testing.T) {
+	// many declarations with the same position, as produced by a macro
+	var list, expect DeclList
+	for i := 0; i < 100; i++ {
+		decl := &Decl{Name: fmt.Sprint("d", i), Pos: 2}
+		if i%2 == 0 {
+			decl.Pos = 1
+			expect = append(expect, decl)
+		}
+		list = append(list, decl)
+	}
+	for _, decl := range list {
+		if decl.Pos == 2 {
+			expect = append(expect, decl)
+		}
+	}
+	list.SortByPos()
+	for i, decl := range list {
+		if decl != expect[i] {
+			t.Errorf("declaration %d: expected %s, actual %s", i, expect[i].Name, decl.Name)
+			return
+		}
+	}
+}
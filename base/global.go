@@ -17,11 +17,15 @@
 package base
 
 import (
+	"bytes"
 	"fmt"
 	"go/ast"
+	"go/format"
 	"go/token"
 	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	r "reflect"
 	"strings"
 
@@ -50,6 +54,14 @@ type Inspector interface {
 	Inspect(name string, val r.Value, typ r.Type, xtyp xr.Type, globals *Globals)
 }
 
+// InspectorWithEval is an Inspector that can evaluate expressions,
+// for example to modify the inspected values.
+// Interpreters supporting it call SetEval() before Inspect()
+type InspectorWithEval interface {
+	Inspector
+	SetEval(eval func(src string) (r.Value, xr.Type, error))
+}
+
 type Globals struct {
 	Output
 	Options      Options
@@ -59,6 +71,7 @@ type Globals struct {
 	Imports      []*ast.GenDecl
 	Declarations []ast.Decl
 	Statements   []ast.Stmt
+	Comments     []*ast.CommentGroup // comments of collected declarations. parsed only if ParserMode contains mp.ParseComments
 	Prompt       string
 	Readline     Readline
 	GensymN      uint
@@ -74,6 +87,7 @@ func NewGlobals() *Globals {
 			Stringer: output.Stringer{
 				Fileset:    mt.NewFileSet(),
 				NamedTypes: make(map[r.Type]string),
+				Limits:     output.DefaultPrintLimits,
 			},
 			// using both os.Stdout and os.Stderr can interleave impredictably
 			// normal output and diagnostic messages - ugly in interactive use
@@ -171,6 +185,9 @@ func (g *Globals) ParseBytes(src []byte) []ast.Node {
 	if err != nil {
 		output.Error(err)
 	}
+	if g.Options&OptCollectDeclarations != 0 {
+		g.Comments = append(g.Comments, parser.Comments()...)
+	}
 	return nodes
 }
 
@@ -178,24 +195,57 @@ func (g *Globals) ParseBytes(src []byte) []ast.Node {
 func (g *Globals) Print(values []r.Value, types []xr.Type) {
 	opts := g.Options
 	if opts&OptShowEval != 0 {
-		if opts&OptShowEvalType != 0 {
-			for i, vi := range values {
-				var ti interface{}
-				if types != nil && i < len(types) {
-					ti = types[i]
-				} else {
+		g.Limits.Multiline = opts&OptPrintMultiline != 0
+		for i, vi := range values {
+			var xt xr.Type
+			if types != nil && i < len(types) {
+				xt = types[i]
+			}
+			str, ok := g.display(vi, xt)
+			if !ok {
+				continue
+			}
+			if opts&OptShowEvalType != 0 {
+				var ti interface{} = xt
+				if xt == nil {
 					ti = reflect.Type(vi)
 				}
-				g.Fprintf(g.Stdout, "%v\t// %v\n", vi, ti)
-			}
-		} else {
-			for _, vi := range values {
-				g.Fprintf(g.Stdout, "%v\n", vi)
+				g.Fprintf(g.Stdout, "%s\t// %v\n", str, ti)
+			} else {
+				g.Fprintf(g.Stdout, "%s\n", str)
 			}
 		}
 	}
 }
 
+// format a value for printing, using its display hook if present.
+// return false if the value was passed to g.RichOutput instead
+func (g *Globals) display(v r.Value, xt xr.Type) (string, bool) {
+	if mime, data, ok := g.Display(v, xt); ok {
+		if output.IsMimeText(mime) {
+			return string(data), true
+		} else if g.RichOutput != nil {
+			g.RichOutput(mime, data)
+			return "", false
+		}
+	}
+	return g.Pretty(v), true
+}
+
+// SetPrintLimits applies the settings NAME=VALUE contained in arg, as Print.MaxDepth=5,
+// and returns the remaining words of arg
+func (g *Globals) SetPrintLimits(arg string) string {
+	var rest []string
+	for _, word := range strings.Fields(arg) {
+		if strings.IndexByte(word, '=') < 0 {
+			rest = append(rest, word)
+		} else if err := g.Limits.Set(word); err != nil {
+			g.Warnf("%v", err)
+		}
+	}
+	return strings.Join(rest, " ")
+}
+
 // remove package 'path' from the list of known packages.
 // later attempts to import it again will trigger a recompile.
 func (g *Globals) UnloadPackage(path string) {
@@ -246,7 +296,10 @@ func (g *Globals) CollectNode(node ast.Node) {
 		if collectDecl {
 			switch node.Tok {
 			case token.IMPORT:
-				g.Imports = append(g.Imports, node)
+				// skip macro imports, Go compilers would choke on them
+				if node = withoutMacroImports(node); node != nil {
+					g.Imports = append(g.Imports, node)
+				}
 			case token.PACKAGE:
 				/*
 					exception: modified parser converts 'package foo' to:
@@ -338,18 +391,45 @@ func (g *Globals) CollectNode(node ast.Node) {
 	}
 }
 
+// return decl without the macro imports import ~"path", or nil if it contains only macro imports
+func withoutMacroImports(decl *ast.GenDecl) *ast.GenDecl {
+	specs := make([]ast.Spec, 0, len(decl.Specs))
+	for _, spec := range decl.Specs {
+		if spec, ok := spec.(*ast.ImportSpec); ok && spec.Name != nil && spec.Name.Name == mt.MACRO.String() {
+			continue
+		}
+		specs = append(specs, spec)
+	}
+	if len(specs) == len(decl.Specs) {
+		return decl
+	} else if len(specs) == 0 {
+		return nil
+	}
+	filtered := *decl
+	filtered.Specs = specs
+	return &filtered
+}
+
+// write collected declarations and statements to file, formatted with gofmt.
+// declarations are preceded by //line directives pointing to their original source file
 func (g *Globals) WriteDeclsToFile(filename string, prologue ...string) {
-	f, err := os.Create(filename)
+	var buf bytes.Buffer
+	for _, str := range prologue {
+		buf.WriteString(str)
+	}
+	g.Output.WriteDeclsToStream(&buf, filepath.Dir(filename), g.PackagePath, g.Imports, g.Declarations, g.Statements, g.Comments)
+
+	src, err := format.Source(buf.Bytes())
 	if err != nil {
-		g.Errorf("failed to create file %q: %v", filename, err)
+		g.Warnf("failed to gofmt file %q: %v", filename, err)
+		src = buf.Bytes()
 	}
-	defer f.Close()
-	for _, str := range prologue {
-		f.WriteString(str)
+	err = ioutil.WriteFile(filename, src, 0666)
+	if err != nil {
+		g.Errorf("failed to write file %q: %v", filename, err)
 	}
-	g.WriteDeclsToStream(f)
 }
 
 func (g *Globals) WriteDeclsToStream(out io.Writer) {
-	g.Output.WriteDeclsToStream(out, g.PackagePath, g.Imports, g.Declarations, g.Statements)
+	g.Output.WriteDeclsToStream(out, ".", g.PackagePath, g.Imports, g.Declarations, g.Statements, g.Comments)
 }
@@ -0,0 +1,197 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * edit.go
+ *
+ *  Created on Oct 19, 2026
+ *      Author agent
+ */
+
+package inspect
+
+import (
+	"errors"
+	"fmt"
+	r "reflect"
+	"strings"
+	"unsafe"
+
+	"github.com/cosmos72/gomacro/base/untyped"
+	xr "github.com/cosmos72/gomacro/xreflect"
+)
+
+// SetEval sets the function used to evaluate expressions,
+// for example the right-hand side of 'set FIELD = EXPR'
+func (ip *Inspector) SetEval(eval func(src string) (r.Value, xr.Type, error)) {
+	ip.eval = eval
+}
+
+// Set executes the inspector command 'set FIELD = EXPR'
+func (ip *Inspector) Set(arg string) {
+	g := ip.globals
+	lhs, rhs := splitAssign(arg)
+	if len(lhs) == 0 || len(rhs) == 0 {
+		g.Fprintf(g.Stdout, "invalid inspector command \"set %s\": expecting set FIELD = EXPR\n", arg)
+		return
+	}
+	pl, ok := ip.lookup(lhs, false)
+	if !ok {
+		return
+	}
+	var t r.Type
+	if pl.mapv.IsValid() {
+		t = pl.mapv.Type().Elem()
+	} else {
+		t = pl.val.Type()
+	}
+	val, err := ip.evalAs(rhs, t, pl.xtype)
+	if err == nil {
+		err = ip.store(pl, val)
+	}
+	if err != nil {
+		g.Fprintf(g.Stdout, "cannot set %s: %v\n", pl.name, err)
+		return
+	}
+	ip.showVar(pl.name, val, t)
+}
+
+// Delete executes the inspector command 'delete KEY'
+func (ip *Inspector) Delete(arg string) {
+	g := ip.globals
+	depth := len(ip.names)
+	if v := dereferenceValue(ip.vals[depth-1]); v.Kind() != r.Map {
+		g.Fprintf(g.Stdout, "cannot delete from <%v>: expecting map\n", v.Type())
+		return
+	}
+	pl, ok := ip.lookup(arg, true)
+	if !ok {
+		return
+	}
+	pl.mapv.SetMapIndex(pl.key, r.Value{})
+	ip.Show()
+}
+
+// Append executes the inspector command 'append EXPR'
+func (ip *Inspector) Append(arg string) {
+	g := ip.globals
+	depth := len(ip.vals)
+	v := dereferenceValue(ip.vals[depth-1])
+	if v.Kind() != r.Slice {
+		g.Fprintf(g.Stdout, "cannot append to <%v>: expecting slice\n", v.Type())
+		return
+	}
+	xt := elemXType(dereferenceXType(ip.xtypes[depth-1], r.Slice))
+	elem, err := ip.evalAs(arg, v.Type().Elem(), xt)
+	if err == nil {
+		err = ip.store(place{name: ip.names[depth-1], val: v}, r.Append(v, elem))
+	}
+	if err != nil {
+		g.Fprintf(g.Stdout, "cannot append to %s: %v\n", strings.Join(ip.names, "."), err)
+		return
+	}
+	ip.Show()
+}
+
+// store val into the struct field, element or map entry pl
+func (ip *Inspector) store(pl place, val r.Value) error {
+	if pl.mapv.IsValid() {
+		pl.mapv.SetMapIndex(pl.key, val)
+		return nil
+	}
+	dst := pl.val
+	if !dst.CanAddr() {
+		return errors.New("not addressable. Inspect a variable or a pointer to modify its contents")
+	}
+	if !dst.CanSet() {
+		// unexported struct field. we are a debugger: allow modifying it
+		dst = r.NewAt(dst.Type(), unsafe.Pointer(dst.UnsafeAddr())).Elem()
+	}
+	dst.Set(val)
+	return nil
+}
+
+// evaluate src and check that the result can be assigned to type t, or xt if not nil.
+// literals are converted to t, as the compiler does for untyped constants
+func (ip *Inspector) evalAs(src string, t r.Type, xt xr.Type) (r.Value, error) {
+	// interpreted types may be emulated, as recursive types whose r.Type is an interface:
+	// in such case, constants cannot be assigned to them
+	constok := xt == nil || xt.Kind() == t.Kind()
+	if constok {
+		if val, err := parseLiteral(src, t); err == nil || ip.eval == nil {
+			return val, err
+		}
+	} else if ip.eval == nil {
+		return r.Value{}, fmt.Errorf("cannot use %s as <%v>", src, xt)
+	}
+	val, vxt, err := ip.eval(src)
+	if err != nil {
+		return r.Value{}, err
+	}
+	if !val.IsValid() {
+		return r.Value{}, fmt.Errorf("%s has no value", src)
+	}
+	if lit, ok := val.Interface().(untyped.Lit); ok {
+		// untyped constant expression, as 1<<10 or "a" + "b"
+		if constok {
+			val = convertConstant(lit.Val, t)
+		} else {
+			val = r.Value{}
+		}
+		if !val.IsValid() {
+			var dst interface{} = t
+			if xt != nil {
+				dst = xt
+			}
+			return val, fmt.Errorf("cannot use %s (untyped constant) as <%v>", src, dst)
+		}
+		return val, nil
+	}
+	if xt != nil && vxt != nil {
+		if !vxt.AssignableTo(xt) {
+			return r.Value{}, fmt.Errorf("cannot use %s <%v> as <%v>", src, vxt, xt)
+		}
+	} else if !val.Type().AssignableTo(t) {
+		return r.Value{}, fmt.Errorf("cannot use %s <%v> as <%v>", src, val.Type(), t)
+	}
+	if val.Type() != t && val.Type().ConvertibleTo(t) {
+		val = val.Convert(t)
+	}
+	return val, nil
+}
+
+// split "LHS = RHS" at the first '=' outside brackets, parentheses and literals
+func splitAssign(src string) (lhs string, rhs string) {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(src); i++ {
+		ch := src[i]
+		switch {
+		case quote != 0:
+			if ch == '\\' && quote != '`' {
+				i++
+			} else if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'' || ch == '`':
+			quote = ch
+		case ch == '[' || ch == '(' || ch == '{':
+			depth++
+		case ch == ']' || ch == ')' || ch == '}':
+			depth--
+		case ch == '=' && depth == 0:
+			if i+1 < len(src) && src[i+1] == '=' {
+				i++
+				continue
+			}
+			return strings.TrimSpace(src[:i]), strings.TrimSpace(src[i+1:])
+		}
+	}
+	return strings.TrimSpace(src), ""
+}
@@ -19,6 +19,7 @@ package inspect
 import (
 	"errors"
 	r "reflect"
+	"runtime"
 	"strconv"
 	"strings"
 
@@ -34,6 +35,7 @@ type Inspector struct {
 	types   []r.Type
 	xtypes  []xr.Type
 	globals *base.Globals
+	eval    func(src string) (r.Value, xr.Type, error) // nil if not available
 }
 
 func (ip *Inspector) Inspect(name string, val r.Value, typ r.Type, xtyp xr.Type, globals *base.Globals) {
@@ -54,15 +56,25 @@ func (ip *Inspector) ShowHelp() {
 	g := ip.globals
 	g.Fprintf(g.Stdout, "%s", `
 // inspector commands:
-NUMBER      enter n-th struct field, or n-th element of array, slice or string
+NUMBER      enter n-th struct field, n-th element of array, slice or string,
+            or n-th map entry
+NAME        enter struct field NAME
+[KEY]       enter element KEY of array, slice or string, or map entry KEY.
+            KEY is an expression, as 3 or "foo"
 .           show current expression
 ?           show this help
+append EXPR append EXPR to current slice
+delete KEY  delete map entry KEY from current map. KEY is NUMBER or [KEY]
+set FIELD = EXPR
+            assign EXPR to struct field, element or map entry of current expression.
+            FIELD is NUMBER, NAME or [KEY]
 help        show this help
 methods     show methods
 quit        exit inspector
 top         return to top-level expression
 up          return to outer expression
 // abbreviations are allowed if unambiguous.
+// struct field names take precedence over commands and their abbreviations.
 `)
 }
 
@@ -77,6 +89,12 @@ func (ip *Inspector) Show() {
 	switch v.Kind() {
 	case r.Array, r.Slice, r.String:
 		ip.showIndexes(v)
+	case r.Chan:
+		ip.showChan(v)
+	case r.Func:
+		ip.showFunc(v, ip.xtypes[depth-1])
+	case r.Map:
+		ip.showEntries(v)
 	case r.Struct:
 		ip.showFields(v)
 	}
@@ -101,7 +119,18 @@ func (ip *Inspector) Repl() error {
 }
 
 func (ip *Inspector) Eval(cmd string) error {
+	if ip.isField(cmd) {
+		// fields named as commands or their abbreviations, as t or up
+		ip.Enter(cmd)
+		return nil
+	}
 	switch {
+	case strings.HasPrefix(cmd, "append "):
+		ip.Append(strings.TrimSpace(cmd[len("append "):]))
+	case strings.HasPrefix(cmd, "delete "):
+		ip.Delete(strings.TrimSpace(cmd[len("delete "):]))
+	case strings.HasPrefix(cmd, "set "):
+		ip.Set(strings.TrimSpace(cmd[len("set "):]))
 	case cmd == "?", strings.HasPrefix("help", cmd):
 		ip.ShowHelp()
 	case strings.HasPrefix("methods", cmd):
@@ -127,10 +156,24 @@ func (ip *Inspector) Eval(cmd string) error {
 	return nil
 }
 
+// return true if the current expression is a struct, or a pointer to struct, with a field named cmd
+func (ip *Inspector) isField(cmd string) bool {
+	v := dereferenceValue(ip.vals[len(ip.vals)-1])
+	if v.Kind() != r.Struct || !isIdent(cmd) {
+		return false
+	}
+	_, found := v.Type().FieldByName(cmd)
+	if !found {
+		_, found = v.Type().FieldByName(base.StrGensymPrivate + cmd)
+	}
+	return found
+}
+
 func (ip *Inspector) Top() {
 	ip.names = ip.names[0:1]
 	ip.vals = ip.vals[0:1]
 	ip.types = ip.types[0:1]
+	ip.xtypes = ip.xtypes[0:1]
 }
 
 func (ip *Inspector) Leave() {
@@ -142,6 +185,7 @@ func (ip *Inspector) Leave() {
 	ip.names = ip.names[:depth]
 	ip.vals = ip.vals[:depth]
 	ip.types = ip.types[:depth]
+	ip.xtypes = ip.xtypes[:depth]
 	if depth > 0 {
 		ip.Show()
 	}
@@ -159,10 +203,15 @@ func (ip *Inspector) showFields(v r.Value) {
 		t := reflect.Type(f)
 		f = dereferenceValue(f)
 		g.Fprintf(g.Stdout, "    %d. ", i)
-		ip.showVar(v.Type().Field(i).Name, f, t)
+		ip.showVar(fieldName(v.Type().Field(i)), f, t)
 	}
 }
 
+// return the name of a struct field, as written in the source code
+func fieldName(field r.StructField) string {
+	return strings.TrimPrefix(field.Name, base.StrGensymPrivate)
+}
+
 func (ip *Inspector) showIndexes(v r.Value) {
 	g := ip.globals
 	n := v.Len()
@@ -175,6 +224,89 @@ func (ip *Inspector) showIndexes(v r.Value) {
 	}
 }
 
+// show map entries, sorted by key when possible
+func (ip *Inspector) showEntries(v r.Value) {
+	g := ip.globals
+	keys := reflect.SortedMapKeys(v)
+	for i, key := range keys {
+		f := v.MapIndex(key)
+		t := reflect.Type(f)
+		f = dereferenceValue(f)
+		g.Fprintf(g.Stdout, "    %d. ", i)
+		ip.showVar(keyName(key), f, t)
+	}
+}
+
+// show length, capacity and buffered contents of a channel.
+// reflect cannot read the contents without receiving them, thus they are received
+// and sent back without blocking. Caveat: goroutines using the channel concurrently
+// may see it temporarily empty, and the contents of a closed channel are lost
+// because they cannot be sent back.
+func (ip *Inspector) showChan(v r.Value) {
+	g := ip.globals
+	if v.IsNil() {
+		return
+	}
+	g.Fprintf(g.Stdout, "    len %d, cap %d\n", v.Len(), v.Cap())
+	if v.Type().ChanDir() != r.BothDir {
+		// cannot receive or cannot send back
+		return
+	}
+	n := v.Len()
+	elems := make([]r.Value, 0, n)
+	for i := 0; i < n; i++ {
+		elem, ok := v.TryRecv()
+		if !ok {
+			break
+		}
+		elems = append(elems, elem)
+	}
+	for i, elem := range elems {
+		g.Fprintf(g.Stdout, "    %d. ", i)
+		ip.showVar("", elem, v.Type().Elem())
+	}
+	for i, elem := range elems {
+		if !trySend(v, elem) {
+			g.Fprintf(g.Stdout, "// channel is closed or full: lost %d elements while showing them\n", len(elems)-i)
+			break
+		}
+	}
+}
+
+// send elem to channel v without blocking. return false if it fails or if v is closed
+func trySend(v r.Value, elem r.Value) (ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	return v.TrySend(elem)
+}
+
+// show function signature and, for compiled functions, their name and position
+func (ip *Inspector) showFunc(v r.Value, xt xr.Type) {
+	g := ip.globals
+	if xt != nil && xt.Kind() == r.Func {
+		g.Fprintf(g.Stdout, "    signature %v\n", xt)
+	} else {
+		g.Fprintf(g.Stdout, "    signature %v\n", v.Type())
+	}
+	if v.IsNil() {
+		return
+	}
+	fun := runtime.FuncForPC(v.Pointer())
+	if fun == nil {
+		return
+	}
+	name := fun.Name()
+	if strings.HasPrefix(name, "reflect.") || strings.HasPrefix(name, "github.com/cosmos72/gomacro/") {
+		g.Fprintf(g.Stdout, "    interpreted function\n")
+		return
+	}
+	file, line := fun.FileLine(fun.Entry())
+	g.Fprintf(g.Stdout, "    compiled function %s at %s:%d\n", name, file, line)
+}
+
 func (ip *Inspector) showMethods(t r.Type, xt xr.Type) {
 	g := ip.globals
 	switch {
@@ -208,58 +340,258 @@ func (ip *Inspector) showMethods(t r.Type, xt xr.Type) {
 
 func (ip *Inspector) Enter(cmd string) {
 	g := ip.globals
-	i, err := strconv.Atoi(cmd)
-	if err != nil {
-		g.Fprintf(g.Stdout, "unknown inspector command \"%s\". Type ? for help\n", cmd)
-		return
-	}
-	depth := len(ip.names)
-	v := dereferenceValue(ip.vals[depth-1])
-	var n int
-	var fname string
-	var f r.Value
-	switch v.Kind() {
-	case r.Array, r.Slice, r.String:
-		n = v.Len()
-		if !ip.validRange(i, n) {
-			return
-		}
-		fname = "[" + cmd + "]"
-		f = v.Index(i)
-	case r.Struct:
-		n = v.NumField()
-		if !ip.validRange(i, n) {
-			return
-		}
-		fname = v.Type().Field(i).Name
-		f = v.Field(i)
-	default:
-		g.Fprintf(g.Stdout, "cannot enter <%v>: expecting array, slice, string or struct\n", reflect.Type(v))
+	pl, ok := ip.lookup(cmd, true)
+	if !ok {
 		return
 	}
+	f, xt := pl.val, pl.xtype
 	var t r.Type
 	if f.IsValid() && f != base.None {
 		if f.Kind() == r.Interface {
 			f = f.Elem() // concrete type
+			xt = nil
+		}
+		if f.IsValid() {
+			t = f.Type()
 		}
-		t = f.Type()
 	}
 
 	switch dereferenceValue(f).Kind() { // dereference pointers on-the-fly
-	case r.Array, r.Slice, r.String, r.Struct:
-		ip.names = append(ip.names, fname)
+	case r.Array, r.Chan, r.Func, r.Map, r.Slice, r.String, r.Struct:
+		if outer := ip.findCycle(f); outer >= 0 {
+			g.Fprintf(g.Stdout, "// %s is the same as %s\n", pl.name, strings.Join(ip.names[:outer+1], "."))
+		}
+		ip.names = append(ip.names, pl.name)
 		ip.vals = append(ip.vals, f)
 		ip.types = append(ip.types, t)
+		ip.xtypes = append(ip.xtypes, xt)
 		ip.Show()
 	default:
-		ip.showVar(fname, f, t)
+		ip.showVar(pl.name, f, t)
 	}
 }
 
+// place is a struct field, an element or a map entry of the current expression
+type place struct {
+	name  string
+	val   r.Value
+	xtype xr.Type // nil if unknown
+	mapv  r.Value // map containing the entry, if place is a map entry
+	key   r.Value // key of the map entry, if place is a map entry
+}
+
+// find the struct field, element or map entry selected by cmd,
+// which can be NUMBER, NAME or [KEY]. if !mustExist, map entries are created as needed
+func (ip *Inspector) lookup(cmd string, mustExist bool) (place, bool) {
+	g := ip.globals
+	depth := len(ip.names)
+	v := dereferenceValue(ip.vals[depth-1])
+	xt := dereferenceXType(ip.xtypes[depth-1], v.Kind())
+	switch {
+	case len(cmd) > 2 && cmd[0] == '[' && cmd[len(cmd)-1] == ']':
+		return ip.lookupKey(v, xt, strings.TrimSpace(cmd[1:len(cmd)-1]), mustExist)
+	case len(cmd) != 0 && cmd[0] >= '0' && cmd[0] <= '9':
+		i, err := strconv.Atoi(cmd)
+		if err != nil {
+			g.Fprintf(g.Stdout, "invalid number \"%s\"\n", cmd)
+			break
+		}
+		return ip.lookupNumber(v, xt, i)
+	case v.Kind() == r.Struct && isIdent(cmd):
+		field, found := v.Type().FieldByName(cmd)
+		if !found {
+			// unexported fields of interpreted structs are renamed
+			field, found = v.Type().FieldByName(base.StrGensymPrivate + cmd)
+		}
+		if !found {
+			g.Fprintf(g.Stdout, "%v has no field \"%s\"\n", v.Type(), cmd)
+			break
+		}
+		if len(field.Index) == 1 {
+			return ip.lookupNumber(v, xt, field.Index[0])
+		}
+		// promoted field: do not follow nil embedded pointers, v.FieldByIndex() would panic
+		for _, i := range field.Index[:len(field.Index)-1] {
+			v = v.Field(i)
+			if v.Kind() == r.Ptr {
+				if v.IsNil() {
+					g.Fprintf(g.Stdout, "cannot enter field \"%s\": embedded pointer %v is nil\n", cmd, v.Type())
+					return place{}, false
+				}
+				v = v.Elem()
+			}
+		}
+		return place{name: cmd, val: v.Field(field.Index[len(field.Index)-1])}, true
+	default:
+		g.Fprintf(g.Stdout, "unknown inspector command \"%s\". Type ? for help\n", cmd)
+	}
+	return place{}, false
+}
+
+// find the n-th struct field, element or map entry of v
+func (ip *Inspector) lookupNumber(v r.Value, xt xr.Type, i int) (place, bool) {
+	name := "[" + strconv.Itoa(i) + "]"
+	switch v.Kind() {
+	case r.Array, r.Slice:
+		if ip.validRange(i, v.Len()) {
+			return place{name: name, val: v.Index(i), xtype: elemXType(xt)}, true
+		}
+	case r.String:
+		if ip.validRange(i, v.Len()) {
+			return place{name: name, val: v.Index(i)}, true
+		}
+	case r.Map:
+		keys := reflect.SortedMapKeys(v)
+		if ip.validRange(i, len(keys)) {
+			return place{name: keyName(keys[i]), val: v.MapIndex(keys[i]), xtype: elemXType(xt), mapv: v, key: keys[i]}, true
+		}
+	case r.Struct:
+		if ip.validRange(i, v.NumField()) {
+			pl := place{name: fieldName(v.Type().Field(i)), val: v.Field(i)}
+			if xt != nil {
+				pl.xtype = xt.Field(i).Type
+			}
+			return pl, true
+		}
+	default:
+		ip.cannotEnter(v)
+	}
+	return place{}, false
+}
+
+// find the element or map entry of v with given key
+func (ip *Inspector) lookupKey(v r.Value, xt xr.Type, src string, mustExist bool) (place, bool) {
+	g := ip.globals
+	switch v.Kind() {
+	case r.Array, r.Slice, r.String:
+		key, err := ip.evalAs(src, r.TypeOf(int(0)), nil)
+		if err != nil {
+			g.Fprintf(g.Stdout, "%v\n", err)
+			break
+		}
+		return ip.lookupNumber(v, xt, int(key.Int()))
+	case r.Map:
+		var keyxt xr.Type
+		if xt != nil {
+			keyxt = xt.Key()
+		}
+		key, err := ip.evalAs(src, v.Type().Key(), keyxt)
+		if err != nil {
+			g.Fprintf(g.Stdout, "%v\n", err)
+			break
+		}
+		f := v.MapIndex(key)
+		if !f.IsValid() {
+			if mustExist {
+				g.Fprintf(g.Stdout, "%s has no entry %s\n", strings.Join(ip.names, "."), keyName(key))
+				break
+			}
+			f = r.Zero(v.Type().Elem())
+		}
+		return place{name: keyName(key), val: f, xtype: elemXType(xt), mapv: v, key: key}, true
+	default:
+		ip.cannotEnter(v)
+	}
+	return place{}, false
+}
+
+func (ip *Inspector) cannotEnter(v r.Value) {
+	g := ip.globals
+	g.Fprintf(g.Stdout, "cannot enter <%v>: expecting array, map, slice, string or struct\n", reflect.Type(v))
+}
+
+// dereference pointers in xt, as dereferenceValue does for values of kind k.
+// return nil if unknown
+func dereferenceXType(xt xr.Type, k r.Kind) xr.Type {
+	for xt != nil && xt.Kind() != k {
+		if xt.Kind() != r.Ptr {
+			return nil
+		}
+		xt = xt.Elem()
+	}
+	return xt
+}
+
+// return the element type of xt, or nil if unknown
+func elemXType(xt xr.Type) xr.Type {
+	if xt == nil {
+		return nil
+	}
+	switch xt.Kind() {
+	case r.Array, r.Chan, r.Map, r.Ptr, r.Slice:
+		return xt.Elem()
+	}
+	return nil
+}
+
+// if v or the pointers it refers to are already being inspected,
+// return the depth of the outermost such expression. otherwise return -1
+func (ip *Inspector) findCycle(v r.Value) int {
+	ptrs := pointers(v)
+	if len(ptrs) == 0 {
+		return -1
+	}
+	for depth, outer := range ip.vals {
+		for p := range pointers(outer) {
+			if ptrs[p] {
+				return depth
+			}
+		}
+	}
+	return -1
+}
+
+type pointer struct {
+	kind r.Kind
+	addr uintptr
+}
+
+// return the addresses of the pointers, maps and channels traversed by dereferenceValue(v)
+func pointers(v r.Value) map[pointer]bool {
+	var ret map[pointer]bool
+	for v.IsValid() {
+		switch k := v.Kind(); k {
+		case r.Interface:
+			v = v.Elem()
+			continue
+		case r.Chan, r.Map, r.Ptr:
+			p := pointer{k, v.Pointer()}
+			if p.addr == 0 || ret[p] {
+				return ret
+			}
+			if ret == nil {
+				ret = make(map[pointer]bool)
+			}
+			ret[p] = true
+			if k == r.Ptr {
+				v = v.Elem()
+				continue
+			}
+		}
+		break
+	}
+	return ret
+}
+
+// dereference pointers and interfaces.
+// stops at pointer cycles, as p := &p, returning the pointer that closes the cycle
 func dereferenceValue(v r.Value) r.Value {
+	var seen map[uintptr]bool
 	for {
 		switch v.Kind() {
-		case r.Interface, r.Ptr:
+		case r.Interface:
+			v = v.Elem()
+			continue
+		case r.Ptr:
+			if addr := v.Pointer(); addr != 0 {
+				if seen[addr] {
+					return v
+				}
+				if seen == nil {
+					seen = make(map[uintptr]bool)
+				}
+				seen[addr] = true
+			}
 			v = v.Elem()
 			continue
 		}
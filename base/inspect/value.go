@@ -0,0 +1,163 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * value.go
+ *
+ *  Created on Oct 19, 2026
+ *      Author agent
+ */
+
+package inspect
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/parser"
+	"go/token"
+	r "reflect"
+	"unicode"
+)
+
+// return the name of map entry with given key, as [KEY]
+func keyName(key r.Value) string {
+	if !key.CanInterface() {
+		return fmt.Sprintf("[%v]", key)
+	}
+	return fmt.Sprintf("[%#v]", key.Interface())
+}
+
+func isIdent(str string) bool {
+	for i, ch := range str {
+		if !(ch == '_' || unicode.IsLetter(ch) || (i != 0 && unicode.IsDigit(ch))) {
+			return false
+		}
+	}
+	return len(str) != 0
+}
+
+// parse a literal, as 3 or -1.5 or "foo" or true, and convert it to type t
+func parseLiteral(src string, t r.Type) (r.Value, error) {
+	expr, err := parser.ParseExpr(src)
+	if err != nil {
+		return r.Value{}, err
+	}
+	c := constantValue(expr)
+	if c == nil {
+		if ident, ok := expr.(*ast.Ident); ok && ident.Name == "nil" {
+			switch t.Kind() {
+			case r.Chan, r.Func, r.Interface, r.Map, r.Ptr, r.Slice, r.UnsafePointer:
+				return r.Zero(t), nil
+			}
+		}
+		return r.Value{}, fmt.Errorf("cannot use %s as %v: expecting a literal", src, t)
+	}
+	v := convertConstant(c, t)
+	if !v.IsValid() {
+		return v, fmt.Errorf("cannot use %s as %v", src, t)
+	}
+	return v, nil
+}
+
+// convert a constant to type t. return the zero r.Value if not possible
+func convertConstant(c constant.Value, t r.Type) r.Value {
+	var v r.Value
+	switch t.Kind() {
+	case r.Bool:
+		if c.Kind() == constant.Bool {
+			v = r.ValueOf(constant.BoolVal(c))
+		}
+	case r.Int, r.Int8, r.Int16, r.Int32, r.Int64:
+		if c = constant.ToInt(c); c.Kind() == constant.Int {
+			if i, exact := constant.Int64Val(c); exact && !r.Zero(t).OverflowInt(i) {
+				v = r.ValueOf(i)
+			}
+		}
+	case r.Uint, r.Uint8, r.Uint16, r.Uint32, r.Uint64, r.Uintptr:
+		if c = constant.ToInt(c); c.Kind() == constant.Int {
+			if u, exact := constant.Uint64Val(c); exact && !r.Zero(t).OverflowUint(u) {
+				v = r.ValueOf(u)
+			}
+		}
+	case r.Float32, r.Float64:
+		if c = constant.ToFloat(c); c.Kind() == constant.Float {
+			f, _ := constant.Float64Val(c)
+			v = r.ValueOf(f)
+		}
+	case r.Complex64, r.Complex128:
+		if c = constant.ToComplex(c); c.Kind() == constant.Complex {
+			re, _ := constant.Float64Val(constant.Real(c))
+			im, _ := constant.Float64Val(constant.Imag(c))
+			v = r.ValueOf(complex(re, im))
+		}
+	case r.String:
+		if c.Kind() == constant.String {
+			v = r.ValueOf(constant.StringVal(c))
+		}
+	case r.Interface:
+		// use the default type of the literal
+		v = defaultValue(c)
+		if v.IsValid() && !v.Type().Implements(t) {
+			v = r.Value{}
+		}
+	}
+	if v.IsValid() {
+		v = v.Convert(t)
+	}
+	return v
+}
+
+// evaluate a constant expression composed of literals, parentheses and unary operators
+func constantValue(expr ast.Expr) constant.Value {
+	switch expr := expr.(type) {
+	case *ast.BasicLit:
+		if c := constant.MakeFromLiteral(expr.Value, expr.Kind, 0); c.Kind() != constant.Unknown {
+			return c
+		}
+	case *ast.Ident:
+		switch expr.Name {
+		case "false":
+			return constant.MakeBool(false)
+		case "true":
+			return constant.MakeBool(true)
+		}
+	case *ast.ParenExpr:
+		return constantValue(expr.X)
+	case *ast.UnaryExpr:
+		switch expr.Op {
+		case token.ADD, token.SUB, token.XOR, token.NOT:
+			if c := constantValue(expr.X); c != nil {
+				return constant.UnaryOp(expr.Op, c, 0)
+			}
+		}
+	}
+	return nil
+}
+
+func defaultValue(c constant.Value) r.Value {
+	switch c.Kind() {
+	case constant.Bool:
+		return r.ValueOf(constant.BoolVal(c))
+	case constant.String:
+		return r.ValueOf(constant.StringVal(c))
+	case constant.Int:
+		if i, exact := constant.Int64Val(c); exact && int64(int(i)) == i {
+			return r.ValueOf(int(i))
+		}
+	case constant.Float:
+		f, _ := constant.Float64Val(c)
+		return r.ValueOf(f)
+	case constant.Complex:
+		re, _ := constant.Float64Val(constant.Real(c))
+		im, _ := constant.Float64Val(constant.Imag(c))
+		return r.ValueOf(complex(re, im))
+	}
+	return r.Value{}
+}
@@ -0,0 +1,158 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * z_test.go
+ *
+ *  Created on Oct 19, 2026
+ *      Author agent
+ */
+
+package inspect
+
+import (
+	"bytes"
+	r "reflect"
+	"strings"
+	"testing"
+
+	"github.com/cosmos72/gomacro/base"
+)
+
+type testStruct struct {
+	A int
+	b string
+	M map[string]int
+	S []uint8
+}
+
+func newInspector(name string, v interface{}) (*Inspector, *bytes.Buffer) {
+	var buf bytes.Buffer
+	g := base.NewGlobals()
+	g.Stdout, g.Stderr = &buf, &buf
+	val := r.ValueOf(v)
+	var ip Inspector
+	ip.Init(name, val, val.Type(), nil, g)
+	return &ip, &buf
+}
+
+func TestInspectChan(t *testing.T) {
+	ch := make(chan int, 3)
+	ch <- 1
+	ch <- 2
+	ip, buf := newInspector("ch", ch)
+	ip.Show()
+	if out := buf.String(); !strings.Contains(out, "len 2, cap 3") {
+		t.Errorf("expecting channel len and cap, found %q", out)
+	} else if !strings.Contains(out, "0. \t= 1\t// int") || !strings.Contains(out, "1. \t= 2\t// int") {
+		t.Errorf("expecting channel contents, found %q", out)
+	}
+	buf.Reset()
+	ip.Eval("0")
+	if out := buf.String(); !strings.Contains(out, "cannot enter") {
+		t.Errorf("expecting channel elements not to be enterable, found %q", out)
+	}
+	// inspecting a channel must leave its contents in place
+	if n := len(ch); n != 2 {
+		t.Errorf("expecting len(ch) == 2 after inspection, found %d", n)
+	}
+	if x := <-ch; x != 1 {
+		t.Errorf("expecting channel order to be preserved, received %d first", x)
+	}
+	// on closed channels, shown contents cannot be sent back
+	close(ch)
+	buf.Reset()
+	ip.Show()
+	if out := buf.String(); !strings.Contains(out, "0. \t= 2\t// int") || !strings.Contains(out, "lost 1 elements") {
+		t.Errorf("expecting closed channel contents and a warning, found %q", out)
+	}
+}
+
+type testEmbedded struct {
+	X int
+}
+
+type testCommands struct {
+	*testEmbedded
+	t  int
+	up string
+}
+
+func TestInspectFieldNames(t *testing.T) {
+	ip, buf := newInspector("v", testCommands{t: 7, up: "x"})
+	// fields named as command abbreviations
+	ip.Eval("t")
+	if out := buf.String(); !strings.HasPrefix(out, "t\t= 7") {
+		t.Errorf("expecting field t, found %q", out)
+	}
+	buf.Reset()
+	ip.Eval("up")
+	if out := buf.String(); !strings.HasPrefix(out, "v.up\t= x") {
+		t.Errorf("expecting field up, found %q", out)
+	}
+	// inside a string, up is the command
+	ip.Eval("up")
+	// promoted field through nil embedded pointer
+	buf.Reset()
+	ip.Eval("X")
+	if out := buf.String(); !strings.Contains(out, "embedded pointer *inspect.testEmbedded is nil") {
+		t.Errorf("expecting nil embedded pointer error, found %q", out)
+	}
+}
+
+func TestInspectSet(t *testing.T) {
+	s := testStruct{M: map[string]int{}}
+	ip, buf := newInspector("s", &s)
+	ip.Eval("set A = 7")
+	ip.Eval(`set b = "x"`)
+	ip.Eval("M")
+	ip.Eval(`set ["k"] = 3`)
+	ip.Eval("up")
+	ip.Eval("S")
+	ip.Eval("append 5")
+	ip.Eval("set 0 = 300")
+	expect := testStruct{A: 7, b: "x", M: map[string]int{"k": 3}, S: []uint8{5}}
+	if !r.DeepEqual(s, expect) {
+		t.Errorf("expecting %#v, found %#v", expect, s)
+	}
+	if out := buf.String(); !strings.Contains(out, "cannot set [0]") {
+		t.Errorf("expecting overflow error, found %q", out)
+	}
+}
+
+func TestParseLiteral(t *testing.T) {
+	tests := []struct {
+		src    string
+		t      r.Type
+		expect interface{}
+	}{
+		{"3", r.TypeOf(int8(0)), int8(3)},
+		{"-(1)", r.TypeOf(int(0)), -1},
+		{"2.5", r.TypeOf(float32(0)), float32(2.5)},
+		{"4", r.TypeOf(float64(0)), float64(4)},
+		{`"foo"`, r.TypeOf(""), "foo"},
+		{"true", r.TypeOf(false), true},
+		{"1i", r.TypeOf(complex128(0)), complex(0, 1)},
+		{"7", r.TypeOf((*interface{})(nil)).Elem(), 7},
+		{"nil", r.TypeOf([]int(nil)), []int(nil)},
+	}
+	for _, test := range tests {
+		v, err := parseLiteral(test.src, test.t)
+		if err != nil {
+			t.Errorf("parseLiteral(%s, %v) failed: %v", test.src, test.t, err)
+		} else if v.Type() != test.t || !r.DeepEqual(v.Interface(), test.expect) {
+			t.Errorf("parseLiteral(%s, %v): expecting %#v, found %#v", test.src, test.t, test.expect, v.Interface())
+		}
+	}
+	for _, src := range []string{"256", "-1", "x", "1.5"} {
+		if v, err := parseLiteral(src, r.TypeOf(uint8(0))); err == nil {
+			t.Errorf("parseLiteral(%s, uint8): expecting error, found %v", src, v)
+		}
+	}
+}
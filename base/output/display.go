@@ -0,0 +1,137 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * display.go
+ *
+ *  Created on Oct 19, 2026
+ *      Author agent
+ */
+
+package output
+
+import (
+	"fmt"
+	r "reflect"
+	"strings"
+
+	"github.com/cosmos72/gomacro/base/reflect"
+	xr "github.com/cosmos72/gomacro/xreflect"
+)
+
+// MimeText is the MIME type of plain text output
+const MimeText = "text/plain"
+
+// Displayer is implemented by values that know how to display themselves,
+// for example as a table, an image or SVG.
+// GomacroDisplay returns the MIME type of data, as "text/html" or "image/png"
+type Displayer interface {
+	GomacroDisplay() (mime string, data []byte)
+}
+
+// DisplayFunc formats a value for display, returning its MIME type and the formatted data
+type DisplayFunc func(v r.Value) (mime string, data []byte)
+
+// XDisplay is a display hook for an interpreted type.
+// xreflect.Type cannot be used as map key: they must be compared with IdenticalTo
+type XDisplay struct {
+	Type xr.Type
+	Func DisplayFunc
+}
+
+// SetDisplay registers fn as display hook for type t, which must be a reflect.Type or a xreflect.Type.
+// If fn is nil, removes the display hook for t
+func (st *Stringer) SetDisplay(t interface{}, fn DisplayFunc) {
+	switch t := t.(type) {
+	case xr.Type:
+		for i, d := range st.XDisplays {
+			if d.Type.IdenticalTo(t) {
+				if fn == nil {
+					st.XDisplays = append(st.XDisplays[:i:i], st.XDisplays[i+1:]...)
+				} else {
+					st.XDisplays[i].Func = fn
+				}
+				return
+			}
+		}
+		if fn != nil {
+			st.XDisplays = append(st.XDisplays, XDisplay{t, fn})
+		}
+	case r.Type:
+		if fn == nil {
+			delete(st.Displays, t)
+		} else {
+			if st.Displays == nil {
+				st.Displays = make(map[r.Type]DisplayFunc)
+			}
+			st.Displays[t] = fn
+		}
+	default:
+		Errorf("SetDisplay: expecting reflect.Type or xreflect.Type, found %v <%v>", t, r.TypeOf(t))
+	}
+}
+
+// Display formats v using the display hook registered for its type xt or v.Type(),
+// or its method GomacroDisplay. xt can be nil.
+// Returns ok = false if v has no display hook
+func (st *Stringer) Display(v r.Value, xt xr.Type) (mime string, data []byte, ok bool) {
+	if !v.IsValid() || v == reflect.None {
+		return "", nil, false
+	}
+	defer func() {
+		if rec := recover(); rec != nil {
+			mime, data, ok = MimeText, []byte(fmt.Sprintf("error displaying value: %v", rec)), true
+		}
+	}()
+	var fn DisplayFunc
+	if xt != nil {
+		for _, d := range st.XDisplays {
+			if d.Type.IdenticalTo(xt) {
+				fn = d.Func
+				break
+			}
+		}
+	}
+	if fn == nil {
+		fn = st.Displays[v.Type()]
+	}
+	if fn != nil {
+		mime, data = fn(v)
+		return mime, data, true
+	}
+	if v.CanInterface() {
+		if d, ok := v.Interface().(Displayer); ok && (v.Kind() != r.Ptr || !v.IsNil()) {
+			mime, data = d.GomacroDisplay()
+			return mime, data, true
+		}
+	}
+	return "", nil, false
+}
+
+// return the text shown by the display hook of v, if it exists and returns plain text
+func (st *Stringer) displayText(v r.Value) (string, bool) {
+	if len(st.Displays) == 0 {
+		// avoid the cost of recover() in Display for the common case
+		if !v.CanInterface() {
+			return "", false
+		} else if _, ok := v.Interface().(Displayer); !ok {
+			return "", false
+		}
+	}
+	mime, data, ok := st.Display(v, nil)
+	if !ok || !IsMimeText(mime) {
+		return "", false
+	}
+	return string(data), true
+}
+
+// IsMimeText returns true if mime is the MIME type of plain text, possibly with parameters as "; charset=utf-8"
+func IsMimeText(mime string) bool {
+	return mime == MimeText || strings.HasPrefix(mime, MimeText+";")
+}
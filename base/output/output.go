@@ -38,12 +38,18 @@ type Stringer struct {
 	Pos        token.Pos
 	Line       int
 	NamedTypes map[r.Type]string
+	Limits     PrintLimits            // limits for Pretty
+	Displays   map[r.Type]DisplayFunc // display hooks for compiled types. see SetDisplay
+	XDisplays  []XDisplay             // display hooks for interpreted types. see SetDisplay
 }
 
 type Output struct {
 	Stringer
 	Stdout io.Writer
 	Stderr io.Writer
+	// if not nil, Globals.Print passes it the results whose display hook
+	// returns a MIME type other than text/plain. Otherwise they are printed as plain text
+	RichOutput func(mime string, data []byte)
 }
 
 type RuntimeError struct {
@@ -56,19 +62,26 @@ func (st *Stringer) Copy(other *Stringer) {
 	st.Fileset = other.Fileset
 	st.Pos = other.Pos
 	st.Line = other.Line
+	st.Limits = other.Limits
+	st.Displays = other.Displays
+	st.XDisplays = other.XDisplays
 }
 
 func (err RuntimeError) Error() string {
 	args := err.args
-	var prefix string
+	var prefix, suffix string
 	if st := err.st; st != nil {
 		args = st.toPrintables(err.format, args)
 		prefix = st.Position().String()
+		suffix = st.Fileset.ExpansionString(st.Pos)
 	}
 	msg := fmt.Sprintf(err.format, args...)
 	if prefix != "" && prefix != "-" {
 		msg = fmt.Sprintf("%s: %s", prefix, msg)
 	}
+	if suffix != "" {
+		msg = fmt.Sprintf("%s (%s)", msg, suffix)
+	}
 	return msg
 }
 
@@ -105,6 +118,10 @@ func (st *Stringer) ErrorAt(pos token.Pos, format string, args ...interface{}) (
 				args = append([]interface{}{position}, args...)
 				format = "%s: " + format
 			}
+			if expansion := st.Fileset.ExpansionString(pos); expansion != "" {
+				args = append(args, expansion)
+				format += " (%s)"
+			}
 		}
 	}
 	panic(RuntimeError{nil, format, args})
@@ -305,7 +322,8 @@ func (st *Stringer) toPrintable(format string, value interface{}) (ret interface
 
 var config = printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}
 
-func (st *Stringer) nodeToPrintable(node ast.Node) interface{} {
+// node must be an ast.Node or a *printer.CommentedNode
+func (st *Stringer) nodeToPrintable(node interface{}) interface{} {
 	if node == nil {
 		return nil
 	}
@@ -0,0 +1,370 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * pretty.go
+ *
+ *  Created on Oct 19, 2026
+ *      Author agent
+ */
+
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	r "reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	. "github.com/cosmos72/gomacro/ast2"
+	"github.com/cosmos72/gomacro/base/reflect"
+	xr "github.com/cosmos72/gomacro/xreflect"
+)
+
+// PrintLimits bound the output of Stringer.Pretty. Zero means unlimited
+type PrintLimits struct {
+	MaxDepth  int  // maximum nesting of arrays, maps, slices, structs and pointers
+	MaxElems  int  // maximum number of elements shown for each array, map, slice or struct
+	MaxString int  // maximum number of bytes shown for each string
+	Multiline bool // show nested structs and maps on multiple lines, indented
+}
+
+// DefaultPrintLimits are the PrintLimits used by the REPL
+var DefaultPrintLimits = PrintLimits{
+	MaxDepth:  10,
+	MaxElems:  100,
+	MaxString: 1000,
+}
+
+var printLimitNames = []string{"Print.MaxDepth", "Print.MaxElems", "Print.MaxString"}
+
+func (l *PrintLimits) field(name string) *int {
+	switch name {
+	case "Print.MaxDepth":
+		return &l.MaxDepth
+	case "Print.MaxElems":
+		return &l.MaxElems
+	case "Print.MaxString":
+		return &l.MaxString
+	}
+	return nil
+}
+
+// Set parses a setting NAME=VALUE, as Print.MaxDepth=5, and applies it
+func (l *PrintLimits) Set(setting string) error {
+	eq := strings.IndexByte(setting, '=')
+	if eq < 0 {
+		return fmt.Errorf("invalid setting %q: expecting NAME=VALUE", setting)
+	}
+	name, value := setting[:eq], setting[eq+1:]
+	field := l.field(name)
+	if field == nil {
+		return fmt.Errorf("unknown setting %q: expecting one of %s", name, strings.Join(printLimitNames, " "))
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid value %q for %s: expecting a non-negative integer", value, name)
+	}
+	*field = n
+	return nil
+}
+
+func (l PrintLimits) String() string {
+	var buf bytes.Buffer
+	for i, name := range printLimitNames {
+		if i != 0 {
+			buf.WriteByte(' ')
+		}
+		fmt.Fprintf(&buf, "%s=%d", name, *l.field(name))
+	}
+	return buf.String()
+}
+
+// pretty prints a value within PrintLimits
+type pretty struct {
+	st     *Stringer
+	limits PrintLimits
+	buf    []byte
+	path   map[prettyRef]int // pointers, maps and slices being printed -> their label, or 0 if none yet
+	labels int
+}
+
+type prettyRef struct {
+	kind r.Kind
+	addr uintptr
+	typ  r.Type
+}
+
+// Pretty formats a value as the %v verb, within the limits st.Limits:
+// the output is truncated where it would be too deep or too long,
+// and references to an enclosing value are shown as <cycle *N>
+// after labeling the referenced value with <ref *N>
+func (st *Stringer) Pretty(value interface{}) string {
+	v, ok := value.(r.Value)
+	if !ok {
+		v = r.ValueOf(value)
+	}
+	p := pretty{st: st, limits: st.Limits, path: make(map[prettyRef]int)}
+	p.value(v, 0)
+	return string(p.buf)
+}
+
+func (p *pretty) str(s string) {
+	p.buf = append(p.buf, s...)
+}
+
+func (p *pretty) newline(depth int) {
+	p.buf = append(p.buf, '\n')
+	for i := 0; i < depth; i++ {
+		p.str("    ")
+	}
+}
+
+// print v, which is nested depth levels inside the value passed to Pretty
+func (p *pretty) value(v r.Value, depth int) {
+	if !v.IsValid() {
+		p.str("<nil>")
+		return
+	} else if v == reflect.None {
+		p.str("/*no value*/")
+		return
+	}
+	if text, ok := p.st.displayText(v); ok {
+		p.str(text)
+		return
+	} else if p.custom(v) {
+		return
+	}
+	switch k := v.Kind(); k {
+	case r.Interface:
+		if v.IsNil() {
+			p.str("<nil>")
+		} else {
+			p.value(v.Elem(), depth)
+		}
+	case r.Ptr:
+		if v.IsNil() {
+			p.str("<nil>")
+			return
+		}
+		switch v.Elem().Kind() {
+		case r.Array, r.Map, r.Slice, r.Struct:
+			p.ref(v, func() {
+				p.str("&")
+				p.value(v.Elem(), depth)
+			})
+		default:
+			p.str(fmt.Sprint(v))
+		}
+	case r.Array:
+		p.list(v, depth)
+	case r.Slice:
+		if v.Len() == 0 {
+			p.str("[]")
+			return
+		}
+		p.ref(v, func() { p.list(v, depth) })
+	case r.Map:
+		if v.Len() == 0 {
+			p.str("map[]")
+			return
+		}
+		p.ref(v, func() { p.dict(v, depth) })
+	case r.Struct:
+		p.fields(v, depth)
+	case r.String:
+		p.string(v.String())
+	default:
+		p.basic(v)
+	}
+}
+
+// print values with custom formatting: AST nodes, types, errors and fmt.Stringer
+func (p *pretty) custom(v r.Value) bool {
+	if !v.CanInterface() {
+		return false
+	}
+	switch k := v.Kind(); k {
+	case r.Chan, r.Func, r.Interface, r.Map, r.Ptr, r.Slice:
+		if v.IsNil() {
+			return false
+		}
+	}
+	switch v.Interface().(type) {
+	case fmt.Formatter, AstWithNode, Ast, ast.Node, r.Type, error, fmt.Stringer:
+		p.str(fmt.Sprint(p.st.toPrintable("%v", v.Interface())))
+		return true
+	}
+	return false
+}
+
+func (p *pretty) basic(v r.Value) {
+	if v.CanInterface() {
+		p.str(fmt.Sprint(p.st.toPrintable("%v", v.Interface())))
+	} else {
+		p.str(fmt.Sprint(v))
+	}
+}
+
+func (p *pretty) string(s string) {
+	if max := p.limits.MaxString; max > 0 && len(s) > max {
+		// do not cut a multi-byte UTF-8 sequence
+		for max > 0 && !utf8.RuneStart(s[max]) {
+			max--
+		}
+		p.str(s[:max])
+		p.str(fmt.Sprintf("... %d more bytes", len(s)-max))
+	} else {
+		p.str(s)
+	}
+}
+
+// print v, which can be referenced by its elements: detect cycles and label v if needed
+func (p *pretty) ref(v r.Value, print func()) {
+	key := prettyRef{v.Kind(), v.Pointer(), v.Type()}
+	if label, visiting := p.path[key]; visiting {
+		if label == 0 {
+			p.labels++
+			label = p.labels
+			p.path[key] = label
+		}
+		p.str(fmt.Sprintf("<cycle *%d>", label))
+		return
+	}
+	start := len(p.buf)
+	p.path[key] = 0
+	print()
+	if label := p.path[key]; label != 0 {
+		// v is referenced by its elements: label it
+		prefix := fmt.Sprintf("<ref *%d> ", label)
+		p.buf = append(p.buf[:start], append([]byte(prefix), p.buf[start:]...)...)
+	}
+	delete(p.path, key)
+}
+
+// return true if depth exceeds the limit. in such case, print an ellipsis
+func (p *pretty) tooDeep(depth int, open string, close string) bool {
+	if max := p.limits.MaxDepth; max > 0 && depth >= max {
+		p.str(open)
+		p.str("...")
+		p.str(close)
+		return true
+	}
+	return false
+}
+
+// return true if i exceeds the maximum number of elements. in such case, print how many are omitted
+func (p *pretty) tooMany(i int, n int) bool {
+	if max := p.limits.MaxElems; max > 0 && i >= max {
+		p.str(fmt.Sprintf("... %d more", n-i))
+		return true
+	}
+	return false
+}
+
+// print an array or slice
+func (p *pretty) list(v r.Value, depth int) {
+	if p.tooDeep(depth, "[", "]") {
+		return
+	}
+	n := v.Len()
+	multiline := p.limits.Multiline && n != 0 && isComposite(v.Type().Elem().Kind())
+	p.str("[")
+	for i := 0; i < n; i++ {
+		if multiline {
+			p.newline(depth + 1)
+		} else if i != 0 {
+			p.str(" ")
+		}
+		if p.tooMany(i, n) {
+			break
+		}
+		p.value(v.Index(i), depth+1)
+	}
+	if multiline {
+		p.newline(depth)
+	}
+	p.str("]")
+}
+
+// print a map, sorting its keys when possible
+func (p *pretty) dict(v r.Value, depth int) {
+	if p.tooDeep(depth, "map[", "]") {
+		return
+	}
+	keys := reflect.SortedMapKeys(v)
+	n := len(keys)
+	multiline := p.limits.Multiline
+	p.str("map[")
+	for i, key := range keys {
+		if multiline {
+			p.newline(depth + 1)
+		} else if i != 0 {
+			p.str(" ")
+		}
+		if p.tooMany(i, n) {
+			break
+		}
+		p.value(key, depth+1)
+		p.str(":")
+		p.value(v.MapIndex(key), depth+1)
+	}
+	if multiline {
+		p.newline(depth)
+	}
+	p.str("]")
+}
+
+// print a struct
+func (p *pretty) fields(v r.Value, depth int) {
+	n := v.NumField()
+	if n == 0 {
+		p.str("{}")
+		return
+	} else if p.tooDeep(depth, "{", "}") {
+		return
+	}
+	t := v.Type()
+	multiline := p.limits.Multiline
+	p.str("{")
+	for i := 0; i < n; i++ {
+		if multiline {
+			p.newline(depth + 1)
+		} else if i != 0 {
+			p.str(" ")
+		}
+		if p.tooMany(i, n) {
+			break
+		}
+		p.str(fieldName(t.Field(i).Name))
+		p.str(":")
+		p.value(v.Field(i), depth+1)
+	}
+	if multiline {
+		p.newline(depth)
+	}
+	p.str("}")
+}
+
+// return the name of a struct field as written in the source code,
+// removing the prefix added to unexported fields of interpreted structs
+func fieldName(name string) string {
+	return strings.TrimPrefix(name, xr.StrGensymPrivate)
+}
+
+func isComposite(k r.Kind) bool {
+	switch k {
+	case r.Array, r.Interface, r.Map, r.Ptr, r.Slice, r.Struct:
+		return true
+	}
+	return false
+}
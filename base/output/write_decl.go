@@ -1,24 +1,33 @@
 package output
 
 import (
+	"bytes"
 	"fmt"
 	"go/ast"
+	"go/token"
 	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/cosmos72/gomacro/printer"
 )
 
-func (o *Output) WriteDeclsToStream(out io.Writer, packagePath string,
-	imports []*ast.GenDecl, declarations []ast.Decl, statements []ast.Stmt) {
+// WriteDeclsToStream writes a Go source file containing the imports, declarations and statements.
+// Each declaration is preceded by a //line directive pointing to its original source file,
+// relative to dir i.e. the directory of the written file, and printed with the comments it contains.
+func (o *Output) WriteDeclsToStream(out io.Writer, dir string, packagePath string,
+	imports []*ast.GenDecl, declarations []ast.Decl, statements []ast.Stmt, comments []*ast.CommentGroup) {
 
 	fmt.Fprintf(out, "package %s\n\n", packagePath)
 
 	for _, imp := range imports {
-		fmt.Fprintln(out, o.toPrintable("%v", imp))
+		fmt.Fprintln(out, o.declToPrintable("", imp, comments))
 	}
 	if len(imports) != 0 {
 		fmt.Fprintln(out)
 	}
 	for _, decl := range declarations {
-		fmt.Fprintln(out, o.toPrintable("%v", decl))
+		fmt.Fprintln(out, o.declToPrintable(dir, decl, comments))
 	}
 	if len(statements) != 0 {
 		fmt.Fprint(out, "\nfunc init() {\n")
@@ -32,3 +41,96 @@ func (o *Output) WriteDeclsToStream(out io.Writer, packagePath string,
 		fmt.Fprint(out, "}\n")
 	}
 }
+
+// return the comments inside node, including its doc comment
+func nodeComments(node ast.Node, comments []*ast.CommentGroup) []*ast.CommentGroup {
+	start, end := nodeStart(node), node.End()
+	var list []*ast.CommentGroup
+	for _, comment := range comments {
+		if comment.Pos() >= start && comment.End() <= end {
+			list = append(list, comment)
+		}
+	}
+	return list
+}
+
+// return the position of node, including its doc comment
+func nodeStart(node ast.Node) token.Pos {
+	if doc := docComment(node); doc != nil {
+		return doc.Pos()
+	}
+	return node.Pos()
+}
+
+func setDocComment(node ast.Node, doc *ast.CommentGroup) {
+	switch node := node.(type) {
+	case *ast.GenDecl:
+		node.Doc = doc
+	case *ast.FuncDecl:
+		node.Doc = doc
+	}
+}
+
+// return the doc comment of a declaration, or nil if it has none
+func docComment(node ast.Node) *ast.CommentGroup {
+	var doc *ast.CommentGroup
+	switch node := node.(type) {
+	case *ast.GenDecl:
+		doc = node.Doc
+	case *ast.FuncDecl:
+		doc = node.Doc
+	}
+	if doc != nil && doc.Pos().IsValid() && doc.Pos() < node.Pos() {
+		return doc
+	}
+	return nil
+}
+
+// print decl with the comments it contains.
+// if dir is not empty, insert a //line directive between the doc comment and the declaration
+func (o *Output) declToPrintable(dir string, decl ast.Node, comments []*ast.CommentGroup) interface{} {
+	var line string
+	if len(dir) != 0 {
+		line = o.lineDirective(dir, decl)
+	}
+	if len(line) == 0 {
+		return o.commentedToPrintable(decl, nodeComments(decl, comments))
+	}
+	var buf bytes.Buffer
+	if doc := docComment(decl); doc != nil {
+		// print the doc comment before the //line directive
+		for _, comment := range doc.List {
+			fmt.Fprintln(&buf, comment.Text)
+		}
+		setDocComment(decl, nil)
+		defer setDocComment(decl, doc)
+	}
+	fmt.Fprintln(&buf, line)
+	fmt.Fprint(&buf, o.commentedToPrintable(decl, nodeComments(decl, comments)))
+	return buf.String()
+}
+
+func (o *Output) commentedToPrintable(node ast.Node, comments []*ast.CommentGroup) interface{} {
+	if len(comments) == 0 {
+		return o.toPrintable("%v", node)
+	}
+	return o.nodeToPrintable(&printer.CommentedNode{Node: node, Comments: comments})
+}
+
+// return the //line directive for decl, or "" if its original source file is unknown
+func (o *Output) lineDirective(dir string, decl ast.Node) string {
+	pos := decl.Pos()
+	if !pos.IsValid() || o.Fileset == nil {
+		return ""
+	}
+	position := o.Fileset.Position(pos)
+	filename := position.Filename
+	if _, err := os.Stat(filename); err != nil {
+		// for example repl.go
+		return ""
+	}
+	if rel, err := filepath.Rel(dir, filename); err == nil {
+		filename = rel
+	}
+	return fmt.Sprintf("//line %s:%d", filepath.ToSlash(filename), position.Line)
+}
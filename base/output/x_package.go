@@ -13,17 +13,37 @@ func init() {
 	imports.Packages["github.com/cosmos72/gomacro/base/output"] = imports.Package{
 	Binds: map[string]r.Value{
 		"Debugf":	r.ValueOf(Debugf),
+		"DefaultPrintLimits":	r.ValueOf(&DefaultPrintLimits).Elem(),
 		"Error":	r.ValueOf(Error),
 		"Errorf":	r.ValueOf(Errorf),
+		"IsMimeText":	r.ValueOf(IsMimeText),
 		"MakeRuntimeError":	r.ValueOf(MakeRuntimeError),
+		"MimeText":	r.ValueOf(MimeText),
 		"ShowPackageHeader":	r.ValueOf(ShowPackageHeader),
 		"Warnf":	r.ValueOf(Warnf),
 	}, Types: map[string]r.Type{
+		"DisplayFunc":	r.TypeOf((*DisplayFunc)(nil)).Elem(),
+		"Displayer":	r.TypeOf((*Displayer)(nil)).Elem(),
 		"Output":	r.TypeOf((*Output)(nil)).Elem(),
+		"PrintLimits":	r.TypeOf((*PrintLimits)(nil)).Elem(),
 		"RuntimeError":	r.TypeOf((*RuntimeError)(nil)).Elem(),
 		"Stringer":	r.TypeOf((*Stringer)(nil)).Elem(),
+		"XDisplay":	r.TypeOf((*XDisplay)(nil)).Elem(),
+	}, Proxies: map[string]r.Type{
+		"Displayer":	r.TypeOf((*P_github_com_cosmos72_gomacro_base_output_Displayer)(nil)).Elem(),
+	}, Untypeds: map[string]string{
+		"MimeText":	"string:text/plain",
 	}, Wrappers: map[string][]string{
-		"Output":	[]string{"Copy","ErrorAt","Errorf","Fprintf","IncLine","IncLineBytes","MakeRuntimeError","Position","Sprintf","ToString",},
+		"Output":	[]string{"Copy","Display","ErrorAt","Errorf","Fprintf","IncLine","IncLineBytes","MakeRuntimeError","Position","Pretty","SetDisplay","Sprintf","ToString",},
 	}, 
 	}
 }
+
+// --------------- proxy for github.com/cosmos72/gomacro/base/output.Displayer ---------------
+type P_github_com_cosmos72_gomacro_base_output_Displayer struct {
+	Object	interface{}
+	GomacroDisplay_	func(_proxy_obj_ interface{}) (mime string, data []byte)
+}
+func (P *P_github_com_cosmos72_gomacro_base_output_Displayer) GomacroDisplay() (mime string, data []byte) {
+	return P.GomacroDisplay_(P.Object)
+}
@@ -0,0 +1,82 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * z_test.go
+ *
+ *  Created on Oct 19, 2026
+ *      Author agent
+ */
+
+package output
+
+import (
+	r "reflect"
+	"testing"
+
+	xr "github.com/cosmos72/gomacro/xreflect"
+)
+
+type prettyNode struct {
+	Val  int
+	Next *prettyNode
+}
+
+func TestPretty(t *testing.T) {
+	cycle := &prettyNode{Val: 1}
+	cycle.Next = &prettyNode{Val: 2, Next: cycle}
+
+	private := r.New(r.StructOf([]r.StructField{
+		{Name: "X", Type: r.TypeOf(0)},
+		{Name: xr.StrGensymPrivate + "y", PkgPath: "main", Type: r.TypeOf(0)},
+	})).Elem()
+	private.Field(0).SetInt(3)
+
+	tests := []struct {
+		name   string
+		limits PrintLimits
+		value  interface{}
+		expect string
+	}{
+		{"unlimited", PrintLimits{}, []int{1, 2, 3}, "[1 2 3]"},
+		{"max_elems", PrintLimits{MaxElems: 2}, []int{1, 2, 3, 4}, "[1 2 ... 2 more]"},
+		{"max_depth", PrintLimits{MaxDepth: 2}, [][][]int{{{1}}}, "[[[...]]]"},
+		{"max_string", PrintLimits{MaxString: 3}, "abcdef", "abc... 3 more bytes"},
+		{"max_string_utf8", PrintLimits{MaxString: 4}, "aèèè", "aè... 4 more bytes"},
+		{"map", PrintLimits{}, map[string]int{"b": 2, "a": 1}, "map[a:1 b:2]"},
+		{"cycle", PrintLimits{}, cycle, "<ref *1> &{Val:1 Next:&{Val:2 Next:<cycle *1>}}"},
+		{"private_field", PrintLimits{}, private, "{X:3 y:0}"},
+		{"multiline", PrintLimits{Multiline: true}, map[string][]int{"a": {1}}, "map[\n    a:[1]\n]"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			st := Stringer{Limits: test.limits}
+			if actual := st.Pretty(test.value); actual != test.expect {
+				t.Errorf("expecting %q, found %q", test.expect, actual)
+			}
+		})
+	}
+}
+
+func TestPrintLimitsSet(t *testing.T) {
+	l := DefaultPrintLimits
+	for _, setting := range []string{"Print.MaxDepth=3", "Print.MaxElems=0", "Print.MaxString=7"} {
+		if err := l.Set(setting); err != nil {
+			t.Errorf("Set(%q) failed: %v", setting, err)
+		}
+	}
+	if expect, actual := "Print.MaxDepth=3 Print.MaxElems=0 Print.MaxString=7", l.String(); actual != expect {
+		t.Errorf("expecting %q, found %q", expect, actual)
+	}
+	for _, setting := range []string{"Print.MaxDepth", "Print.Foo=1", "Print.MaxElems=-1", "Print.MaxString=x"} {
+		if err := l.Set(setting); err == nil {
+			t.Errorf("Set(%q) should fail", setting)
+		}
+	}
+}
@@ -0,0 +1,290 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * profile.go
+ *
+ *  Created on Oct 19, 2026
+ *      Author agent
+ */
+
+// Package profile builds profiles in the format read by 'go tool pprof',
+// i.e. gzip-compressed protocol buffers described by
+// https://github.com/google/pprof/blob/master/proto/profile.proto
+package profile
+
+import (
+	"compress/gzip"
+	"io"
+	"time"
+)
+
+// Frame is a stack frame: a source line inside a function
+type Frame struct {
+	Func string
+	File string
+	Line int
+}
+
+// ValueType describes the type and unit of a sample value,
+// for example {"cpu", "nanoseconds"}
+type ValueType struct {
+	Type string
+	Unit string
+}
+
+type funcKey struct {
+	name string
+	file string
+}
+
+type sample struct {
+	locs   []uint64
+	values []int64
+}
+
+// Builder accumulates samples and writes them in pprof format
+type Builder struct {
+	SampleTypes []ValueType
+	PeriodType  ValueType
+	Period      int64
+	Start       time.Time
+	strings     map[string]int64
+	stringList  []string
+	funcs       map[funcKey]uint64
+	funcList    []funcKey
+	locs        map[Frame]uint64
+	locList     []Frame
+	samples     map[string]*sample
+	sampleList  []*sample
+}
+
+// NewBuilder creates a Builder with given sample types, period type and period
+func NewBuilder(sampleTypes []ValueType, periodType ValueType, period int64) *Builder {
+	return &Builder{
+		SampleTypes: sampleTypes,
+		PeriodType:  periodType,
+		Period:      period,
+		Start:       time.Now(),
+		strings:     map[string]int64{"": 0},
+		stringList:  []string{""},
+		funcs:       make(map[funcKey]uint64),
+		locs:        make(map[Frame]uint64),
+		samples:     make(map[string]*sample),
+	}
+}
+
+// Add adds values to the sample with given stack. stack[0] is the innermost frame.
+// len(values) must be equal to len(b.SampleTypes)
+func (b *Builder) Add(stack []Frame, values ...int64) {
+	locs := make([]uint64, len(stack))
+	for i, frame := range stack {
+		locs[i] = b.location(frame)
+	}
+	key := locsKey(locs)
+	s := b.samples[key]
+	if s == nil {
+		s = &sample{locs: locs, values: make([]int64, len(b.SampleTypes))}
+		b.samples[key] = s
+		b.sampleList = append(b.sampleList, s)
+	}
+	for i, v := range values {
+		if i < len(s.values) {
+			s.values[i] += v
+		}
+	}
+}
+
+func locsKey(locs []uint64) string {
+	var enc encoder
+	for _, loc := range locs {
+		enc.varint(loc)
+	}
+	return string(enc.data)
+}
+
+func (b *Builder) string(s string) int64 {
+	id, ok := b.strings[s]
+	if !ok {
+		id = int64(len(b.stringList))
+		b.strings[s] = id
+		b.stringList = append(b.stringList, s)
+	}
+	return id
+}
+
+func (b *Builder) function(name, file string) uint64 {
+	key := funcKey{name, file}
+	id, ok := b.funcs[key]
+	if !ok {
+		b.funcList = append(b.funcList, key)
+		id = uint64(len(b.funcList))
+		b.funcs[key] = id
+		b.string(name)
+		b.string(file)
+	}
+	return id
+}
+
+func (b *Builder) location(frame Frame) uint64 {
+	id, ok := b.locs[frame]
+	if !ok {
+		b.function(frame.Func, frame.File)
+		b.locList = append(b.locList, frame)
+		id = uint64(len(b.locList))
+		b.locs[frame] = id
+	}
+	return id
+}
+
+// Write writes the accumulated samples to out, gzip-compressed
+func (b *Builder) Write(out io.Writer) error {
+	zout := gzip.NewWriter(out)
+	if _, err := zout.Write(b.Encode()); err != nil {
+		return err
+	}
+	return zout.Close()
+}
+
+// field numbers from profile.proto
+const (
+	profileSampleType    = 1
+	profileSample        = 2
+	profileLocation      = 4
+	profileFunction      = 5
+	profileStringTable   = 6
+	profileTimeNanos     = 9
+	profileDurationNanos = 10
+	profilePeriodType    = 11
+	profilePeriod        = 12
+
+	valueTypeType = 1
+	valueTypeUnit = 2
+
+	sampleLocationID = 1
+	sampleValue      = 2
+
+	locationID   = 1
+	locationLine = 4
+
+	lineFunctionID = 1
+	lineLine       = 2
+
+	functionID         = 1
+	functionName       = 2
+	functionSystemName = 3
+	functionFilename   = 4
+)
+
+// Encode returns the accumulated samples as an uncompressed protocol buffer
+func (b *Builder) Encode() []byte {
+	var enc encoder
+	for _, vt := range b.SampleTypes {
+		enc.message(profileSampleType, b.valueType(vt))
+	}
+	for _, s := range b.sampleList {
+		var m encoder
+		m.packedUint64(sampleLocationID, s.locs)
+		m.packedInt64(sampleValue, s.values)
+		enc.message(profileSample, m.data)
+	}
+	for i, frame := range b.locList {
+		var line encoder
+		line.uint64(lineFunctionID, b.funcs[funcKey{frame.Func, frame.File}])
+		line.int64(lineLine, int64(frame.Line))
+		var m encoder
+		m.uint64(locationID, uint64(i+1))
+		m.message(locationLine, line.data)
+		enc.message(profileLocation, m.data)
+	}
+	for i, key := range b.funcList {
+		var m encoder
+		m.uint64(functionID, uint64(i+1))
+		m.int64(functionName, b.strings[key.name])
+		m.int64(functionSystemName, b.strings[key.name])
+		m.int64(functionFilename, b.strings[key.file])
+		enc.message(profileFunction, m.data)
+	}
+	// the string table must be complete: encode it after everything else
+	periodType := b.valueType(b.PeriodType)
+	for _, s := range b.stringList {
+		enc.bytes(profileStringTable, []byte(s))
+	}
+	enc.int64(profileTimeNanos, b.Start.UnixNano())
+	enc.int64(profileDurationNanos, int64(time.Since(b.Start)))
+	enc.message(profilePeriodType, periodType)
+	enc.int64(profilePeriod, b.Period)
+	return enc.data
+}
+
+func (b *Builder) valueType(vt ValueType) []byte {
+	var m encoder
+	m.int64(valueTypeType, b.string(vt.Type))
+	m.int64(valueTypeUnit, b.string(vt.Unit))
+	return m.data
+}
+
+// ================================= encoder =================================
+
+// minimal protocol buffer encoder
+type encoder struct {
+	data []byte
+}
+
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+func (e *encoder) varint(x uint64) {
+	for x >= 0x80 {
+		e.data = append(e.data, byte(x)|0x80)
+		x >>= 7
+	}
+	e.data = append(e.data, byte(x))
+}
+
+func (e *encoder) tag(field int, wire int) {
+	e.varint(uint64(field)<<3 | uint64(wire))
+}
+
+func (e *encoder) uint64(field int, x uint64) {
+	e.tag(field, wireVarint)
+	e.varint(x)
+}
+
+func (e *encoder) int64(field int, x int64) {
+	e.uint64(field, uint64(x))
+}
+
+func (e *encoder) bytes(field int, b []byte) {
+	e.tag(field, wireBytes)
+	e.varint(uint64(len(b)))
+	e.data = append(e.data, b...)
+}
+
+func (e *encoder) message(field int, m []byte) {
+	e.bytes(field, m)
+}
+
+func (e *encoder) packedUint64(field int, xs []uint64) {
+	var m encoder
+	for _, x := range xs {
+		m.varint(x)
+	}
+	e.bytes(field, m.data)
+}
+
+func (e *encoder) packedInt64(field int, xs []int64) {
+	var m encoder
+	for _, x := range xs {
+		m.varint(uint64(x))
+	}
+	e.bytes(field, m.data)
+}
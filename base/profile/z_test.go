@@ -0,0 +1,157 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * z_test.go
+ *
+ *  Created on Oct 19, 2026
+ *      Author agent
+ */
+
+package profile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+// field of a protocol buffer message: a varint or a byte slice
+type field struct {
+	num   int
+	x     uint64
+	bytes []byte
+}
+
+func readVarint(t *testing.T, data *[]byte) uint64 {
+	var x uint64
+	for shift := uint(0); ; shift += 7 {
+		if len(*data) == 0 {
+			t.Fatalf("truncated varint")
+		}
+		b := (*data)[0]
+		*data = (*data)[1:]
+		x |= uint64(b&0x7f) << shift
+		if b < 0x80 {
+			return x
+		}
+	}
+}
+
+// minimal protocol buffer decoder: returns the fields of a message
+func decode(t *testing.T, data []byte) []field {
+	var fields []field
+	for len(data) != 0 {
+		tag := readVarint(t, &data)
+		f := field{num: int(tag >> 3)}
+		switch tag & 7 {
+		case wireVarint:
+			f.x = readVarint(t, &data)
+		case wireBytes:
+			n := readVarint(t, &data)
+			if uint64(len(data)) < n {
+				t.Fatalf("truncated field %d", f.num)
+			}
+			f.bytes, data = data[:n], data[n:]
+		default:
+			t.Fatalf("unexpected wire type %d", tag&7)
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+func decodePacked(t *testing.T, data []byte) []uint64 {
+	var xs []uint64
+	for len(data) != 0 {
+		xs = append(xs, readVarint(t, &data))
+	}
+	return xs
+}
+
+func TestEncoder(t *testing.T) {
+	var enc encoder
+	enc.uint64(1, 300)
+	enc.bytes(2, []byte("ab"))
+	enc.packedInt64(3, []int64{1, 128})
+	expect := []byte{0x08, 0xac, 0x02, 0x12, 2, 'a', 'b', 0x1a, 3, 1, 0x80, 0x01}
+	if !bytes.Equal(enc.data, expect) {
+		t.Errorf("expecting % x, found % x", expect, enc.data)
+	}
+}
+
+func TestBuilder(t *testing.T) {
+	b := NewBuilder([]ValueType{{"samples", "count"}, {"cpu", "nanoseconds"}}, ValueType{"cpu", "nanoseconds"}, 10)
+	f := Frame{Func: "main.f", File: "a.go", Line: 3}
+	g := Frame{Func: "main.g", File: "a.go", Line: 7}
+	b.Add([]Frame{f, g}, 1, 10)
+	b.Add([]Frame{g}, 1, 10)
+	b.Add([]Frame{f, g}, 1, 10)
+
+	var strings []string
+	var samples [][]uint64
+	var nlocs, nfuncs int
+	var period uint64
+	for _, fld := range decode(t, b.Encode()) {
+		switch fld.num {
+		case profileStringTable:
+			strings = append(strings, string(fld.bytes))
+		case profileSample:
+			var sample []uint64
+			for _, sf := range decode(t, fld.bytes) {
+				sample = append(sample, decodePacked(t, sf.bytes)...)
+			}
+			samples = append(samples, sample)
+		case profileLocation:
+			nlocs++
+		case profileFunction:
+			nfuncs++
+		case profilePeriod:
+			period = fld.x
+		}
+	}
+	// each sample contains its location ids followed by its values
+	expectSamples := [][]uint64{{1, 2, 2, 20}, {2, 1, 10}}
+	if !reflect.DeepEqual(samples, expectSamples) {
+		t.Errorf("expecting samples %v, found %v", expectSamples, samples)
+	}
+	expectStrings := []string{"", "main.f", "a.go", "main.g", "samples", "count", "cpu", "nanoseconds"}
+	if !reflect.DeepEqual(strings, expectStrings) {
+		t.Errorf("expecting string table %q, found %q", expectStrings, strings)
+	}
+	if nlocs != 2 || nfuncs != 2 || period != 10 {
+		t.Errorf("expecting 2 locations, 2 functions and period 10, found %d, %d and %d", nlocs, nfuncs, period)
+	}
+}
+
+func TestBuilderWrite(t *testing.T) {
+	b := NewBuilder([]ValueType{{"samples", "count"}}, ValueType{"samples", "count"}, 1)
+	b.Add([]Frame{{Func: "main.f"}}, 1)
+	var buf bytes.Buffer
+	if err := b.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	zin, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(zin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, f := range decode(t, data) {
+		found = found || (f.num == profileStringTable && string(f.bytes) == "main.f")
+	}
+	if !found {
+		t.Errorf("function name not found in decompressed profile")
+	}
+}
@@ -130,6 +130,21 @@ func (m mode) String() string {
 	}
 }
 
+// return true if line, which follows ~", is the rest of a macro import path ~"path"
+func isMacroImportPath(line []byte) bool {
+	end := bytes.IndexByte(line, '"')
+	if end <= 0 || bytes.IndexAny(line[:end], " \\\t") >= 0 {
+		return false
+	}
+	rest := bytes.TrimSpace(line[end+1:])
+	return len(rest) == 0 || rest[0] == ';' || rest[0] == ')' || bytes.HasPrefix(rest, []byte("//"))
+}
+
+// return true if line is a //gomacro:macros directive
+func isMacroDirective(line []byte) bool {
+	return bytes.Equal(bytes.TrimSpace(line), []byte("//gomacro:macros"))
+}
+
 // return read string, position of first non-comment token and error (if any)
 // on EOF, return "", -1, io.EOF
 func ReadMultiline(in Readline, opts ReadOptions, prompt string) (src string, firstToken int, err error) {
@@ -274,6 +289,11 @@ func ReadMultiline(in Readline, opts ReadOptions, prompt string) (src string, fi
 				switch ch {
 				case '/':
 					m = mLineComment
+					if isMacroDirective(line[i-1:]) {
+						// //gomacro:macros is part of the following import: keep reading
+						foundtoken(i - 1)
+						ignorenl = true
+					}
 					continue // no tokens
 				case '*':
 					m = mComment
@@ -315,6 +335,10 @@ func ReadMultiline(in Readline, opts ReadOptions, prompt string) (src string, fi
 				continue
 			case mTilde:
 				m = mNormal
+				if ch == '"' && isMacroImportPath(line[i+1:]) {
+					// macro import ~"path", not ~"quasiquote
+					m = mString
+				}
 			}
 			if debug {
 				output.Debugf("ReadMultiline:          \tmode=%v\tparen=%d ignorenl=%t resetnl=%t", m, paren, ignorenl, resetnl(paren, m))
@@ -336,6 +360,11 @@ func ReadMultiline(in Readline, opts ReadOptions, prompt string) (src string, fi
 		if err != nil {
 			break
 		}
+		if firstToken >= 0 && buf[firstToken] == ':' {
+			// interpreter commands as ":trace func" or ":trace main.*" end at newline,
+			// even if their last word is a keyword or their last character is an operator
+			break
+		}
 		if paren <= 0 && !ignorenl && m == mNormal && (firstToken >= 0 || !optAllComments) {
 			if firstToken >= 0 && lastIsKeywordIgnoresNl(line, firstToken, lastToken) {
 				ignorenl = true
@@ -17,7 +17,9 @@
 package reflect
 
 import (
+	"fmt"
 	r "reflect"
+	"sort"
 
 	xr "github.com/cosmos72/gomacro/xreflect"
 )
@@ -188,3 +190,36 @@ func IsNillableKind(k r.Kind) bool {
 		return false
 	}
 }
+
+// SortedMapKeys returns the keys of map v, sorted when possible.
+// Keys without a natural order are sorted by their printed representation
+func SortedMapKeys(v r.Value) []r.Value {
+	keys := v.MapKeys()
+	var less func(a, b r.Value) bool
+	switch Category(v.Type().Key().Kind()) {
+	case r.Bool:
+		less = func(a, b r.Value) bool { return !a.Bool() && b.Bool() }
+	case r.Int:
+		less = func(a, b r.Value) bool { return a.Int() < b.Int() }
+	case r.Uint:
+		less = func(a, b r.Value) bool { return a.Uint() < b.Uint() }
+	case r.Float64:
+		less = func(a, b r.Value) bool { return a.Float() < b.Float() }
+	case r.String:
+		less = func(a, b r.Value) bool { return a.String() < b.String() }
+	default:
+		strs := make(map[r.Value]string, len(keys))
+		for _, key := range keys {
+			if key.CanInterface() {
+				strs[key] = fmt.Sprintf("%#v", key.Interface())
+			} else {
+				strs[key] = fmt.Sprint(key)
+			}
+		}
+		less = func(a, b r.Value) bool { return strs[a] < strs[b] }
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		return less(keys[i], keys[j])
+	})
+	return keys
+}
@@ -23,6 +23,7 @@ func init() {
 			"None":             r.ValueOf(&None).Elem(),
 			"PackTypes":        r.ValueOf(PackTypes),
 			"PackValues":       r.ValueOf(PackValues),
+			"SortedMapKeys":    r.ValueOf(SortedMapKeys),
 			"TypeOfBool":       r.ValueOf(&TypeOfBool).Elem(),
 			"TypeOfComplex128": r.ValueOf(&TypeOfComplex128).Elem(),
 			"TypeOfComplex64":  r.ValueOf(&TypeOfComplex64).Elem(),
@@ -58,6 +58,7 @@ const (
 	SigReturn
 	SigInterrupt // user pressed Ctrl+C, process received SIGINT, or similar
 	SigDebug     // debugger asked to execute in single-step mode
+	SigProfile   // profiler asked to record the interpreted call stack
 
 	SigNone = Signal(0) // no signal
 	SigAll  = ^SigNone  // mask of all possible signals
@@ -76,6 +77,8 @@ func (sig Signal) String() string {
 		s = "// signal: interrupt"
 	case SigDebug:
 		s = "// signal: debug"
+	case SigProfile:
+		s = "// signal: profile"
 	default:
 		s = fmt.Sprintf("// signal: unknown(%d)", uint16(sig))
 	}
@@ -92,3 +95,20 @@ type Signals struct {
 func (s *Signals) IsEmpty() bool {
 	return atomic.LoadUint32((*uint32)(unsafe.Pointer(s))) == 0
 }
+
+// CompareAndSwapAsync atomically sets s.Async to new if it is equal to old,
+// and returns true if it was set. Use it to send signals from other goroutines
+func (s *Signals) CompareAndSwapAsync(old Signal, new Signal) bool {
+	addr := (*uint32)(unsafe.Pointer(s))
+	for {
+		word := atomic.LoadUint32(addr)
+		tmp := *(*Signals)(unsafe.Pointer(&word))
+		if tmp.Async != old {
+			return false
+		}
+		tmp.Async = new
+		if atomic.CompareAndSwapUint32(addr, word, *(*uint32)(unsafe.Pointer(&tmp))) {
+			return true
+		}
+	}
+}
@@ -31,7 +31,7 @@ const (
 	OptDebugger           // enable debugger support. "break" and _ = "break" are breakpoints and enter the debugger
 	OptKeepUntyped
 	OptMacroExpandOnly // do not compile or execute code, only parse and macroexpand it
-	OptPanicStackTrace
+	OptPanicStackTrace // on panic, collect the interpreted stack trace. slower: all code runs with the same support as code that uses defer
 	OptTrapPanic
 	OptDebugCallStack
 	OptDebugDebugger // print debug information related to the debugger
@@ -51,6 +51,11 @@ const (
 	OptShowParse
 	OptShowPrompt
 	OptShowTime
+	OptPanicDebugger  // on panic, enter the debugger before unwinding the stack. requires OptDebugger. slower, as OptPanicStackTrace
+	OptCoverage       // record which statements are executed. see fast.Interp.WriteCoverage
+	OptPprofLabels    // apply runtime/pprof labels and runtime/trace regions when entering interpreted functions
+	OptPrintMultiline // print results on multiple lines, indenting nested structs and maps. see output.PrintLimits
+	OptMacroHygiene   // rename the identifiers declared by ~quasiquote templates. see fast.Comp.Quasiquote
 )
 
 const (
@@ -86,6 +91,11 @@ var optNames = map[Options]string{
 	OptShowParse:           "Parse.Show",
 	OptShowPrompt:          "Prompt.Show",
 	OptShowTime:            "Time.Show",
+	OptPanicDebugger:       "Panic.Debugger",
+	OptCoverage:            "Coverage",
+	OptPprofLabels:         "Pprof.Labels",
+	OptPrintMultiline:      "Print.Multiline",
+	OptMacroHygiene:        "Macro.Hygiene",
 }
 
 var optValues = map[string]Options{}
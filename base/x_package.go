@@ -42,6 +42,7 @@ func init() {
 		"One":	r.ValueOf(&One).Elem(),
 		"OptCollectDeclarations":	r.ValueOf(OptCollectDeclarations),
 		"OptCollectStatements":	r.ValueOf(OptCollectStatements),
+		"OptCoverage":	r.ValueOf(OptCoverage),
 		"OptCtrlCEnterDebugger":	r.ValueOf(OptCtrlCEnterDebugger),
 		"OptDebugCallStack":	r.ValueOf(OptDebugCallStack),
 		"OptDebugDebugger":	r.ValueOf(OptDebugDebugger),
@@ -57,7 +58,11 @@ func init() {
 		"OptDebugger":	r.ValueOf(OptDebugger),
 		"OptKeepUntyped":	r.ValueOf(OptKeepUntyped),
 		"OptMacroExpandOnly":	r.ValueOf(OptMacroExpandOnly),
+		"OptMacroHygiene":	r.ValueOf(OptMacroHygiene),
+		"OptPanicDebugger":	r.ValueOf(OptPanicDebugger),
 		"OptPanicStackTrace":	r.ValueOf(OptPanicStackTrace),
+		"OptPprofLabels":	r.ValueOf(OptPprofLabels),
+		"OptPrintMultiline":	r.ValueOf(OptPrintMultiline),
 		"OptShowCompile":	r.ValueOf(OptShowCompile),
 		"OptShowEval":	r.ValueOf(OptShowEval),
 		"OptShowEvalType":	r.ValueOf(OptShowEvalType),
@@ -77,6 +82,7 @@ func init() {
 		"SigDefer":	r.ValueOf(SigDefer),
 		"SigInterrupt":	r.ValueOf(SigInterrupt),
 		"SigNone":	r.ValueOf(SigNone),
+		"SigProfile":	r.ValueOf(SigProfile),
 		"SigReturn":	r.ValueOf(SigReturn),
 		"SimplifyAstForQuote":	r.ValueOf(SimplifyAstForQuote),
 		"SimplifyNodeForQuote":	r.ValueOf(SimplifyNodeForQuote),
@@ -138,6 +144,7 @@ func init() {
 		"CmdOpt":	r.TypeOf((*CmdOpt)(nil)).Elem(),
 		"Globals":	r.TypeOf((*Globals)(nil)).Elem(),
 		"Inspector":	r.TypeOf((*Inspector)(nil)).Elem(),
+		"InspectorWithEval":	r.TypeOf((*InspectorWithEval)(nil)).Elem(),
 		"Options":	r.TypeOf((*Options)(nil)).Elem(),
 		"Output":	r.TypeOf((*Output)(nil)).Elem(),
 		"ReadOptions":	r.TypeOf((*ReadOptions)(nil)).Elem(),
@@ -153,8 +160,8 @@ func init() {
 		"CmdOptForceEval":	"int:2",
 		"CmdOptQuit":	"int:1",
 	}, Wrappers: map[string][]string{
-		"Globals":	[]string{"Copy","Debugf","Error","ErrorAt","Errorf","Fprintf","IncLine","IncLineBytes","MakeRuntimeError","Position","Sprintf","ToString","WarnExtraValues","Warnf",},
-		"Output":	[]string{"Copy","ErrorAt","Errorf","Fprintf","IncLine","IncLineBytes","MakeRuntimeError","Position","Sprintf","ToString",},
+		"Globals":	[]string{"Copy","Debugf","Display","Error","ErrorAt","Errorf","Fprintf","IncLine","IncLineBytes","MakeRuntimeError","Position","Pretty","SetDisplay","Sprintf","ToString","WarnExtraValues","Warnf",},
+		"Output":	[]string{"Copy","Display","ErrorAt","Errorf","Fprintf","IncLine","IncLineBytes","MakeRuntimeError","Position","Pretty","SetDisplay","Sprintf","ToString",},
 	}, 
 	}
 }
@@ -0,0 +1,116 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * z_test.go
+ *
+ *  Created on Oct 19, 2026
+ *      Author agent
+ */
+
+package base
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	r "reflect"
+	"strings"
+	"testing"
+
+	"github.com/cosmos72/gomacro/base/output"
+)
+
+func TestReadMultiline(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		expect string // first source read
+	}{
+		{"statement", "x := 1\ny := 2\n", "x := 1\n"},
+		{"continued_operator", "x := 1 +\n2\n", "x := 1 +\n2\n"},
+		{"continued_keyword", "func\nf() {}\n", "func\nf() {}\n"},
+		{"continued_paren", "f(1,\n2)\n", "f(1,\n2)\n"},
+		{"cmd_keyword", ":trace func\nx\n", ":trace func\n"},
+		{"cmd_operator", ":trace main.*\nx\n", ":trace main.*\n"},
+		{"cmd_paren", ":env (\nx\n", ":env (\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			in := MakeBufReadline(bufio.NewReader(strings.NewReader(test.input)), ioutil.Discard)
+			src, _, err := ReadMultiline(in, 0, "")
+			if err != nil {
+				t.Errorf("ReadMultiline(%q) failed: %v", test.input, err)
+			} else if src != test.expect {
+				t.Errorf("ReadMultiline(%q) returned %q, expecting %q", test.input, src, test.expect)
+			}
+		})
+	}
+}
+
+func TestSignalsCompareAndSwapAsync(t *testing.T) {
+	s := Signals{Sync: SigReturn, Debug: SigDebug}
+	if !s.CompareAndSwapAsync(SigNone, SigProfile) || s.Async != SigProfile {
+		t.Errorf("CompareAndSwapAsync(SigNone, SigProfile) failed: %+v", s)
+	}
+	if s.CompareAndSwapAsync(SigNone, SigInterrupt) || s.Async != SigProfile {
+		t.Errorf("CompareAndSwapAsync(SigNone, SigInterrupt) should fail: %+v", s)
+	}
+	if !s.CompareAndSwapAsync(SigProfile, SigNone) || s != (Signals{Sync: SigReturn, Debug: SigDebug}) {
+		t.Errorf("CompareAndSwapAsync(SigProfile, SigNone) failed: %+v", s)
+	}
+}
+
+type displayCelsius float64
+
+type displayHTML string
+
+func (h displayHTML) GomacroDisplay() (string, []byte) {
+	return "text/html", []byte("<b>" + string(h) + "</b>")
+}
+
+// golden output of REPL results with display hooks
+func TestPrintDisplay(t *testing.T) {
+	var buf bytes.Buffer
+	g := NewGlobals()
+	g.Stdout, g.Stderr = &buf, &buf
+	g.Options |= OptShowEval | OptShowEvalType
+	g.SetDisplay(r.TypeOf(displayCelsius(0)), func(v r.Value) (string, []byte) {
+		return output.MimeText, []byte(fmt.Sprintf("%g°C", v.Float()))
+	})
+	g.SetDisplay(r.TypeOf(0), func(v r.Value) (string, []byte) {
+		panic("broken hook")
+	})
+	values := []r.Value{
+		r.ValueOf(displayCelsius(21.5)),
+		r.ValueOf([]displayCelsius{1, 2}),
+		r.ValueOf(7),
+		r.ValueOf(displayHTML("x")), // no RichOutput: printed as usual
+	}
+	g.Print(values, nil)
+
+	var rich []string
+	g.RichOutput = func(mime string, data []byte) {
+		rich = append(rich, mime+" "+string(data))
+	}
+	g.Print([]r.Value{r.ValueOf(displayHTML("y"))}, nil)
+
+	expect := `21.5°C	// base.displayCelsius
+[1°C 2°C]	// []base.displayCelsius
+error displaying value: broken hook	// int
+x	// base.displayHTML
+`
+	if actual := buf.String(); actual != expect {
+		t.Errorf("expecting:\n%s\nfound:\n%s", expect, actual)
+	}
+	if len(rich) != 1 || rich[0] != "text/html <b>y</b>" {
+		t.Errorf("expecting rich output %q, found %q", "text/html <b>y</b>", rich)
+	}
+}
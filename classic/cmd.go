@@ -55,7 +55,8 @@ func (cmds Cmds) ShowHelp(g *Globals) {
                    in current package, or from imported package NAME
 %chelp              show this help
 %cinspect EXPR      inspect expression interactively
-%coptions [OPTS]    show or toggle interpreter options
+%coptions [OPTS]    show or toggle interpreter options.
+                   OPTS can also contain Print.MaxDepth=N, Print.MaxElems=N or Print.MaxString=N
 %cpackage "PKGPATH" switch to package PKGPATH, importing it if possible.
 %cquit              quit the interpreter
 %cunload "PKGPATH"  remove package PKGPATH from the list of known packages.
@@ -136,10 +137,11 @@ func (ir *Interp) cmdOptions(arg string, opt CmdOpt) (string, CmdOpt) {
 	g := env.Globals
 
 	if len(arg) != 0 {
-		g.Options ^= ParseOptions(arg)
+		g.Options ^= ParseOptions(g.SetPrintLimits(arg))
 	} else {
 		fmt.Fprintf(env.Stdout, "// current options: %v\n", g.Options)
 		fmt.Fprintf(env.Stdout, "// unset   options: %v\n", ^g.Options)
+		fmt.Fprintf(env.Stdout, "// print   limits:  %v\n", g.Limits)
 	}
 	return "", opt
 }
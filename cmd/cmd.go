@@ -17,6 +17,7 @@
 package cmd
 
 import (
+	"bufio"
 	"bytes"
 	"fmt"
 	"io"
@@ -29,6 +30,7 @@ import (
 	"github.com/cosmos72/gomacro/base/paths"
 	"github.com/cosmos72/gomacro/fast"
 	"github.com/cosmos72/gomacro/fast/debug"
+	mp "github.com/cosmos72/gomacro/parser"
 )
 
 type Cmd struct {
@@ -64,7 +66,7 @@ func (cmd *Cmd) Main(args []string) (err error) {
 	g := &ir.Comp.Globals
 
 	var set, clear Options
-	var repl, forcerepl = true, false
+	var repl, forcerepl, jsonrepl = true, false, false
 	cmd.WriteDeclsAndStmts = false
 	cmd.OverwriteFiles = false
 
@@ -72,6 +74,31 @@ func (cmd *Cmd) Main(args []string) (err error) {
 		switch args[0] {
 		case "-c", "--collect":
 			g.Options |= OptCollectDeclarations | OptCollectStatements
+		case "--coverprofile":
+			if len(args) > 1 {
+				filename := args[1]
+				g.Options |= OptCoverage
+				set |= OptCoverage
+				clear &^= OptCoverage
+				defer func() {
+					if err1 := ir.WriteCoverageFile(filename); err == nil {
+						err = err1
+					}
+				}()
+				args = args[1:]
+			}
+		case "--cpuprofile":
+			if len(args) > 1 {
+				if err := ir.StartProfileFile(args[1]); err != nil {
+					return err
+				}
+				defer func() {
+					if err1 := ir.StopProfile(); err == nil {
+						err = err1
+					}
+				}()
+				args = args[1:]
+			}
 		case "-e", "--expr":
 			if len(args) > 1 {
 				repl = false
@@ -91,6 +118,8 @@ func (cmd *Cmd) Main(args []string) (err error) {
 			return cmd.Usage()
 		case "-i", "--repl":
 			forcerepl = true
+		case "--json":
+			jsonrepl = true
 		case "-m", "--macro-only":
 			set |= OptMacroExpandOnly
 			clear &^= OptMacroExpandOnly
@@ -123,19 +152,27 @@ func (cmd *Cmd) Main(args []string) (err error) {
 			repl = false
 			if cmd.WriteDeclsAndStmts {
 				g.Options |= OptCollectDeclarations | OptCollectStatements
+				// preserve the comments of written declarations
+				g.ParserMode |= mp.ParseComments
 			}
 			g.Options &^= OptShowPrompt | OptShowEval | OptShowEvalType // cleared by default, overridden by -s, -v and -vv
 			g.Options = (g.Options | set) &^ clear
-			cmd.EvalFileOrDir(arg)
+			if err := cmd.EvalFileOrDir(arg); err != nil {
+				return err
+			}
 
-			g.Imports, g.Declarations, g.Statements = nil, nil, nil
+			g.Imports, g.Declarations, g.Statements, g.Comments = nil, nil, nil, nil
 		}
 		args = args[1:]
 	}
 	if repl || forcerepl {
 		g.Options |= OptShowPrompt | OptShowEval | OptShowEvalType // set by default, overridden by -s, -v and -vv
 		g.Options = (g.Options | set) &^ clear
-		ir.ReplStdin()
+		if jsonrepl {
+			ir.ReplJSON(bufio.NewReader(os.Stdin), os.Stdout)
+		} else {
+			ir.ReplStdin()
+		}
 	}
 	return nil
 }
@@ -146,11 +183,19 @@ func (cmd *Cmd) Usage() error {
 
   Recognized options:
     -c,   --collect          collect declarations and statements, to print them later
+          --coverprofile FILE
+                             record which statements are executed, writing a coverage profile to FILE on exit.
+                             examine it with 'go tool cover -html=FILE'
+          --cpuprofile FILE  profile interpreted code, writing a pprof profile to FILE on exit.
+                             examine it with 'go tool pprof FILE'
     -e,   --expr EXPR        evaluate expression
     -f,   --force-overwrite  option -w will overwrite existing files
     -h,   --help             show this help and exit
     -i,   --repl             interactive. start a REPL after evaluating expression, files and dirs.
                              default: start a REPL only if no expressions, files or dirs are specified
+          --json             machine-readable REPL: for each input read from standard input,
+                             print on standard output a JSON object containing its results,
+                             captured output, warnings, error and evaluation time
     -m,   --macro-only       do not execute code, only parse and macroexpand it.
                              useful to run gomacro as a Go preprocessor
     -n,   --no-trap          do not trap panics in the interpreter
@@ -161,8 +206,9 @@ func (cmd *Cmd) Usage() error {
                              default when executing an expression.
     -vv,  --very-verbose     as -v, and in addition show the type of expressions results.
                              default when executing a REPL
-    -w,   --write-decls      write collected declarations and statements to *.go files.
-                             implies -c
+    -w,   --write-decls      write collected declarations and statements to *.go files,
+                             formatted with gofmt and with comments and //line directives
+                             pointing to the original files. implies -c
     -x,   --exec             execute parsed code (default). disabled by -m
 
     Options are processed in order, except for -i that is always processed as last.
@@ -225,6 +271,7 @@ func (cmd *Cmd) EvalFile(filename string) error {
 	g := &cmd.Interp.Comp.Globals
 	g.Declarations = nil
 	g.Statements = nil
+	g.Comments = nil
 
 	comments, err := cmd.Interp.EvalFile(filename)
 	if err != nil {
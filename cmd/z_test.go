@@ -0,0 +1,133 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * z_test.go
+ *
+ *  Created on Oct 19, 2026
+ *      Author agent
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cosmos72/gomacro/base"
+	"github.com/cosmos72/gomacro/fast"
+)
+
+// errors from evaluating files must be returned by Main
+func TestMainFileError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gomacro_cmd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cmd := New()
+	var buf bytes.Buffer
+	g := &cmd.Interp.Comp.Globals
+	g.Stdout, g.Stderr = &buf, &buf
+
+	if err := cmd.Main([]string{filepath.Join(dir, "missing.gomacro")}); !os.IsNotExist(err) {
+		t.Errorf("expecting file not found error, found %v", err)
+	}
+
+	file := filepath.Join(dir, "panic.gomacro")
+	src := "func cmd_f() { panic(\"boom\") }\ncmd_f()\n"
+	if err := ioutil.WriteFile(file, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	g.Options = (g.Options | base.OptPanicStackTrace) &^ base.OptTrapPanic
+	err = cmd.Main([]string{file})
+	perr, ok := err.(*fast.PanicError)
+	if !ok {
+		t.Fatalf("expecting *fast.PanicError, found %T: %v", err, err)
+	}
+	if len(perr.Stack) != 1 || perr.Stack[0].Name != "cmd_f" {
+		t.Errorf("unexpected stack trace:\n%v", perr.Stack)
+	}
+}
+
+const preprocessSource = `package main
+
+import "fmt"
+
+~macro double(x interface{}) interface{} {
+	return ~"{~,x *= 2}
+}
+
+// doubled returns 2*x
+func doubled(x int) int {
+	double; x
+	return x
+}
+
+// Answer is the answer
+const Answer = 42
+
+func main() {
+	fmt.Println(doubled(Answer))
+}
+`
+
+// golden output of -m -w: the generated file has //line directives pointing
+// to the original file and keeps its doc comments
+func TestMacroOnlyWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gomacro_cmd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "prep.gomacro")
+	if err := ioutil.WriteFile(file, []byte(preprocessSource), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := New()
+	var buf bytes.Buffer
+	g := &cmd.Interp.Comp.Globals
+	g.Stdout, g.Stderr = &buf, &buf
+	if err := cmd.Main([]string{"-m", "-w", file}); err != nil {
+		t.Fatal(err)
+	}
+	out, err := ioutil.ReadFile(filepath.Join(dir, "prep.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := disclaimer + `package main
+
+import "fmt"
+
+// doubled returns 2*x
+//
+//line prep.gomacro:10
+func doubled(x int) int {
+	x *= 2
+	return x
+}
+
+// Answer is the answer
+//
+//line prep.gomacro:16
+const Answer = 42
+
+//line prep.gomacro:18
+func main() {
+	fmt.Println(doubled(Answer))
+}
+`
+	if actual := string(out); actual != expect {
+		t.Errorf("expecting:\n%s\nfound:\n%s", expect, actual)
+	}
+}
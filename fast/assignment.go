@@ -34,6 +34,10 @@ type Assign struct {
 func (a *Assign) init(c *Comp, place *Place) {
 	if place.IsVar() {
 		a.setvar = c.varSetValue(&place.Var)
+		if a.setvar == nil {
+			// assigning a value to _ has no effect at all
+			a.setvar = func(*Env, r.Value) {}
+		}
 	} else {
 		a.placefun = place.Fun
 		a.placekey = place.MapKey
@@ -102,6 +102,7 @@ func (ce *Interp) addBuiltins() {
 	ce.DeclEnvFunc("MacroExpand1", Function{callMacroExpand1, tfunI2_Nb})
 	ce.DeclEnvFunc("MacroExpandCodeWalk", Function{callMacroExpandCodeWalk, tfunI2_Nb})
 	ce.DeclEnvFunc("Parse", Function{callParse, ce.Comp.TypeOf(funSI_I)})
+	ce.DeclEnvFunc("Rewrite", Function{callRewrite, ce.Comp.TypeOf(funI3_N)})
 	/*
 		binds["Read"] = r.ValueOf(ReadString)
 		binds["ReadDir"] = r.ValueOf(callReadDir)
@@ -692,6 +693,27 @@ func callParse(argv r.Value, interpv r.Value) r.Value {
 	return r.ValueOf(&form).Elem() // always return type ast2.Ast
 }
 
+// --- Rewrite() ---
+
+func funI3_N(I, I, I) ast.Node {
+	return nil
+}
+
+// callRewrite returns a copy of form where each node matching pattern
+// is replaced by template. See ast2.Match for the wildcards accepted in pattern
+func callRewrite(formv r.Value, patternv r.Value, templatev r.Value) r.Value {
+	if !formv.IsValid() {
+		return r.Zero(rtypeOfNode)
+	}
+	form := anyToAst(formv.Interface(), "Rewrite")
+	rule := ast2.Rule{
+		Pattern:  anyToAst(patternv.Interface(), "Rewrite"),
+		Template: anyToAst(templatev.Interface(), "Rewrite"),
+	}
+	form, _ = ast2.Rewrite(ast2.Clone(form), rule)
+	return r.ValueOf(form.Interface()).Convert(rtypeOfNode)
+}
+
 // --- print(), println() ---
 
 func callPrint(out I, args ...I) {
@@ -703,6 +725,9 @@ func callPrintln(out I, args ...I) {
 }
 
 func getStdout(env *Env) r.Value {
+	if s := env.Run.stdio; s != nil && s.active {
+		return r.ValueOf(stdioWriter{s, 1})
+	}
 	return r.ValueOf(env.Run.Stdout)
 }
 
@@ -838,6 +863,8 @@ func callRecover(v r.Value) r.Value {
 	// consume the current panic
 	run.Panic = nil
 	run.PanicFun = nil
+	run.PanicStack = nil
+	run.caughtPanic = false
 	return v
 }
 
@@ -1128,6 +1155,19 @@ func (c *Comp) call_builtin(call *Call) I {
 			ret0, ret1 := fun(arg0, arg1)
 			return ret0, []r.Value{ret0, ret1}
 		}
+	case func(r.Value, r.Value, r.Value) r.Value: // Rewrite()
+		argfunsX1 := call.MakeArgfunsX1()
+		argfuns := [3]func(env *Env) r.Value{
+			argfunsX1[0],
+			argfunsX1[1],
+			argfunsX1[2],
+		}
+		ret = func(env *Env) r.Value {
+			arg0 := argfuns[0](env)
+			arg1 := argfuns[1](env)
+			arg2 := argfuns[2](env)
+			return fun(arg0, arg1, arg2)
+		}
 	case func(r.Value, ...r.Value) r.Value: // append()
 		argfunsX1 := call.MakeArgfunsX1()
 		if call.Ellipsis {
@@ -34,6 +34,8 @@ type Call struct {
 	Builtin  bool // if true, call is a builtin function
 	Const    bool // if true, call has no side effects and always returns the same result => it can be invoked at compile time
 	Ellipsis bool // if true, must use reflect.Value.CallSlice or equivalent to invoke the function
+	// if not nil, call is a const func: it can be evaluated at compile time if all arguments are constant
+	ConstFunc *ConstFunc
 }
 
 func newCall1(fun *Expr, arg *Expr, isconst bool, outtypes ...xr.Type) *Call {
@@ -65,6 +67,11 @@ func (c *Comp) CallExpr(node *ast.CallExpr) *Expr {
 		}
 	}
 	call := c.prepareCall(node, fun)
+	if call.ConstFunc != nil {
+		if expr := c.callConstFunc(node, call); expr != nil {
+			return expr
+		}
+	}
 	return c.call_any(call)
 }
 
@@ -76,12 +83,16 @@ func (c *Comp) prepareCall(node *ast.CallExpr, fun *Expr) *Call {
 	t := fun.Type
 	var builtin bool
 	var lastarg *Expr
+	var constfunc *ConstFunc
 	if t.IdenticalTo(c.TypeOfBuiltin()) {
 		return c.callBuiltin(node, fun)
 	} else if t.IdenticalTo(c.TypeOfFunction()) {
 		fun, lastarg = c.callFunction(node, fun)
 		t = fun.Type
 		builtin = true
+	} else if constfunc, _ = fun.Value.(*ConstFunc); constfunc != nil && fun.Const() {
+		fun = constfunc.expr()
+		t = fun.Type
 	}
 	// compile args early, and use them to infer template function instantiation
 	var args []*Expr
@@ -119,7 +130,7 @@ func (c *Comp) prepareCall(node *ast.CallExpr, fun *Expr) *Call {
 	for i := 0; i < outn; i++ {
 		outtypes[i] = t.Out(i)
 	}
-	return &Call{Fun: fun, Args: args, OutTypes: outtypes, Builtin: builtin, Ellipsis: ellipsis}
+	return &Call{Fun: fun, Args: args, OutTypes: outtypes, Builtin: builtin, Ellipsis: ellipsis, ConstFunc: constfunc}
 }
 
 // call_any emits a compiled function call
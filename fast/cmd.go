@@ -20,11 +20,13 @@ import (
 	"errors"
 	"io"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/cosmos72/gomacro/base/paths"
 
 	"github.com/cosmos72/gomacro/base"
+	"github.com/cosmos72/gomacro/base/output"
 	bstrings "github.com/cosmos72/gomacro/base/strings"
 )
 
@@ -37,7 +39,7 @@ import (
 // note that Interp.Eval() does **not** look for special commands!
 //
 // Cmd.Name is the command name **without** the initial ':'
-//   it must be a valid Go identifier and must not be empty.
+//   it must be a valid Go identifier, optionally containing '-', and must not be empty.
 //   Using a reserved Go keyword (const, for, func, if, package, return, switch, type, var...)
 //   or predefined identifier (bool, int, rune, true, false, nil...)
 //   is a bad idea because it interferes with gomacro preprocessor mode.
@@ -139,6 +141,9 @@ func prefixSearch(vec []Cmd, prefix string) (int, error) {
 	}
 	if lo == n {
 		return 0, io.EOF
+	} else if vec[lo].Name == prefix {
+		// exact match wins, even if other names start with prefix
+		return lo, nil
 	}
 	hi := lo + 1
 	for ; hi < n; hi++ {
@@ -270,16 +275,37 @@ var Commands Cmds
 
 func init() {
 	Commands.m = map[byte][]Cmd{
-		'd': []Cmd{{"debug", (*Interp).cmdDebug, `debug EXPR        debug expression or statement interactively`}},
-		'e': []Cmd{{"env", (*Interp).cmdEnv, `env [NAME]        show available functions, variables and constants
+		'c': []Cmd{{"coverage", (*Interp).cmdCoverage, `coverage [FILE]   write coverage profile to standard output or to FILE.
+                   only code compiled after %copt Coverage is instrumented`}},
+		'd': []Cmd{{"debug", (*Interp).cmdDebug, `debug EXPR        debug expression or statement interactively`},
+			{"dump", (*Interp).cmdDump, `dump EXPR         show the value of expression as Go source code.
+                   options before EXPR: -depth=N limits nesting, -cycle=error fails on cyclic values`}},
+		'e': []Cmd{{"edit", (*Interp).cmdEdit, `edit NAME         edit the source of declaration NAME with $EDITOR, then evaluate it`},
+			{"env", (*Interp).cmdEnv, `env [NAME]        show available functions, variables and constants
                    in current package, or from imported package NAME`}},
-		'h': []Cmd{{"help", (*Interp).cmdHelp, `help              show this help`}},
+		'f': []Cmd{{"forget", (*Interp).cmdForget, `forget NAME...    remove the functions, variables, constants, types and macros NAME...`}},
+		'h': []Cmd{{"help", (*Interp).cmdHelp, `help              show this help`},
+			{"history", (*Interp).cmdHistory, `history           show the results kept in variables _1, _2 ... and __`}},
 		'i': []Cmd{{"inspect", (*Interp).cmdInspect, `inspect EXPR      inspect expression interactively`}},
-		'o': []Cmd{{"options", (*Interp).cmdOptions, `options [OPTS]    show or toggle interpreter options`}},
-		'p': []Cmd{{"package", (*Interp).cmdPackage, `package "PKGPATH" switch to package PKGPATH, importing it if possible`}},
+		'm': []Cmd{{"macroexpand", (*Interp).cmdMacroExpand, `macroexpand CODE  show each step of the macroexpansion of the macro calls in CODE.
+                   option -diff before CODE shows a unified diff between consecutive steps`},
+			{"macroexpand-all", (*Interp).cmdMacroExpandAll, `macroexpand-all CODE show each step of the macroexpansion of CODE, including nested macro calls`},
+			{"macroexpand1", (*Interp).cmdMacroExpand1, `macroexpand1 CODE show the first step of the macroexpansion of the macro calls in CODE`}},
+		'o': []Cmd{{"options", (*Interp).cmdOptions, `options [OPTS]    show or toggle interpreter options.
+                   OPTS can also contain Print.MaxDepth=N, Print.MaxElems=N, Print.MaxString=N
+                   or History.Size=N`}},
+		'p': []Cmd{{"package", (*Interp).cmdPackage, `package "PKGPATH" switch to package PKGPATH, importing it if possible`},
+			{"profile", (*Interp).cmdProfile, `profile start|stop start profiling interpreted code with %cprofile start FILE
+                   or stop profiling and write a pprof profile to FILE.
+                   function names are known only for code compiled after %copt Debugger`}},
 		'q': []Cmd{{"quit", (*Interp).cmdQuit, `quit              quit the interpreter`}},
-		'u': []Cmd{{"unload", (*Interp).cmdUnload, `unload "PKGPATH"  remove package PKGPATH from the list of known packages.
-                   later attempts to import it will trigger a recompile`}},
+		's': []Cmd{{"source", (*Interp).cmdSource, `source [NAME]     show the source of declaration NAME, or list declarations with known source`}},
+		't': []Cmd{{"trace", (*Interp).cmdTrace, `trace [PATTERN]   show traced functions, or trace calls and returns of functions matching PATTERN.
+                   examples: %ctrace fib  %ctrace main.*  %ctrace MyType.*`}},
+		'u': []Cmd{{"undo", (*Interp).cmdUndo, `undo              revert the declarations made by the last evaluated input`},
+			{"unload", (*Interp).cmdUnload, `unload "PKGPATH"  remove package PKGPATH from the list of known packages.
+                   later attempts to import it will trigger a recompile`},
+			{"untrace", (*Interp).cmdUntrace, `untrace [PATTERN] stop tracing functions matching PATTERN, or all functions`}},
 		'w': []Cmd{{"write", (*Interp).cmdWrite, `write [FILE]      write collected declarations and/or statements to standard output or to FILE
                    use %copt Declarations and/or %copt Statements to start collecting them`}},
 	}
@@ -317,6 +343,20 @@ func (ir *Interp) Cmd(src string) (string, base.CmdOpt) {
 	return src, opt
 }
 
+func (ir *Interp) cmdCoverage(filename string, opt base.CmdOpt) (string, base.CmdOpt) {
+	g := &ir.Comp.Globals
+	var err error
+	if len(filename) == 0 {
+		err = ir.WriteCoverage(g.Stdout)
+	} else {
+		err = ir.WriteCoverageFile(filename)
+	}
+	if err != nil {
+		g.Fprintf(g.Stdout, "// coverage: %v\n", err)
+	}
+	return "", opt
+}
+
 func (ir *Interp) cmdDebug(arg string, opt base.CmdOpt) (string, base.CmdOpt) {
 	g := &ir.Comp.Globals
 	if len(arg) == 0 {
@@ -327,6 +367,49 @@ func (ir *Interp) cmdDebug(arg string, opt base.CmdOpt) (string, base.CmdOpt) {
 	return "", opt
 }
 
+func (ir *Interp) cmdDump(arg string, opt base.CmdOpt) (string, base.CmdOpt) {
+	c := ir.Comp
+	g := &c.Globals
+	saved := c.Literal
+	defer func() {
+		c.Literal = saved
+	}()
+	arg = strings.TrimSpace(arg)
+	for strings.HasPrefix(arg, "-") {
+		flag, rest := bstrings.Split2(arg, ' ')
+		switch {
+		case strings.HasPrefix(flag, "-depth="):
+			depth, err := strconv.Atoi(flag[len("-depth="):])
+			if err != nil || depth < 0 {
+				g.Fprintf(g.Stdout, "// dump: invalid option %s\n", flag)
+				return "", opt
+			}
+			c.Literal.MaxDepth = depth
+		case flag == "-cycle=error":
+			c.Literal.Cycle = LiteralCycleError
+		case flag == "-cycle=nil":
+			c.Literal.Cycle = LiteralCycleNil
+		default:
+			// not an option, for example -x
+			rest = arg
+		}
+		if rest == arg {
+			break
+		}
+		arg = strings.TrimSpace(rest)
+	}
+	if len(arg) == 0 {
+		g.Fprintf(g.Stdout, "// dump: missing argument\n")
+		return "", opt
+	}
+	// not ir.Compile because it only macroexpands if OptMacroExpandOnly is set
+	val, xtyp := ir.RunExpr1(c.Compile(c.Parse(arg)))
+	expr, fset := ir.GoLiteral(val, xtyp)
+	st := output.Stringer{Fileset: fset}
+	st.Fprintf(g.Stdout, "%v\n", expr)
+	return "", opt
+}
+
 func (ir *Interp) cmdEnv(arg string, opt base.CmdOpt) (string, base.CmdOpt) {
 	ir.ShowPackage(arg)
 	return "", opt
@@ -352,7 +435,8 @@ func (ir *Interp) cmdOptions(arg string, opt base.CmdOpt) (string, base.CmdOpt)
 	g := &c.Globals
 
 	if len(arg) != 0 {
-		g.Options ^= base.ParseOptions(arg)
+		arg = c.History.setSize(g, arg)
+		g.Options ^= base.ParseOptions(g.SetPrintLimits(arg))
 
 		debugdepth := 0
 		if g.Options&base.OptDebugFromReflect != 0 {
@@ -363,6 +447,8 @@ func (ir *Interp) cmdOptions(arg string, opt base.CmdOpt) (string, base.CmdOpt)
 	} else {
 		g.Fprintf(g.Stdout, "// current options: %v\n", g.Options)
 		g.Fprintf(g.Stdout, "// unset   options: %v\n", ^g.Options)
+		g.Fprintf(g.Stdout, "// print   limits:  %v\n", g.Limits)
+		g.Fprintf(g.Stdout, "// result  history: %v\n", &c.History)
 	}
 	return "", opt
 }
@@ -385,10 +471,66 @@ func (ir *Interp) cmdPackage(path string, cmdopt base.CmdOpt) (string, base.CmdO
 	return "", cmdopt
 }
 
+func (ir *Interp) cmdProfile(arg string, opt base.CmdOpt) (string, base.CmdOpt) {
+	g := &ir.Comp.Globals
+	op, filename := bstrings.Split2(strings.TrimSpace(arg), ' ')
+	filename = strings.TrimSpace(filename)
+	var err error
+	switch op {
+	case "start":
+		if len(filename) == 0 {
+			g.Fprintf(g.Stdout, "// profile start: missing FILE\n")
+			return "", opt
+		}
+		err = ir.StartProfileFile(filename)
+	case "stop":
+		err = ir.StopProfile()
+	case "":
+		if ir.IsProfiling() {
+			g.Fprintf(g.Stdout, "// profiling is active\n")
+		} else {
+			g.Fprintf(g.Stdout, "// profiling is not active\n")
+		}
+	default:
+		g.Fprintf(g.Stdout, "// profile: expecting start FILE or stop, found %q\n", arg)
+	}
+	if err != nil {
+		g.Fprintf(g.Stdout, "// profile %s: %v\n", op, err)
+	}
+	return "", opt
+}
+
 func (ir *Interp) cmdQuit(_ string, opt base.CmdOpt) (string, base.CmdOpt) {
 	return "", opt | base.CmdOptQuit
 }
 
+func (ir *Interp) cmdTrace(arg string, opt base.CmdOpt) (string, base.CmdOpt) {
+	g := &ir.Comp.Globals
+	patterns := strings.Fields(arg)
+	for _, pattern := range patterns {
+		if err := ir.Trace(pattern); err != nil {
+			g.Fprintf(g.Stdout, "// trace %s: %v\n", pattern, err)
+		}
+	}
+	if len(patterns) == 0 {
+		patterns, names := ir.Traced()
+		g.Fprintf(g.Stdout, "// traced patterns:  %s\n", strings.Join(patterns, " "))
+		g.Fprintf(g.Stdout, "// traced functions: %s\n", strings.Join(names, " "))
+	}
+	return "", opt
+}
+
+func (ir *Interp) cmdUntrace(arg string, opt base.CmdOpt) (string, base.CmdOpt) {
+	patterns := strings.Fields(arg)
+	for _, pattern := range patterns {
+		ir.Untrace(pattern)
+	}
+	if len(patterns) == 0 {
+		ir.Untrace("")
+	}
+	return "", opt
+}
+
 // remove package 'path' from the list of known packages
 func (ir *Interp) cmdUnload(path string, opt base.CmdOpt) (string, base.CmdOpt) {
 	if len(path) != 0 {
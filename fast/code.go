@@ -70,12 +70,15 @@ func spinInterrupt(env *Env) (Stmt, *Env) {
 }
 
 func (run *Run) applyAsyncSignal(sig Signal) {
-	run.Signals.Async = SigNone
+	// keep signals sent by other goroutines in the meantime
+	run.Signals.CompareAndSwapAsync(sig, SigNone)
 	switch sig {
 	case SigNone:
 		break
 	case SigDebug:
 		run.applyDebugOp(DebugOpStep)
+	case SigProfile:
+		run.profileSample()
 	default:
 		panic(SigInterrupt)
 	}
@@ -166,6 +169,7 @@ func exec(all []Stmt, pos []token.Pos) func(*Env) {
 		env.Code = all
 		env.DebugPos = pos
 
+	again:
 		for j := 0; j < 5; j++ {
 			if stmt, env = stmt(env); stmt != nil {
 				if stmt, env = stmt(env); stmt != nil {
@@ -224,11 +228,16 @@ func exec(all []Stmt, pos []token.Pos) func(*Env) {
 			}
 		}
 	finish:
-		// restore env.ThreadGlobals.Interrupt and Signal before returning
-		run.Interrupt = saveInterrupt
 		if sig := run.Signals.Async; sig != SigNone {
 			run.applyAsyncSignal(sig) // may set run.Signals.Debug if OptCtrlCEnterDebugger is set
+			if run.Signals.IsEmpty() && stmt != nil {
+				// signal serviced without interrupting execution, i.e. SigProfile: resume
+				run.Interrupt = nil
+				goto again
+			}
 		}
+		// restore env.ThreadGlobals.Interrupt and Signal before returning
+		run.Interrupt = saveInterrupt
 		if run.Signals.Debug == SigNone {
 			run.Signals.Sync = SigNone
 		} else {
@@ -259,6 +268,10 @@ func reExecWithFlags(env *Env, all []Stmt, pos []token.Pos, stmt Stmt, ip int) {
 	caller := run.CurrEnv
 	// restore g.IsDefer, g.Signal, g.DebugCallDepth, g.Interrupt and g.Caller on return
 	defer restore(run, run.ExecFlags.IsDefer(), run.Interrupt, caller)
+	if ef.IsCatchPanic() {
+		// runs after the defers installed by interpreted code
+		defer run.catchPanic()
+	}
 	ef.SetDefer(ef.StartDefer())
 	ef.SetStartDefer(false)
 	ef.SetDebug(run.Signals.Debug != SigNone)
@@ -274,6 +287,10 @@ func reExecWithFlags(env *Env, all []Stmt, pos []token.Pos, stmt Stmt, ip int) {
 			panicking = true
 			panicking2 = false
 			run.Panic = recover()
+			if run.ExecFlags.IsCatchPanic() {
+				// intercept the panic before interpreted defers are executed
+				run.interceptPanic(run.Panic)
+			}
 		}
 		defer popDefer(pushDefer(run, funenv, panicking))
 		panicking2 = true // detect panics inside defer
@@ -373,6 +390,10 @@ signal:
 		// if OptCtrlCEnterDebugger is set, convert early
 		// Signals.Async = SigDebug to Signals.Debug = SigDebug
 		run.applyAsyncSignal(sig)
+		if run.Signals.IsEmpty() && stmt != nil {
+			// signal serviced without interrupting execution, i.e. SigProfile: resume
+			goto again
+		}
 	}
 
 	for run.Signals.Debug != SigNone {
@@ -107,6 +107,7 @@ func (run *Run) new(goid uintptr) *Run {
 	return &Run{
 		IrGlobals: run.IrGlobals,
 		goid:      goid,
+		ExecFlags: run.ExecFlags & EFCatchPanic,
 		// Interrupt, Signal, PoolSize and Pool are zero-initialized, fine with that
 	}
 }
@@ -327,7 +328,9 @@ func (c *Comp) Compile(in Ast) *Expr {
 		// complicated, use general technique below
 	case ast.Node:
 		// shortcut
-		return c.compileNode(node, dep.Unknown)
+		e := c.compileNode(node, dep.Unknown)
+		c.recordSource(dep.Unknown, "", node)
+		return e
 	}
 	// order declarations by topological sort on their dependencies
 	sorter := dep.NewSorter()
@@ -375,15 +378,23 @@ func (c *Comp) compileDecl(decl *dep.Decl) *Expr {
 			defer top.endIota(top.beginIota())
 			top.setIota(extra.Iota)
 
-			c.DeclConsts(extra.Spec(), nil, nil)
+			spec := extra.Spec()
+			c.DeclConsts(spec, nil, nil)
+			// spec is synthesized, its positions do not delimit its source code
+			c.recordSource(decl.Kind, decl.Name, &ast.GenDecl{Tok: token.CONST, Specs: []ast.Spec{spec}})
 			return c.Code.AsExpr()
 		case dep.Var:
-			c.DeclVars(extra.Spec())
+			spec := extra.Spec()
+			c.DeclVars(spec)
+			// spec is synthesized, its positions do not delimit its source code
+			c.recordSource(decl.Kind, decl.Name, &ast.GenDecl{Tok: token.VAR, Specs: []ast.Spec{spec}})
 			return c.Code.AsExpr()
 		}
 	}
 	if node := decl.Node; node != nil {
-		return c.compileNode(node, decl.Kind)
+		e := c.compileNode(node, decl.Kind)
+		c.recordSource(decl.Kind, decl.Name, node)
+		return e
 	}
 	// may happen for second and later variables in VarMulti,
 	// which CANNOT be declared individually
@@ -0,0 +1,307 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * constfunc.go
+ *
+ *  Created on Oct 19, 2026
+ *      Author agent
+ */
+
+package fast
+
+import (
+	"go/ast"
+	"go/token"
+	r "reflect"
+	"strings"
+
+	"github.com/cosmos72/gomacro/base/output"
+	"github.com/cosmos72/gomacro/base/reflect"
+	"github.com/cosmos72/gomacro/gls"
+	xr "github.com/cosmos72/gomacro/xreflect"
+)
+
+// Compile-time function evaluation. A function declared with
+//
+//	const func NAME(PARAMS) RESULT { BODY }
+//
+// can be called in constant expressions, array lengths and template arguments:
+// when all the arguments of a call are constants, the interpreter executes the call
+// while compiling it, and the result is a constant. Calls with non-constant arguments
+// are executed at runtime, as for normal functions.
+//
+// Parameters and result must be booleans, numbers or strings, i.e. convertible to untyped constants.
+// The body must be pure: it cannot start goroutines, use channels or access variables
+// and functions declared outside the const func, except other const funcs.
+// It can only call the functions of a few packages without side effects, as math and strings.
+//
+// Compile-time evaluation fails if it executes too many loop iterations and calls,
+// or too many nested calls: it is assumed to never terminate.
+
+// limits of compile-time evaluation of const funcs
+const (
+	constFuncMaxSteps = 1000000 // loop iterations and calls
+	constFuncMaxDepth = 10000   // nested calls
+)
+
+// ConstFunc is a function declared with 'const func'
+type ConstFunc struct {
+	Name string
+	Type xr.Type
+	Func *func(*Env) r.Value // creates the function. *Func is nil while compiling the function body
+	fun  r.Value             // the function used for compile-time evaluation
+}
+
+func (f *ConstFunc) String() string {
+	if f == nil {
+		return "<nil>"
+	}
+	return "const func " + f.Name + strings.TrimPrefix(f.Type.String(), "func")
+}
+
+// constFuncCheck verifies that the body of a const func is pure
+type constFuncCheck struct {
+	name string
+	comp *Comp // where the const func is declared
+}
+
+// packages whose functions can be called by const funcs
+var constFuncPackages = map[string]bool{
+	"math":          true,
+	"math/bits":     true,
+	"math/cmplx":    true,
+	"strconv":       true,
+	"strings":       true,
+	"unicode":       true,
+	"unicode/utf16": true,
+	"unicode/utf8":  true,
+}
+
+// DeclConstFunc compiles a function declared with 'const func'.
+// The parser converts it to the constant declaration const NAME = func(PARAMS) RESULT { BODY }
+func (c *Comp) DeclConstFunc(ident *ast.Ident, lit *ast.FuncLit) {
+	name := ident.Name
+	if lit.Body == nil {
+		c.Errorf("const func %s: missing function body", name)
+	}
+	t, _, _ := c.TypeFunction(lit.Type)
+	for i, n := 0, t.NumIn(); i < n; i++ {
+		if tin := t.In(i); !reflect.IsOptimizedKind(tin.Kind()) {
+			c.Errorf("const func %s: parameter type <%v> is not a boolean, number or string", name, tin)
+		}
+	}
+	if t.NumOut() != 1 || !reflect.IsOptimizedKind(t.Out(0).Kind()) {
+		c.Errorf("const func %s: must return a single boolean, number or string, found %v", name, t)
+	}
+	c.checkConstFuncBody(name, lit.Body)
+
+	oldbind := c.Binds[name]
+	panicking := true
+	defer func() {
+		// On compile error, restore pre-existing declaration
+		if !panicking || c.Binds == nil {
+			// nothing to do
+		} else if oldbind != nil {
+			c.Binds[name] = oldbind
+		} else {
+			delete(c.Binds, name)
+		}
+	}()
+	// declare the const func before compiling its body: allows recursive const funcs
+	fun := &ConstFunc{Name: name, Type: t, Func: new(func(*Env) r.Value)}
+	bind := c.NewBind(name, ConstBind, c.TypeOfPtrConstFunc())
+	bind.Value = fun
+
+	g := c.CompGlobals
+	saved := g.constFunc
+	g.constFunc = &constFuncCheck{name: name, comp: c}
+	defer func() {
+		g.constFunc = saved
+	}()
+	// a const func declaration has no runtime effect
+	*fun.Func = c.FuncLit(lit).AsX1()
+	panicking = false
+}
+
+// reject the statements and expressions that have side effects
+func (c *Comp) checkConstFuncBody(name string, body *ast.BlockStmt) {
+	ast.Inspect(body, func(node ast.Node) bool {
+		var what string
+		switch node := node.(type) {
+		case *ast.ForStmt:
+			if node.Cond == nil && !containsExit(node.Body) {
+				what = "loop forever"
+			}
+		case *ast.GoStmt:
+			what = "start goroutines"
+		case *ast.SelectStmt:
+			what = "use select"
+		case *ast.SendStmt:
+			what = "send to channels"
+		case *ast.UnaryExpr:
+			if node.Op == token.ARROW {
+				what = "receive from channels"
+			}
+		}
+		if len(what) != 0 {
+			c.ErrorAt(node.Pos(), "const func %s cannot %s: %v", name, what, node)
+		}
+		return true
+	})
+}
+
+// return true if body contains a statement that may exit a loop without condition:
+// break, goto, return or a call to panic
+func containsExit(body *ast.BlockStmt) bool {
+	found := false
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.BranchStmt:
+			found = found || node.Tok == token.BREAK || node.Tok == token.GOTO
+		case *ast.ReturnStmt:
+			found = true
+		case *ast.CallExpr:
+			if ident, ok := node.Fun.(*ast.Ident); ok && ident.Name == "panic" {
+				found = true
+			}
+		case *ast.FuncLit:
+			// exits from a closure do not exit the loop
+			return false
+		}
+		return !found
+	})
+	return found
+}
+
+// check that the body of a const func can use bind, declared in c outside the const func
+func (check *constFuncCheck) checkBind(c *Comp, name string, bind *Bind) {
+	prefix := "const func " + check.name
+	switch bind.Desc.Class() {
+	case ConstBind:
+		switch bind.Value.(type) {
+		case Builtin:
+			if name == "print" || name == "println" {
+				c.Errorf("%s cannot perform I/O: %s", prefix, name)
+			}
+		case Function:
+			c.Errorf("%s cannot call interpreter function %s", prefix, name)
+		}
+	case VarBind, IntBind:
+		c.Errorf("%s cannot access variable %s declared outside it", prefix, name)
+	case FuncBind:
+		c.Errorf("%s cannot call function %s: it is not a const func", prefix, name)
+	case TemplateFuncBind:
+		c.Errorf("%s cannot call template function %s: it is not a const func", prefix, name)
+	}
+}
+
+// check that the body of a const func can use the functions and variables of imported package imp
+func (check *constFuncCheck) checkImport(c *Comp, imp *Import) {
+	if !constFuncPackages[imp.Path] {
+		c.Errorf("const func %s cannot use package %q: it may have side effects", check.name, imp.Path)
+	}
+}
+
+// return an expression that, when evaluated at runtime, returns the const func
+func (f *ConstFunc) expr() *Expr {
+	addr := f.Func
+	return exprX1(f.Type, func(env *Env) r.Value {
+		env.Run.constFuncStep(env)
+		return (*addr)(env)
+	})
+}
+
+// return the const func to use for compile-time evaluation.
+// return false if it cannot be created yet, i.e. while compiling its body
+func (f *ConstFunc) value(c *Comp) (r.Value, bool) {
+	if !f.fun.IsValid() {
+		top := c.CompGlobals.top
+		if *f.Func == nil || top == nil {
+			return f.fun, false
+		}
+		// the body of a const func does not access its outer *Env: any one will do
+		f.fun = (*f.Func)(top.env)
+	}
+	return f.fun, true
+}
+
+// evaluate at compile time a call to a const func.
+// return nil if some argument is not a constant, or if the const func is still being compiled
+func (c *Comp) callConstFunc(node *ast.CallExpr, call *Call) *Expr {
+	f := call.ConstFunc
+	if call.Ellipsis {
+		return nil
+	}
+	for _, arg := range call.Args {
+		if !arg.Const() {
+			return nil
+		}
+	}
+	fun, ok := f.value(c)
+	if !ok {
+		return nil
+	}
+	t := f.Type
+	args := make([]r.Value, len(call.Args))
+	for i, arg := range call.Args {
+		args[i] = r.ValueOf(arg.ConstTo(t.In(i))).Convert(t.In(i).ReflectType())
+	}
+	run := c.CompGlobals.top.env.Run
+	if goid := gls.GoID(); run.goid != goid {
+		run = run.getRun4Goid(goid)
+	}
+	var rets []r.Value
+	func() {
+		savedSteps, savedDepth, savedEnv := run.constSteps, run.constDepth, run.CurrEnv
+		run.constSteps = constFuncMaxSteps + 1
+		if savedEnv != nil {
+			run.constDepth = savedEnv.CallDepth + constFuncMaxDepth
+		} else {
+			run.constDepth = constFuncMaxDepth
+		}
+		defer func() {
+			// a failed evaluation does not return from the calls it started
+			run.constSteps, run.constDepth, run.CurrEnv = savedSteps, savedDepth, savedEnv
+			if rec := recover(); rec != nil {
+				c.Errorf("error evaluating %v at compile time: %v", node, rec)
+			}
+		}()
+		rets = fun.Call(args)
+	}()
+	return exprValue(t.Out(0), rets[0].Interface())
+}
+
+// if c is compiling a const func, append a statement that counts one loop iteration or jump
+func (c *Comp) appendConstFuncStep(pos token.Pos) {
+	if c.constFunc == nil {
+		return
+	}
+	c.Append(func(env *Env) (Stmt, *Env) {
+		env.Run.constFuncStep(env)
+		env.IP++
+		return env.Code[env.IP], env
+	}, pos)
+}
+
+// count one loop iteration or call executed by a const func.
+// panics if compile-time evaluation exceeded its limits
+func (run *Run) constFuncStep(env *Env) {
+	if run.constSteps == 0 {
+		// not evaluating a const func at compile time
+		return
+	}
+	run.constSteps--
+	if run.constSteps == 0 {
+		output.Errorf("exceeded %d loop iterations and calls, it may not terminate", constFuncMaxSteps)
+	} else if env.CallDepth > run.constDepth {
+		run.constSteps = 0
+		output.Errorf("exceeded %d nested calls, it may not terminate", constFuncMaxDepth)
+	}
+}
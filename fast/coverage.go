@@ -0,0 +1,254 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * coverage.go
+ *
+ *  Created on Oct 19, 2026
+ *      Author agent
+ */
+
+package fast
+
+import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// coverage records which statements and switch cases were executed.
+// only code compiled while OptCoverage is set is recorded
+type coverage struct {
+	lock   sync.Mutex
+	blocks []*coverBlock
+	inner  map[ast.Stmt]bool // init and post statements of compound statements, already covered by their header
+}
+
+// coverBlock is a source range, and the number of times it was executed
+type coverBlock struct {
+	count uint64 // updated atomically. first field, to be 64-bit aligned
+	coverRange
+}
+
+type coverRange struct {
+	file                string
+	startLine, startCol int
+	endLine, endCol     int
+}
+
+// coverStmt instruments a statement: when executed, it will increment a counter.
+// compound statements (if, for, switch...) only count their header:
+// their bodies are instrumented separately
+func (c *Comp) coverStmt(node ast.Stmt) {
+	cov := c.coverage()
+	if cov.inner[node] {
+		delete(cov.inner, node)
+		return
+	}
+	var end token.Pos
+	switch node := node.(type) {
+	case nil, *ast.BlockStmt, *ast.EmptyStmt, *ast.LabeledStmt:
+		return
+	case *ast.IfStmt:
+		end = coverEnd(node.Init, node.Cond)
+		cov.skip(node.Init)
+	case *ast.ForStmt:
+		end = coverEnd(node.Init, node.Cond, node.Post)
+		cov.skip(node.Init, node.Post)
+	case *ast.RangeStmt:
+		end = coverEnd(node.Key, node.Value, node.X)
+	case *ast.SelectStmt:
+		end = node.Select + token.Pos(len("select"))
+	case *ast.SwitchStmt:
+		end = coverEnd(node.Init, node.Tag)
+		if node.Tag == nil && node.Init == nil {
+			end = node.Switch + token.Pos(len("switch"))
+		}
+		cov.skip(node.Init)
+	case *ast.TypeSwitchStmt:
+		end = coverEnd(node.Init, node.Assign)
+		cov.skip(node.Init, node.Assign)
+	default:
+		// function literals are instrumented separately: stop at their body
+		ast.Inspect(node, func(n ast.Node) bool {
+			if lit, ok := n.(*ast.FuncLit); ok {
+				lbrace := lit.Body.Lbrace
+				if lbrace == token.NoPos {
+					lbrace = lit.Type.End()
+				}
+				if lbrace != token.NoPos && (end == token.NoPos || lbrace < end) {
+					end = lbrace
+				}
+				return false
+			}
+			return true
+		})
+		if end == token.NoPos {
+			end = coverEnd(node)
+		}
+	}
+	c.coverRange(coverStart(node), end)
+}
+
+// return the position of the first character of node.
+// if node.Pos() is unknown, as it happens for some macroexpanded code,
+// return the first known position of its children
+func coverStart(node ast.Node) token.Pos {
+	start := node.Pos()
+	if start == token.NoPos {
+		ast.Inspect(node, func(n ast.Node) bool {
+			if n != nil {
+				if pos := n.Pos(); pos != token.NoPos && (start == token.NoPos || pos < start) {
+					start = pos
+				}
+			}
+			return true
+		})
+	}
+	return start
+}
+
+// return the maximum known end position of nodes
+func coverEnd(nodes ...ast.Node) token.Pos {
+	var end token.Pos
+	for _, node := range nodes {
+		if node == nil {
+			continue
+		}
+		if pos := node.End(); pos > end {
+			end = pos
+		}
+	}
+	return end
+}
+
+// coverClause instruments a switch case: when the case is entered, it will increment a counter.
+// must be invoked immediately before compiling the case body
+func (c *Comp) coverClause(node *ast.CaseClause) {
+	c.coverRange(node.Case, node.Colon+1)
+}
+
+func (c *Comp) coverRange(start token.Pos, end token.Pos) {
+	if start == token.NoPos || end <= start || c.Fileset == nil {
+		return
+	}
+	from, to := c.Fileset.Position(start), c.Fileset.Position(end)
+	if len(from.Filename) == 0 || from.Filename != to.Filename {
+		// code typed at REPL: 'go tool cover' cannot show it
+		return
+	}
+	file := from.Filename
+	if abs, err := filepath.Abs(file); err == nil {
+		// 'go tool cover' interprets relative file names as package paths
+		file = abs
+	}
+	block := &coverBlock{
+		coverRange: coverRange{file, from.Line, from.Column, to.Line, to.Column},
+	}
+	cov := c.coverage()
+	cov.lock.Lock()
+	cov.blocks = append(cov.blocks, block)
+	cov.lock.Unlock()
+
+	count := &block.count
+	// no position: the debugger will not stop at this statement
+	c.Code.Append(func(env *Env) (Stmt, *Env) {
+		atomic.AddUint64(count, 1)
+		env.IP++
+		return env.Code[env.IP], env
+	}, token.NoPos)
+}
+
+func (c *Comp) coverage() *coverage {
+	cov := c.CompGlobals.coverage
+	if cov == nil {
+		cov = &coverage{inner: make(map[ast.Stmt]bool)}
+		c.CompGlobals.coverage = cov
+	}
+	return cov
+}
+
+// mark init and post statements of a compound statement: they must not be instrumented
+func (cov *coverage) skip(nodes ...ast.Stmt) {
+	for _, node := range nodes {
+		if node != nil {
+			cov.inner[node] = true
+		}
+	}
+}
+
+// WriteCoverage writes in 'go tool cover' format which statements and switch cases were executed.
+// Only code compiled while OptCoverage is set is included
+func (ir *Interp) WriteCoverage(out io.Writer) error {
+	counts := make(map[coverRange]uint64)
+	var ranges []coverRange
+	if cov := ir.Comp.CompGlobals.coverage; cov != nil {
+		cov.lock.Lock()
+		for _, block := range cov.blocks {
+			// the same source may be compiled multiple times: merge the counts
+			count, ok := counts[block.coverRange]
+			if !ok {
+				ranges = append(ranges, block.coverRange)
+			}
+			counts[block.coverRange] = count + atomic.LoadUint64(&block.count)
+		}
+		cov.lock.Unlock()
+	}
+	sort.Slice(ranges, func(i, j int) bool {
+		a, b := &ranges[i], &ranges[j]
+		if a.file != b.file {
+			return a.file < b.file
+		} else if a.startLine != b.startLine {
+			return a.startLine < b.startLine
+		}
+		return a.startCol < b.startCol
+	})
+	w := bufio.NewWriter(out)
+	fmt.Fprintln(w, "mode: count")
+	for _, rng := range ranges {
+		fmt.Fprintf(w, "%s:%d.%d,%d.%d 1 %d\n", rng.file,
+			rng.startLine, rng.startCol, rng.endLine, rng.endCol, counts[rng])
+	}
+	return w.Flush()
+}
+
+// WriteCoverageFile writes in 'go tool cover' format to the file 'filename'
+// which statements and switch cases were executed.
+// Only code compiled while OptCoverage is set is included
+func (ir *Interp) WriteCoverageFile(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	err = ir.WriteCoverage(f)
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	return err
+}
+
+// ResetCoverage sets to zero the execution counts of statements and switch cases
+func (ir *Interp) ResetCoverage() {
+	cov := ir.Comp.CompGlobals.coverage
+	if cov == nil {
+		return
+	}
+	cov.lock.Lock()
+	for _, block := range cov.blocks {
+		atomic.StoreUint64(&block.count, 0)
+	}
+	cov.lock.Unlock()
+}
@@ -86,7 +86,8 @@ func singleStep(env *Env) (Stmt, *Env) {
 		return stmt, env // resume normal execution
 	}
 
-	if env.CallDepth < run.DebugDepth {
+	// statements without position are bookkeeping, as coverage counters: do not stop there
+	if ip := env.IP; env.CallDepth < run.DebugDepth && (ip >= len(env.DebugPos) || env.DebugPos[ip] != token.NoPos) {
 		if run.Options&OptDebugDebugger != 0 {
 			run.Debugf("single-stepping: stmt = %p, env = %p, IP = %v, env.CallDepth = %d, g.DebugDepth = %d", stmt, env, env.IP, env.CallDepth, run.DebugDepth)
 		}
@@ -127,6 +128,75 @@ func (ir *Interp) debug(breakpoint bool) Signal {
 	return run.applyDebugOp(op)
 }
 
+// catchPanic is deferred by functions executed with EFCatchPanic:
+// it intercepts panics not yet seen, then resumes unwinding the stack
+func (run *Run) catchPanic() {
+	if run.caughtPanic {
+		// let the panic continue unwinding the stack
+		return
+	}
+	if rec := recover(); rec != nil {
+		run.interceptPanic(rec)
+		panic(rec)
+	}
+}
+
+// UpdateCatchPanic enables or disables intercepting panics
+// before unwinding the stack, according to current options.
+// Intercepting panics sets run.ExecFlags, which makes every function call and block
+// take the slower path reExecWithFlags, also used to support defer
+func (run *Run) UpdateCatchPanic() {
+	const debugPanic = OptDebugger | OptPanicDebugger
+	opts := run.Options
+	run.ExecFlags.SetCatchPanic(opts&debugPanic == debugPanic || opts&OptPanicStackTrace != 0)
+}
+
+// interceptPanic is invoked on a panic raised by interpreted code.
+// the stack is not unwound yet: run.CurrEnv is still the *Env that panicked
+func (run *Run) interceptPanic(rec interface{}) {
+	if run.caughtPanic || rec == SigInterrupt {
+		return
+	}
+	run.caughtPanic = true
+	env := run.CurrEnv
+	opts := run.Options
+	if opts&OptPanicStackTrace != 0 {
+		run.PanicStack = env.StackTrace()
+	}
+	if opts&OptDebugger != 0 && opts&OptPanicDebugger != 0 {
+		run.debugPanic(env, rec)
+	}
+}
+
+// debugPanic invokes the debugger on a panic raised by interpreted code
+func (run *Run) debugPanic(env *Env, rec interface{}) {
+	var c *Comp
+	for e := env; e != nil && c == nil; e = e.Outer {
+		c = e.DebugComp
+	}
+	if c == nil {
+		return
+	}
+	if run.Debugger == nil {
+		c.Warnf("// panic: no debugger set with Interp.SetDebugger(), resuming stack unwinding (warned only once)")
+		run.Debugger = stubDebugger{}
+	}
+	debugger, ok := run.Debugger.(PanicDebugger)
+	if !ok {
+		return
+	}
+	ir := Interp{c, env}
+	stack := run.PanicStack
+	op := debugger.Panic(&ir, env, rec)
+	if run.Options&OptDebugDebugger != 0 {
+		run.Debugf("Debugger returned op = %v", op)
+	}
+	// evaluating code at debugger prompt resets run.caughtPanic and run.PanicStack
+	run.caughtPanic = true
+	run.PanicStack = stack
+	run.applyDebugOp(op)
+}
+
 func (run *Run) applyDebugOp(op DebugOp) Signal {
 	if op.Panic != nil {
 		if run.Options&OptDebugDebugger != 0 {
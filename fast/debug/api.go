@@ -44,7 +44,26 @@ func (d *Debugger) At(interp *fast.Interp, env *fast.Env) DebugOp {
 	return d.main(interp, env, false)
 }
 
+// Panic is invoked when interpreted code panics, before the stack is unwound
+func (d *Debugger) Panic(interp *fast.Interp, env *fast.Env, rec interface{}) DebugOp {
+	d.init(interp, env)
+	g := d.globals
+	g.Fprintf(g.Stdout, "// panic: %v\n", rec)
+	d.show("panic")
+	g.Fprintf(g.Stdout, "// type continue to resume unwinding the stack, kill to terminate execution\n")
+	return d.Repl()
+}
+
 func (d *Debugger) main(interp *fast.Interp, env *fast.Env, breakpoint bool) DebugOp {
+	d.init(interp, env)
+	if !d.Show(breakpoint) {
+		// skip synthetic statements
+		return DebugOp{Depth: env.Run.DebugDepth}
+	}
+	return d.Repl()
+}
+
+func (d *Debugger) init(interp *fast.Interp, env *fast.Env) {
 	// create an inner Interp to preserve existing Binds, compiled Code and IP
 	//
 	// this is needed to allow compiling and evaluating code at a breakpoint or single step
@@ -52,9 +71,4 @@ func (d *Debugger) main(interp *fast.Interp, env *fast.Env, breakpoint bool) Deb
 	d.interp = fast.NewInnerInterp(interp, "debug", "debug")
 	d.env = env
 	d.globals = &interp.Comp.Globals
-	if !d.Show(breakpoint) {
-		// skip synthetic statements
-		return DebugOp{Depth: env.Run.DebugDepth}
-	}
-	return d.Repl()
 }
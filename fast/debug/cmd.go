@@ -28,7 +28,9 @@ type Cmd struct {
 	Func func(d *Debugger, arg string) DebugOp
 }
 
-type Cmds map[byte]Cmd
+// Cmds is indexed by first letter. when a prefix matches multiple commands,
+// the first one in the slice wins: it allows single-letter abbreviations
+type Cmds map[byte][]Cmd
 
 func (cmd *Cmd) Match(prefix string) bool {
 	return strings.HasPrefix(cmd.Name, prefix)
@@ -36,28 +38,29 @@ func (cmd *Cmd) Match(prefix string) bool {
 
 func (cmds Cmds) Lookup(prefix string) (Cmd, bool) {
 	if len(prefix) != 0 {
-		cmd, found := cmds[prefix[0]]
-		if found && cmd.Match(prefix) {
-			return cmd, true
+		for _, cmd := range cmds[prefix[0]] {
+			if cmd.Match(prefix) {
+				return cmd, true
+			}
 		}
 	}
 	return Cmd{}, false
 }
 
 var cmds = Cmds{
-	'b': Cmd{"backtrace", (*Debugger).cmdBacktrace},
-	'c': Cmd{"continue", (*Debugger).cmdContinue},
-	'e': Cmd{"env", (*Debugger).cmdEnv},
-	'f': Cmd{"finish", (*Debugger).cmdFinish},
-	'h': Cmd{"help", (*Debugger).cmdHelp},
-	'?': Cmd{"?", (*Debugger).cmdHelp},
-	'i': Cmd{"inspect", (*Debugger).cmdInspect},
-	'k': Cmd{"kill", (*Debugger).cmdKill},
-	'l': Cmd{"list", (*Debugger).cmdList},
-	'n': Cmd{"next", (*Debugger).cmdNext},
-	'p': Cmd{"print", (*Debugger).cmdPrint},
-	's': Cmd{"step", (*Debugger).cmdStep},
-	'v': Cmd{"vars", (*Debugger).cmdVars},
+	'b': {{"backtrace", (*Debugger).cmdBacktrace}},
+	'c': {{"continue", (*Debugger).cmdContinue}, {"catch", (*Debugger).cmdCatch}},
+	'e': {{"env", (*Debugger).cmdEnv}},
+	'f': {{"finish", (*Debugger).cmdFinish}},
+	'h': {{"help", (*Debugger).cmdHelp}},
+	'?': {{"?", (*Debugger).cmdHelp}},
+	'i': {{"inspect", (*Debugger).cmdInspect}},
+	'k': {{"kill", (*Debugger).cmdKill}},
+	'l': {{"list", (*Debugger).cmdList}},
+	'n': {{"next", (*Debugger).cmdNext}},
+	'p': {{"print", (*Debugger).cmdPrint}},
+	's': {{"step", (*Debugger).cmdStep}},
+	'v': {{"vars", (*Debugger).cmdVars}},
 }
 
 // execute one of the debugger commands
@@ -84,6 +87,27 @@ func (d *Debugger) cmdBacktrace(arg string) DebugOp {
 	return DebugOpRepl
 }
 
+func (d *Debugger) cmdCatch(arg string) DebugOp {
+	g := d.globals
+	switch arg {
+	case "", "on":
+		g.Options |= base.OptPanicDebugger
+	case "off":
+		g.Options &^= base.OptPanicDebugger
+	default:
+		g.Fprintf(g.Stdout, "// catch: expecting on or off, found: %s\n", arg)
+		return DebugOpRepl
+	}
+	// apply immediately to the code being debugged, not only at next evaluation
+	d.env.Run.UpdateCatchPanic()
+	if g.Options&base.OptPanicDebugger != 0 {
+		g.Fprintf(g.Stdout, "// catch: panics will enter the debugger before unwinding the stack\n")
+	} else {
+		g.Fprintf(g.Stdout, "// catch: disabled\n")
+	}
+	return DebugOpRepl
+}
+
 func (d *Debugger) cmdContinue(arg string) DebugOp {
 	return DebugOpContinue
 }
@@ -29,6 +29,7 @@ func (d *Debugger) Help() {
 	g := d.globals
 	g.Fprintf(g.Stdout, "%s", `// debugger commands:
 backtrace       show call stack
+catch  [on|off] enter the debugger when code panics, before unwinding the stack
 env [NAME]      show available functions, variables and constants
                 in current scope, or from imported package NAME
 ?               show this help
@@ -52,6 +53,16 @@ vars            show local variables
 }
 
 func (d *Debugger) Show(breakpoint bool) bool {
+	var label string
+	if breakpoint {
+		label = "breakpoint"
+	} else {
+		label = "stopped"
+	}
+	return d.show(label)
+}
+
+func (d *Debugger) show(label string) bool {
 	// d.env is the Env being debugged.
 	// to execute code at debugger prompt, use d.interp
 	env := d.env
@@ -59,12 +70,6 @@ func (d *Debugger) Show(breakpoint bool) bool {
 	g := d.globals
 	ip := env.IP
 
-	var label string
-	if breakpoint {
-		label = "breakpoint"
-	} else {
-		label = "stopped"
-	}
 	if ip < len(pos) && g.Fileset != nil {
 		p := pos[ip]
 		if p == token.NoPos {
@@ -72,6 +77,9 @@ func (d *Debugger) Show(breakpoint bool) bool {
 		}
 		source, pos := g.Fileset.Source(p)
 		g.Fprintf(g.Stdout, "// %s at %s IP=%d, call depth=%d. type ? for debugger help\n", label, pos, ip, env.CallDepth)
+		if expansion := g.Fileset.ExpansionString(p); len(expansion) != 0 {
+			g.Fprintf(g.Stdout, "// %s\n", expansion)
+		}
 		if len(source) != 0 {
 			g.Fprintf(g.Stdout, "%s\n", source)
 			d.showCaret(source, pos.Column)
@@ -131,12 +139,19 @@ func (d *Debugger) Eval(src string) ([]reflect.Value, []xreflect.Type) {
 	trap := g.Options&base.OptTrapPanic != 0
 
 	// do NOT debug expression evaluated at debugger prompt!
-	sig := &d.env.Run.Signals
+	run := d.env.Run
+	sig := &run.Signals
 	sigdebug := sig.Debug
 	sig.Debug = base.SigNone
+	// and do NOT catch its panics either
+	catchpanic := run.ExecFlags.IsCatchPanic()
+	opts := g.Options
+	g.Options &^= base.OptPanicDebugger
 
 	defer func() {
 		sig.Debug = sigdebug
+		g.Options = (g.Options &^ base.OptPanicDebugger) | (opts & base.OptPanicDebugger)
+		run.ExecFlags.SetCatchPanic(catchpanic)
 		if trap {
 			rec := recover()
 			if g.Options&base.OptPanicStackTrace != 0 {
@@ -122,6 +122,10 @@ func (c *Comp) DeclConsts(node ast.Spec, defaultType ast.Expr, defaultExprs []as
 	c.Pos = node.Pos()
 	switch node := node.(type) {
 	case *ast.ValueSpec:
+		if lit, ok := constFuncLit(node); ok {
+			c.DeclConstFunc(node.Names[0], lit)
+			return
+		}
 		if node.Type != nil || node.Values != nil {
 			defaultType = node.Type
 			defaultExprs = node.Values
@@ -133,6 +137,16 @@ func (c *Comp) DeclConsts(node ast.Spec, defaultType ast.Expr, defaultExprs []as
 	}
 }
 
+// return the function literal of a const func, which the parser converts
+// to the constant declaration const NAME = func(PARAMS) RESULT { BODY }
+func constFuncLit(node *ast.ValueSpec) (*ast.FuncLit, bool) {
+	if node.Type != nil || len(node.Names) != 1 || len(node.Values) != 1 {
+		return nil, false
+	}
+	lit, ok := node.Values[0].(*ast.FuncLit)
+	return lit, ok
+}
+
 // DeclVars compiles a set of variable declarations i.e. "var x1, x2... [type] = expr1, expr2..."
 func (c *Comp) DeclVars(node ast.Spec) {
 	c.Pos = node.Pos()
@@ -275,6 +289,9 @@ func (c *Comp) NewBind(name string, class BindClass, t xr.Type) *Bind {
 			class = VarBind
 		}
 	}
+	if len(name) != 0 && name != "_" {
+		c.undoBind(name)
+	}
 	return c.CompBinds.NewBind(&c.Output, name, class, t)
 }
 
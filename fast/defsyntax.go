@@ -0,0 +1,411 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * defsyntax.go
+ *
+ *  Created on Oct 19, 2026
+ *      Author agent
+ */
+
+package fast
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	r "reflect"
+	"strings"
+
+	. "github.com/cosmos72/gomacro/ast2"
+	mt "github.com/cosmos72/gomacro/token"
+)
+
+// Pattern-based macros. They are declared with
+//
+//	~defsyntax NAME {
+//		~pattern { ITEM, ... } => { TEMPLATE }
+//		...
+//	}
+//
+// A call to the macro NAME is expanded with the TEMPLATE of the first ~pattern
+// whose items match the macro arguments. Each ITEM is an expression or a block { STATEMENTS }
+// and can contain metavariables, which match any node of the given kind:
+//
+//	$name, $name:expr  an expression
+//	$name:ident        an identifier
+//	$name:type         a type
+//	$name:stmt         a statement
+//	$name...           zero or more elements of a list: call arguments, composite literal elements...
+//	$name:stmt...      zero or more statements of a block
+//
+// The other nodes in the items must match exactly, ignoring source positions.
+// Each TEMPLATE is compiled as a ~quasiquote, where each metavariable is replaced by the node it matched,
+// as if it was written ~unquote{$name} or ~unquote_splice{$name}
+
+type syntaxKind uint8
+
+const (
+	syntaxExpr syntaxKind = iota
+	syntaxIdent
+	syntaxType
+	syntaxStmt
+)
+
+var syntaxKindNames = [...]string{
+	syntaxExpr:  "expr",
+	syntaxIdent: "ident",
+	syntaxType:  "type",
+	syntaxStmt:  "stmt",
+}
+
+// metavariable of a ~pattern
+type syntaxVar struct {
+	text   string // as written in source code
+	name   string // without '$', ':kind' and '...'
+	kind   syntaxKind
+	typed  bool // true if ':kind' was written explicitly
+	repeat bool
+	index  int // position among the parameters of the compiled template
+}
+
+// syntaxRule is a compiled ~pattern { ITEM, ... } => { TEMPLATE }
+type syntaxRule struct {
+	macro  string
+	text   string     // pattern source code, for error messages
+	items  []ast.Stmt // one per macro argument
+	vars   map[string]*syntaxVar
+	list   []*syntaxVar // in order of appearance
+	idents map[*ast.Ident]*syntaxVar
+}
+
+// return true if funcdecl was created by ~defsyntax
+func isDefSyntax(funcdecl *ast.FuncDecl) bool {
+	if funcdecl.Body == nil || len(funcdecl.Body.List) == 0 {
+		return false
+	}
+	_, ok := funcdecl.Body.List[0].(*ast.CaseClause)
+	return ok
+}
+
+// DeclSyntax compiles a macro declared with ~defsyntax
+func (c *Comp) DeclSyntax(funcdecl *ast.FuncDecl) {
+	name := funcdecl.Name.Name
+	n := len(funcdecl.Body.List)
+	rules := make([]*syntaxRule, n)
+	templates := make([]*ast.UnaryExpr, n)
+	argnum := -1
+	for i, stmt := range funcdecl.Body.List {
+		rules[i], templates[i] = c.syntaxRule(name, stmt)
+		if nitem := len(rules[i].items); argnum < 0 {
+			argnum = nitem
+		} else if nitem != argnum {
+			c.Errorf("%s %s: all patterns must have the same number of items, found %d and %d: %s",
+				mt.String(mt.DEFSYNTAX), name, argnum, nitem, rules[i].text)
+		}
+	}
+
+	oldbind := c.Binds[name]
+	panicking := true
+	defer func() {
+		// On compile error, restore pre-existing declaration
+		if !panicking || c.Binds == nil {
+			// nothing to do
+		} else if oldbind != nil {
+			c.Binds[name] = oldbind
+		} else {
+			delete(c.Binds, name)
+		}
+	}()
+	// use a ConstBind, as builtins and macros do
+	bind := c.NewBind(name, ConstBind, c.TypeOfMacro())
+
+	funs := make([]func(*Env) r.Value, n)
+	for i, rule := range rules {
+		funs[i] = c.syntaxTemplate(rule, templates[i])
+	}
+	if name == "_" {
+		// macro named "_". still compile it (to check for compile errors) but discard the compiled code
+		panicking = false
+		return
+	}
+	addr := &bind.Value
+	pos := funcdecl.Pos()
+	g := c.CompGlobals
+
+	// a ~defsyntax declaration is a statement:
+	// executing it stores the macro function into Comp.Binds[name].Value
+	stmt := func(env *Env) (Stmt, *Env) {
+		fs := make([]r.Value, len(funs))
+		for i, fun := range funs {
+			fs[i] = fun(env)
+		}
+		*addr = Macro{g.syntaxExpander(name, rules, fs), argnum, name, pos}
+		env.IP++
+		return env.Code[env.IP], env
+	}
+	c.Append(stmt, funcdecl.Pos())
+	panicking = false
+}
+
+// extract the pattern items and the template of a ~pattern clause, and collect the pattern metavariables
+func (c *Comp) syntaxRule(macro string, stmt ast.Stmt) (*syntaxRule, *ast.UnaryExpr) {
+	clause, ok := stmt.(*ast.CaseClause)
+	if !ok || len(clause.List) != 1 || len(clause.Body) != 1 {
+		c.Errorf("%s %s: invalid pattern, expecting %s { ITEM, ... } => { TEMPLATE }, found: %v",
+			mt.String(mt.DEFSYNTAX), macro, mt.String(mt.PATTERN), stmt)
+	}
+	pattern := clause.List[0].(*ast.UnaryExpr).X.(*ast.FuncLit).Body
+	template := clause.Body[0].(*ast.ExprStmt).X.(*ast.UnaryExpr)
+
+	text, ok := c.Fileset.SourceRange(clause.Case, pattern.Rbrace+1)
+	if !ok {
+		text = fmt.Sprintf("%s %v", mt.String(mt.PATTERN), pattern)
+	}
+	rule := &syntaxRule{
+		macro:  macro,
+		text:   text,
+		items:  pattern.List,
+		vars:   make(map[string]*syntaxVar),
+		idents: make(map[*ast.Ident]*syntaxVar),
+	}
+	for _, item := range rule.items {
+		if v := rule.varOf(c, ToAst(item)); v != nil && v.repeat {
+			c.Errorf("%s %s: repeated metavariable %s cannot be a whole pattern item: %s",
+				mt.String(mt.DEFSYNTAX), macro, v.text, text)
+		}
+		rule.collect(c, ToAst(item))
+	}
+	return rule, template
+}
+
+// parse a metavariable $name, $name:kind, $name... or $name:kind...
+// return nil if ident is not a metavariable
+func (c *Comp) parseSyntaxVar(ident *ast.Ident) *syntaxVar {
+	s := ident.Name
+	if len(s) < 2 || s[0] != '$' {
+		return nil
+	}
+	v := &syntaxVar{text: s}
+	if strings.HasSuffix(s, "...") {
+		v.repeat = true
+		s = s[:len(s)-3]
+	}
+	if i := strings.IndexByte(s, ':'); i >= 0 {
+		kind := s[i+1:]
+		s = s[:i]
+		v.typed = true
+		found := false
+		for k, name := range syntaxKindNames {
+			if kind == name {
+				v.kind = syntaxKind(k)
+				found = true
+				break
+			}
+		}
+		if !found {
+			c.Errorf("invalid metavariable %s: unknown kind %q, expecting one of: %s",
+				ident.Name, kind, strings.Join(syntaxKindNames[:], " "))
+		}
+	}
+	v.name = s[1:]
+	return v
+}
+
+// return the metavariable in form, which may be wrapped in an *ast.ExprStmt.
+// return nil if form is not a metavariable
+func (rule *syntaxRule) varOf(c *Comp, form Ast) *syntaxVar {
+	if stmt, ok := form.(ExprStmt); ok {
+		form = stmt.Get(0)
+	}
+	ident, ok := form.(Ident)
+	if !ok || ident.X == nil {
+		return nil
+	}
+	if v := rule.idents[ident.X]; v != nil || c == nil {
+		return v
+	}
+	return c.parseSyntaxVar(ident.X)
+}
+
+// collect the metavariables in a pattern item
+func (rule *syntaxRule) collect(c *Comp, form Ast) {
+	if form == nil || form.Interface() == nil {
+		return
+	}
+	if v := rule.varOf(c, form); v != nil {
+		if rule.vars[v.name] != nil {
+			c.Errorf("%s %s: duplicate metavariable $%s in pattern: %s",
+				mt.String(mt.DEFSYNTAX), rule.macro, v.name, rule.text)
+		}
+		if stmt, ok := form.(ExprStmt); ok {
+			form = stmt.Get(0)
+		}
+		v.index = len(rule.list)
+		rule.vars[v.name] = v
+		rule.list = append(rule.list, v)
+		rule.idents[form.(Ident).X] = v
+		return
+	}
+	_, islist := form.(AstWithSlice)
+	repeats := 0
+	for i, n := 0, form.Size(); i < n; i++ {
+		child := form.Get(i)
+		if v := rule.varOf(c, child); v != nil && v.repeat {
+			if !islist {
+				c.Errorf("%s %s: repeated metavariable %s must be an element of a list: %s",
+					mt.String(mt.DEFSYNTAX), rule.macro, v.text, rule.text)
+			}
+			repeats++
+		}
+		rule.collect(c, child)
+	}
+	if repeats > 1 {
+		c.Errorf("%s %s: at most one repeated metavariable is allowed in each list: %s",
+			mt.String(mt.DEFSYNTAX), rule.macro, rule.text)
+	}
+}
+
+// compile the template of a ~pattern into a closure
+// that accepts the values of the metavariables and returns the expansion
+func (c *Comp) syntaxTemplate(rule *syntaxRule, template *ast.UnaryExpr) func(*Env) r.Value {
+	pos := template.Pos()
+	params := make([]*ast.Field, len(rule.list))
+	for i, v := range rule.list {
+		params[i] = &ast.Field{
+			Names: []*ast.Ident{{NamePos: pos, Name: "$" + v.name}},
+			Type:  &ast.InterfaceType{Methods: &ast.FieldList{}},
+		}
+	}
+	lit := &ast.FuncLit{
+		Type: &ast.FuncType{
+			Func:   pos,
+			Params: &ast.FieldList{List: params},
+			Results: &ast.FieldList{List: []*ast.Field{
+				{Type: &ast.InterfaceType{Methods: &ast.FieldList{}}},
+			}},
+		},
+		Body: &ast.BlockStmt{List: []ast.Stmt{
+			&ast.ReturnStmt{Return: pos, Results: []ast.Expr{template}},
+		}},
+	}
+	g := c.CompGlobals
+	saved := g.syntax
+	g.syntax = rule
+	defer func() {
+		g.syntax = saved
+	}()
+	return c.FuncLit(lit).AsX1()
+}
+
+// compile a metavariable found inside the template of a ~pattern.
+// return ok == false if ident is not such a metavariable
+func (c *Comp) syntaxVarExpr(ident *ast.Ident, depth int, canSplice bool) (expr *Expr, splice bool, ok bool) {
+	rule := c.CompGlobals.syntax
+	if rule == nil || depth != 1 {
+		return nil, false, false
+	}
+	v := c.parseSyntaxVar(ident)
+	if v == nil {
+		return nil, false, false
+	}
+	bound := rule.vars[v.name]
+	prefix := mt.String(mt.DEFSYNTAX) + " " + rule.macro
+	switch {
+	case bound == nil:
+		c.Errorf("%s: metavariable $%s in template is not defined by pattern: %s", prefix, v.name, rule.text)
+	case v.typed && v.kind != bound.kind:
+		c.Errorf("%s: metavariable %s in template has kind %s in pattern: %s", prefix, v.text, syntaxKindNames[bound.kind], rule.text)
+	case bound.repeat && !v.repeat:
+		c.Errorf("%s: metavariable $%s is repeated in pattern, expecting $%s... in template", prefix, v.name, v.name)
+	case !bound.repeat && v.repeat:
+		c.Errorf("%s: metavariable $%s is not repeated in pattern, cannot write %s in template", prefix, v.name, v.text)
+	case v.repeat && !canSplice:
+		c.Errorf("%s: repeated metavariable %s in template must be an element of a list", prefix, v.text)
+	}
+	return c.Ident("$" + v.name), v.repeat, true
+}
+
+// return the function that expands the calls to a macro declared with ~defsyntax
+func (g *CompGlobals) syntaxExpander(macro string, rules []*syntaxRule, templates []r.Value) func(args []r.Value) []r.Value {
+	m := Matcher{
+		Default: syntaxKindNames[syntaxExpr],
+		Text: func(node ast.Node) string {
+			return g.Sprintf("%v", node)
+		},
+	}
+	return func(args []r.Value) []r.Value {
+		nodes := make([]ast.Node, len(args))
+		for i, arg := range args {
+			if arg.IsValid() && arg.CanInterface() {
+				nodes[i], _ = arg.Interface().(ast.Node)
+			}
+		}
+		reasons := make([]string, len(rules))
+		for i, rule := range rules {
+			vals, err := rule.match(&m, nodes)
+			if err == nil {
+				rets := templates[i].Call(vals)
+				if list, ok := rets[0].Interface().([]ast.Node); ok {
+					// template is a single repeated metavariable
+					block := &ast.BlockStmt{List: ToStmtSlice(NodeSlice{X: list})}
+					rets = []r.Value{r.ValueOf(block)}
+				}
+				return rets
+			}
+			reasons[i] = fmt.Sprintf("\n    %s\n        %v", rule.text, err)
+		}
+		texts := make([]string, len(nodes))
+		pos := token.NoPos
+		for i, node := range nodes {
+			texts[i] = g.Sprintf("%v", node)
+			if pos == token.NoPos && node != nil {
+				pos = node.Pos()
+			}
+		}
+		g.ErrorAt(pos, "%s %s: no pattern matches the arguments { %s }%s",
+			mt.String(mt.DEFSYNTAX), macro, strings.Join(texts, ", "), strings.Join(reasons, ""))
+		return nil
+	}
+}
+
+// match the macro arguments against the pattern items,
+// and return the values of the metavariables as arguments for the compiled template
+func (rule *syntaxRule) match(m *Matcher, args []ast.Node) ([]r.Value, error) {
+	vals := make([]r.Value, len(rule.list))
+	for i := range vals {
+		// $_ matches without binding
+		vals[i] = r.Zero(rtypeOfNode)
+	}
+	for i, item := range rule.items {
+		var arg Ast
+		if args[i] != nil {
+			arg = ToAst(args[i])
+		}
+		// metavariables are unique in a pattern, so each item binds different ones
+		b, err := m.Match(ToAst(item), arg)
+		if err != nil {
+			return nil, err
+		}
+		for name, form := range b {
+			v := rule.vars[name]
+			if !v.repeat {
+				vals[v.index] = r.ValueOf(ToNode(form))
+				continue
+			}
+			n := form.Size()
+			list := make([]ast.Node, n)
+			for j := 0; j < n; j++ {
+				list[j] = ToNode(form.Get(j))
+			}
+			vals[v.index] = r.ValueOf(list)
+		}
+	}
+	return vals, nil
+}
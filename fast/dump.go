@@ -0,0 +1,538 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * dump.go
+ *
+ *  Created on Oct 19, 2026
+ *      Author agent
+ */
+
+package fast
+
+import (
+	"go/ast"
+	"go/token"
+	"math"
+	r "reflect"
+	"strconv"
+	"time"
+
+	. "github.com/cosmos72/gomacro/base"
+	"github.com/cosmos72/gomacro/base/reflect"
+	mt "github.com/cosmos72/gomacro/token"
+	xr "github.com/cosmos72/gomacro/xreflect"
+)
+
+// LiteralCycle specifies what GoLiteral does when a value refers to itself
+type LiteralCycle uint8
+
+const (
+	LiteralCycleNil   LiteralCycle = iota // replace the back-reference with nil and a comment
+	LiteralCycleError                     // fail with an error
+)
+
+// LiteralOptions configures Interp.GoLiteral and the REPL command :dump
+type LiteralOptions struct {
+	MaxDepth int // maximum nesting of composite literals. deeper values are replaced by their zero value. 0 means unlimited
+	Cycle    LiteralCycle
+}
+
+// LiteralConstructors contains the functions used by GoLiteral
+// to render values of types that should not be written as composite literals,
+// for example time.Time is rendered as time.Date(...)
+var LiteralConstructors = map[r.Type]func(v r.Value) ast.Expr{
+	r.TypeOf(time.Duration(0)):      literalDuration,
+	r.TypeOf(time.Time{}):           literalTime,
+	r.TypeOf((*time.Location)(nil)): literalLocation,
+}
+
+// maximum number of lines of a literal. beyond it, no more newlines are inserted
+const literalMaxLines = 1 << 20
+
+// literalWriter converts a value to Go source code
+type literalWriter struct {
+	c        *Comp
+	opts     LiteralOptions
+	file     *token.File // fake file, used only to place newlines in the output
+	line     int
+	visiting map[literalVisit]bool // pointers, maps and slices being converted. used to detect cycles
+}
+
+type literalVisit struct {
+	kind r.Kind
+	addr uintptr
+	typ  r.Type
+}
+
+// GoLiteral returns a Go expression that evaluates to v.
+// Arbitrary values are rendered as composite literals,
+// including interpreted struct types, maps, slices and pointers,
+// while types listed in LiteralConstructors are rendered with their constructor.
+// t can be nil, in such case the type of v is used.
+// Unexported fields of compiled struct types are omitted: they cannot appear in a literal outside their package.
+// Depth limit and cycle handling are configured by Interp.Comp.Literal
+// The positions in the result belong to the returned FileSet,
+// thus it can be printed with output.Stringer{Fileset: fset}.Sprintf("%v", expr)
+func (ir *Interp) GoLiteral(v r.Value, t xr.Type) (ast.Expr, *mt.FileSet) {
+	c := ir.Comp
+	if v.IsValid() && v.CanInterface() {
+		if lit, ok := v.Interface().(UntypedLit); ok {
+			// untyped constant: use its default type
+			t = lit.DefaultType()
+			v = r.ValueOf(lit.Convert(t))
+		}
+	}
+	// use a private FileSet: the fake file would otherwise be kept forever by c.Fileset
+	fset := mt.NewFileSet()
+	lw := literalWriter{
+		c:        c,
+		opts:     c.Literal,
+		file:     fset.AddFile("", -1, literalMaxLines, 0).File,
+		visiting: make(map[literalVisit]bool),
+	}
+	expr := lw.expr(v, t, 0, false)
+	lines := make([]int, lw.line+1)
+	for i := range lines {
+		lines[i] = i
+	}
+	lw.file.SetLines(lines)
+	return expr, fset
+}
+
+// return the position at the beginning of a new line
+func (lw *literalWriter) newline() token.Pos {
+	if lw.file == nil {
+		return token.NoPos
+	}
+	if lw.line < literalMaxLines-1 {
+		lw.line++
+	}
+	return lw.file.Pos(lw.line)
+}
+
+// return a position on current line
+func (lw *literalWriter) pos() token.Pos {
+	if lw.file == nil {
+		return token.NoPos
+	}
+	return lw.file.Pos(lw.line)
+}
+
+func (lw *literalWriter) expr(v r.Value, t xr.Type, depth int, elide bool) ast.Expr {
+	if v.IsValid() && v != None && v.CanInterface() {
+		if ctor := LiteralConstructors[v.Type()]; ctor != nil {
+			return ctor(v)
+		}
+	}
+	if t == nil {
+		if !v.IsValid() || v == None {
+			return lw.ident("nil")
+		}
+		t = lw.c.Universe.FromReflectType(v.Type())
+	}
+	if v.IsValid() && v.Kind() == r.Interface && t.Kind() != r.Interface {
+		// recursive interpreted types are emulated with interface{}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return lw.zero(t, elide)
+	} else if ctor := LiteralConstructors[v.Type()]; ctor != nil && v.CanInterface() {
+		return ctor(v)
+	}
+	switch k := t.Kind(); k {
+	case r.Bool, r.Int, r.Int8, r.Int16, r.Int32, r.Int64,
+		r.Uint, r.Uint8, r.Uint16, r.Uint32, r.Uint64, r.Uintptr,
+		r.Float32, r.Float64, r.Complex64, r.Complex128, r.String:
+		return lw.basic(v, t, elide)
+	case r.Array, r.Map, r.Slice, r.Struct:
+		if k != r.Array && k != r.Struct && v.IsNil() {
+			return lw.ident("nil")
+		}
+		if lw.opts.MaxDepth > 0 && depth >= lw.opts.MaxDepth {
+			return lw.omit(t, elide, "max depth reached")
+		}
+		if k == r.Map || (k == r.Slice && v.Len() != 0) {
+			visit := literalVisit{k, v.Pointer(), v.Type()}
+			if lw.visiting[visit] {
+				return lw.cycle(t)
+			}
+			lw.visiting[visit] = true
+			defer delete(lw.visiting, visit)
+		}
+		return lw.composite(v, t, depth, elide)
+	case r.Ptr:
+		if v.IsNil() {
+			return lw.ident("nil")
+		}
+		visit := literalVisit{k, v.Pointer(), v.Type()}
+		if lw.visiting[visit] {
+			return lw.cycle(t)
+		}
+		lw.visiting[visit] = true
+		defer delete(lw.visiting, visit)
+		return lw.pointer(v, t, depth, elide)
+	case r.Interface:
+		if v.IsNil() {
+			return lw.ident("nil")
+		}
+		return lw.expr(v.Elem(), nil, depth, false)
+	case r.Chan:
+		if v.IsNil() {
+			return lw.ident("nil")
+		}
+		// the contents of the channel are not rendered
+		args := []ast.Expr{lw.typeExpr(t)}
+		if cap := v.Cap(); cap != 0 {
+			args = append(args, lw.intLit(int64(cap)))
+		}
+		return &ast.CallExpr{Fun: lw.ident("make"), Args: args}
+	default:
+		// functions and unsafe pointers
+		if v.IsNil() || v.Pointer() == 0 {
+			return lw.ident("nil")
+		}
+		return lw.comment("nil", "cannot render "+k.String())
+	}
+}
+
+// render a boolean, number or string
+func (lw *literalWriter) basic(v r.Value, t xr.Type, elide bool) ast.Expr {
+	var lit ast.Expr
+	var def r.Kind // kind of literal default type
+	switch k := v.Kind(); k {
+	case r.Bool:
+		lit, def = lw.ident(strconv.FormatBool(v.Bool())), r.Bool
+	case r.Int, r.Int8, r.Int16, r.Int32, r.Int64:
+		lit, def = lw.intLit(v.Int()), r.Int
+	case r.Uint, r.Uint8, r.Uint16, r.Uint32, r.Uint64, r.Uintptr:
+		lit, def = &ast.BasicLit{ValuePos: lw.pos(), Kind: token.INT, Value: strconv.FormatUint(v.Uint(), 10)}, r.Int
+	case r.Float32, r.Float64:
+		lit, def = lw.floatLit(v.Float(), v.Type().Bits()), r.Float64
+	case r.Complex64, r.Complex128:
+		c := v.Complex()
+		bits := v.Type().Bits() / 2
+		lit = &ast.CallExpr{Fun: lw.ident("complex"),
+			Args: []ast.Expr{lw.floatLit(real(c), bits), lw.floatLit(imag(c), bits)}}
+		def = r.Complex128
+	case r.String:
+		lit, def = &ast.BasicLit{ValuePos: lw.pos(), Kind: token.STRING, Value: strconv.Quote(v.String())}, r.String
+	}
+	if elide || (t.Kind() == def && t.PkgPath() == "" && t.Name() == def.String()) {
+		// either the type is implied by the context, or it is the default type of the literal
+		return lit
+	}
+	return &ast.CallExpr{Fun: lw.typeExpr(t), Args: []ast.Expr{lit}}
+}
+
+func (lw *literalWriter) intLit(i int64) ast.Expr {
+	return &ast.BasicLit{ValuePos: lw.pos(), Kind: token.INT, Value: strconv.FormatInt(i, 10)}
+}
+
+func (lw *literalWriter) floatLit(f float64, bits int) ast.Expr {
+	switch {
+	case math.IsNaN(f):
+		return lw.call("math", "NaN")
+	case math.IsInf(f, 0):
+		sign := 1
+		if f < 0 {
+			sign = -1
+		}
+		return lw.call("math", "Inf", lw.intLit(int64(sign)))
+	}
+	str := strconv.FormatFloat(f, 'g', -1, bits)
+	if _, err := strconv.ParseInt(str, 10, 64); err == nil {
+		// keep the literal untyped float, not untyped int
+		str += ".0"
+	}
+	return &ast.BasicLit{ValuePos: lw.pos(), Kind: token.FLOAT, Value: str}
+}
+
+// render an array, map, slice or struct as a composite literal
+func (lw *literalWriter) composite(v r.Value, t xr.Type, depth int, elide bool) ast.Expr {
+	lit := &ast.CompositeLit{Lbrace: lw.pos()}
+	if !elide {
+		lit.Type = lw.typeExpr(t)
+	}
+	switch t.Kind() {
+	case r.Array, r.Slice:
+		n := v.Len()
+		multiline := literalMultiline(t.Elem())
+		for i := 0; i < n; i++ {
+			if multiline {
+				lw.newline()
+			}
+			lit.Elts = append(lit.Elts, lw.expr(v.Index(i), t.Elem(), depth+1, true))
+		}
+	case r.Map:
+		for _, key := range reflect.SortedMapKeys(v) {
+			pos := lw.newline()
+			lit.Elts = append(lit.Elts, &ast.KeyValueExpr{
+				Key:   lw.expr(key, t.Key(), depth+1, true),
+				Colon: pos,
+				Value: lw.expr(v.MapIndex(key), t.Elem(), depth+1, true),
+			})
+		}
+	case r.Struct:
+		n := t.NumField()
+		for i := 0; i < n; i++ {
+			field := v.Field(i)
+			if field.IsZero() || !field.CanInterface() {
+				// skip zero fields, and also unexported fields of compiled types:
+				// they come from another package, where a literal could not set them.
+				// fields of interpreted types are always exported at reflect level, see StrGensymPrivate
+				continue
+			}
+			ft := t.Field(i)
+			lit.Elts = append(lit.Elts, &ast.KeyValueExpr{
+				Key:   &ast.Ident{NamePos: lw.newline(), Name: literalFieldName(ft)},
+				Value: lw.expr(field, ft.Type, depth+1, false),
+			})
+		}
+	}
+	if len(lit.Elts) != 0 && lit.Lbrace != lw.pos() {
+		lit.Rbrace = lw.newline()
+	} else {
+		lit.Rbrace = lw.pos()
+	}
+	return lit
+}
+
+// return true if arrays or slices with element type t should be rendered one element per line
+func literalMultiline(t xr.Type) bool {
+	switch t.Kind() {
+	case r.Array, r.Interface, r.Map, r.Ptr, r.Slice, r.Struct:
+		return true
+	}
+	return false
+}
+
+// return the name of a struct field as written in the source code
+func literalFieldName(field xr.StructField) string {
+	name := field.Name
+	if IsGensymPrivate(name) {
+		name = name[len(StrGensymPrivate):]
+	}
+	return name
+}
+
+// render a non-nil pointer
+func (lw *literalWriter) pointer(v r.Value, t xr.Type, depth int, elide bool) ast.Expr {
+	elem := v.Elem()
+	if elem.Kind() == r.Interface && t.Elem().Kind() != r.Interface {
+		elem = elem.Elem()
+	}
+	var expr ast.Expr
+	if elem.IsValid() && LiteralConstructors[elem.Type()] == nil {
+		switch t.Elem().Kind() {
+		case r.Array, r.Map, r.Slice, r.Struct:
+			expr = lw.expr(elem, t.Elem(), depth, elide)
+			if _, ok := expr.(*ast.CompositeLit); ok {
+				if elide {
+					// &T is implied by the context
+					return expr
+				}
+				return &ast.UnaryExpr{OpPos: lw.pos(), Op: token.AND, X: expr}
+			}
+		}
+	}
+	if expr == nil {
+		expr = lw.expr(elem, t.Elem(), depth, true)
+	}
+	// pointer to non-composite value: use &[]T{value}[0]
+	slice := &ast.CompositeLit{
+		Type: &ast.ArrayType{Lbrack: lw.pos(), Elt: lw.typeExpr(t.Elem())},
+		Elts: []ast.Expr{expr},
+	}
+	return &ast.UnaryExpr{OpPos: lw.pos(), Op: token.AND,
+		X: &ast.IndexExpr{X: slice, Index: lw.intLit(0)}}
+}
+
+// render the zero value of type t
+func (lw *literalWriter) zero(t xr.Type, elide bool) ast.Expr {
+	switch k := t.Kind(); k {
+	case r.Bool, r.Int, r.Int8, r.Int16, r.Int32, r.Int64,
+		r.Uint, r.Uint8, r.Uint16, r.Uint32, r.Uint64, r.Uintptr,
+		r.Float32, r.Float64, r.Complex64, r.Complex128, r.String:
+		return lw.basic(r.Zero(reflect.KindToType(k)), t, elide)
+	case r.Array, r.Struct:
+		lit := &ast.CompositeLit{Lbrace: lw.pos(), Rbrace: lw.pos()}
+		if !elide {
+			lit.Type = lw.typeExpr(t)
+		}
+		return lit
+	default:
+		return lw.ident("nil")
+	}
+}
+
+// render the zero value of type t, followed by a comment explaining why
+func (lw *literalWriter) omit(t xr.Type, elide bool, reason string) ast.Expr {
+	return lw.comment(lw.c.Sprintf("%v", lw.zero(t, elide)), reason)
+}
+
+// handle a value that refers to itself
+func (lw *literalWriter) cycle(t xr.Type) ast.Expr {
+	if lw.opts.Cycle == LiteralCycleError {
+		lw.c.Errorf("cannot render <%v> as Go source: value contains a cycle", t)
+	}
+	return lw.comment("nil", "cycle")
+}
+
+// return an identifier that prints as "code /* comment */".
+// go/printer prints identifiers verbatim
+func (lw *literalWriter) comment(code string, comment string) ast.Expr {
+	return lw.ident(code + " /* " + comment + " */")
+}
+
+func (lw *literalWriter) ident(name string) *ast.Ident {
+	return &ast.Ident{NamePos: lw.pos(), Name: name}
+}
+
+// return the expression pkg.name(args...)
+func (lw *literalWriter) call(pkg string, name string, args ...ast.Expr) ast.Expr {
+	return &ast.CallExpr{Fun: lw.selector(pkg, name), Args: args}
+}
+
+func (lw *literalWriter) selector(pkg string, name string) ast.Expr {
+	return &ast.SelectorExpr{X: lw.ident(pkg), Sel: lw.ident(name)}
+}
+
+// ================================= types =================================
+
+// convert a type to an ast.Expr
+func (lw *literalWriter) typeExpr(t xr.Type) ast.Expr {
+	if name := t.Name(); len(name) != 0 {
+		pkg := t.PkgPath()
+		if len(pkg) == 0 || pkg == lw.c.FileComp().Path {
+			return lw.ident(name)
+		}
+		return lw.selector(t.PkgName(), name)
+	}
+	switch t.Kind() {
+	case r.Array:
+		return &ast.ArrayType{Lbrack: lw.pos(), Len: lw.intLit(int64(t.Len())), Elt: lw.typeExpr(t.Elem())}
+	case r.Chan:
+		dir := ast.SEND | ast.RECV
+		switch t.ChanDir() {
+		case r.RecvDir:
+			dir = ast.RECV
+		case r.SendDir:
+			dir = ast.SEND
+		}
+		return &ast.ChanType{Begin: lw.pos(), Dir: dir, Value: lw.typeExpr(t.Elem())}
+	case r.Func:
+		return lw.funcType(t)
+	case r.Map:
+		return &ast.MapType{Map: lw.pos(), Key: lw.typeExpr(t.Key()), Value: lw.typeExpr(t.Elem())}
+	case r.Ptr:
+		return &ast.StarExpr{Star: lw.pos(), X: lw.typeExpr(t.Elem())}
+	case r.Slice:
+		return &ast.ArrayType{Lbrack: lw.pos(), Elt: lw.typeExpr(t.Elem())}
+	case r.Struct:
+		n := t.NumField()
+		fields := make([]*ast.Field, n)
+		for i := 0; i < n; i++ {
+			ft := t.Field(i)
+			field := &ast.Field{Type: lw.typeExpr(ft.Type)}
+			if !ft.Anonymous {
+				field.Names = []*ast.Ident{lw.ident(literalFieldName(ft))}
+			}
+			if len(ft.Tag) != 0 {
+				field.Tag = &ast.BasicLit{ValuePos: lw.pos(), Kind: token.STRING, Value: strconv.Quote(string(ft.Tag))}
+			}
+			fields[i] = field
+		}
+		return &ast.StructType{Struct: lw.pos(), Fields: lw.fieldList(fields)}
+	case r.Interface:
+		if t.NumMethod() == 0 {
+			return &ast.InterfaceType{Interface: lw.pos(), Methods: lw.fieldList(nil)}
+		}
+	}
+	// unnamed interface with methods. String() produces valid Go source for them
+	return lw.ident(t.String())
+}
+
+func (lw *literalWriter) fieldList(fields []*ast.Field) *ast.FieldList {
+	return &ast.FieldList{Opening: lw.pos(), List: fields, Closing: lw.pos()}
+}
+
+func (lw *literalWriter) funcType(t xr.Type) *ast.FuncType {
+	types := func(n int, get func(int) xr.Type) *ast.FieldList {
+		list := lw.fieldList(make([]*ast.Field, n))
+		for i := 0; i < n; i++ {
+			list.List[i] = &ast.Field{Type: lw.typeExpr(get(i))}
+		}
+		return list
+	}
+	params := types(t.NumIn(), t.In)
+	if t.IsVariadic() {
+		last := params.List[len(params.List)-1]
+		last.Type = &ast.Ellipsis{Ellipsis: lw.pos(), Elt: last.Type.(*ast.ArrayType).Elt}
+	}
+	return &ast.FuncType{Func: lw.pos(), Params: params, Results: types(t.NumOut(), t.Out)}
+}
+
+// ================================= constructors =================================
+
+var literalDurationUnits = []struct {
+	d    time.Duration
+	name string
+}{
+	{time.Hour, "Hour"},
+	{time.Minute, "Minute"},
+	{time.Second, "Second"},
+	{time.Millisecond, "Millisecond"},
+	{time.Microsecond, "Microsecond"},
+}
+
+// render a time.Duration, as 3 * time.Second
+func literalDuration(v r.Value) ast.Expr {
+	var lw literalWriter
+	d := time.Duration(v.Int())
+	if d != 0 {
+		for _, unit := range literalDurationUnits {
+			if d%unit.d == 0 {
+				return &ast.BinaryExpr{X: lw.intLit(int64(d / unit.d)), Op: token.MUL, Y: lw.selector("time", unit.name)}
+			}
+		}
+	}
+	return lw.call("time", "Duration", lw.intLit(int64(d)))
+}
+
+// render a time.Time, as time.Date(2018, time.April, 20, 0, 0, 0, 0, time.UTC)
+func literalTime(v r.Value) ast.Expr {
+	var lw literalWriter
+	t := v.Interface().(time.Time)
+	return lw.call("time", "Date",
+		lw.intLit(int64(t.Year())), lw.selector("time", t.Month().String()), lw.intLit(int64(t.Day())),
+		lw.intLit(int64(t.Hour())), lw.intLit(int64(t.Minute())), lw.intLit(int64(t.Second())),
+		lw.intLit(int64(t.Nanosecond())), literalLocationOf(&lw, t))
+}
+
+// render a *time.Location, as time.UTC or time.FixedZone("CET", 3600)
+func literalLocation(v r.Value) ast.Expr {
+	var lw literalWriter
+	if v.IsNil() {
+		return lw.ident("nil")
+	}
+	return literalLocationOf(&lw, time.Date(2000, time.January, 1, 0, 0, 0, 0, v.Interface().(*time.Location)))
+}
+
+func literalLocationOf(lw *literalWriter, t time.Time) ast.Expr {
+	switch loc := t.Location(); loc {
+	case time.UTC:
+		return lw.selector("time", "UTC")
+	case time.Local:
+		return lw.selector("time", "Local")
+	}
+	name, offset := t.Zone()
+	return lw.call("time", "FixedZone",
+		&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(name)}, lw.intLit(int64(offset)))
+}
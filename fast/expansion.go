@@ -0,0 +1,128 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * expansion.go
+ *
+ *  Created on Oct 19, 2026
+ *      Author agent
+ */
+
+package fast
+
+import (
+	"go/ast"
+	"go/token"
+	r "reflect"
+
+	. "github.com/cosmos72/gomacro/ast2"
+	mt "github.com/cosmos72/gomacro/token"
+)
+
+// Provenance of macroexpanded code. The nodes created by a macro have either no position
+// or a position inside the macro declaration, which is not useful for compile errors,
+// runtime panics and the debugger. So the nodes created by a macro are moved
+// to the position of the macro call, and the expansion is recorded in the Fileset:
+// Fileset.ExpansionString(pos) then describes the macro call and the macro declaration.
+//
+// The nodes passed to the macro as arguments keep their position.
+
+var rtypeOfPos = r.TypeOf(token.NoPos)
+
+// record the expansion of a call to macro, and move the nodes created by the macro to the position of the call.
+// call is the macro name in the source, args are the macro arguments, and out is the macro result
+func (c *Comp) markExpansion(macro Macro, call Ast, args []r.Value, out Ast) Ast {
+	var pos token.Pos
+	if call, ok := call.(AstWithNode); ok && call.Node() != nil {
+		pos = call.Node().Pos()
+	}
+	if pos == token.NoPos || c.Fileset == nil || out == nil {
+		return out
+	}
+	keep := make(map[ast.Node]bool)
+	for _, arg := range args {
+		if !arg.IsValid() || !arg.CanInterface() {
+			continue
+		}
+		if node, ok := arg.Interface().(ast.Node); ok && node != nil {
+			ast.Inspect(node, func(node ast.Node) bool {
+				if node != nil {
+					keep[node] = true
+				}
+				return true
+			})
+		}
+	}
+	c.Fileset.AddExpansion(&mt.Expansion{Macro: macro.name, Call: pos, Def: macro.pos})
+	return reposition(out, pos, keep)
+}
+
+// return a copy of in, where all positions are replaced by pos.
+// nodes in keep are not copied and keep their position
+func reposition(in Ast, pos token.Pos, keep map[ast.Node]bool) Ast {
+	if in == nil {
+		return nil
+	}
+	var out Ast
+	if form, ok := in.(AstWithNode); ok {
+		node := form.Node()
+		if node == nil || keep[node] {
+			return in
+		}
+		out = in.New()
+		setPositions(out.(AstWithNode).Node(), pos)
+	} else {
+		out = in.New()
+	}
+	n := in.Size()
+	if outSlice, ok := out.(AstWithSlice); ok {
+		// New() returns zero-length slice... resize it
+		for outSlice.Size() < n {
+			outSlice = outSlice.Append(nil)
+		}
+		out = outSlice
+	}
+	for i := 0; i < n; i++ {
+		out.Set(i, reposition(in.Get(i), pos, keep))
+	}
+	return out
+}
+
+// token.Pos fields whose absence has a meaning, as f() versus f(x...) or type A int versus type A = int.
+// setPositions must not set them if they are missing
+var optionalPos = map[r.Type]string{
+	r.TypeOf(ast.CallExpr{}): "Ellipsis",
+	r.TypeOf(ast.ChanType{}): "Arrow",
+	r.TypeOf(ast.GenDecl{}):  "Lparen",
+	r.TypeOf(ast.TypeSpec{}): "Assign",
+}
+
+// set all the token.Pos fields of node to pos
+func setPositions(node ast.Node, pos token.Pos) {
+	v := r.ValueOf(node)
+	if v.Kind() != r.Ptr || v.IsNil() {
+		return
+	}
+	v = v.Elem()
+	if v.Kind() != r.Struct {
+		return
+	}
+	t := v.Type()
+	optional := optionalPos[t]
+	for i, n := 0, v.NumField(); i < n; i++ {
+		field := v.Field(i)
+		if field.Type() != rtypeOfPos || !field.CanSet() {
+			continue
+		}
+		if field.Int() == int64(token.NoPos) && t.Field(i).Name == optional {
+			continue
+		}
+		field.SetInt(int64(pos))
+	}
+}
@@ -0,0 +1,282 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * forget.go
+ *
+ *  Created on Oct 19, 2026
+ *      Author agent
+ */
+
+package fast
+
+import (
+	r "reflect"
+	"sort"
+	"strings"
+
+	"github.com/cosmos72/gomacro/base"
+	"github.com/cosmos72/gomacro/base/output"
+	xr "github.com/cosmos72/gomacro/xreflect"
+)
+
+// maximum number of inputs that can be reverted with :undo
+const maxUndo = 100
+
+// undoEntry contains what is needed to revert the declarations made by one REPL input
+type undoEntry struct {
+	comp    *Comp
+	env     *Env
+	binds   map[string]*Bind   // previous binds of the names declared by the input. nil if they did not exist
+	types   map[string]xr.Type // previous types of the names declared by the input. nil if they did not exist
+	sources map[string]string  // previous sources of the names declared by the input. "" if they did not exist
+	vals    map[int]r.Value    // previous content of Env.Vals slots reused by redefinitions
+	ints    map[int]uint64     // previous content of Env.Ints slots reused by redefinitions
+}
+
+// start recording the declarations made by a REPL input
+func (ir *Interp) beginUndo() {
+	ir.Comp.CompGlobals.undoing = &undoEntry{comp: ir.Comp, env: ir.env}
+}
+
+// stop recording the declarations made by a REPL input, and remember how to revert them
+func (ir *Interp) endUndo() {
+	g := ir.Comp.CompGlobals
+	e := g.undoing
+	g.undoing = nil
+	if e == nil || (e.binds == nil && e.types == nil && e.sources == nil) {
+		// nothing declared
+		return
+	}
+	g.undo = append(g.undo, *e)
+	if n := len(g.undo); n > maxUndo {
+		g.undo = g.undo[n-maxUndo:]
+	}
+}
+
+// return the undoEntry recording the declarations of c, or nil if not recording them
+func (c *Comp) undoEntry() *undoEntry {
+	if e := c.CompGlobals.undoing; e != nil && e.comp == c {
+		return e
+	}
+	return nil
+}
+
+// remember the current bind of name, before it is replaced
+func (c *Comp) undoBind(name string) {
+	e := c.undoEntry()
+	if e == nil {
+		return
+	}
+	if _, saved := e.binds[name]; saved {
+		return
+	}
+	if e.binds == nil {
+		e.binds = make(map[string]*Bind)
+	}
+	old := c.Binds[name]
+	e.binds[name] = old
+	if old != nil {
+		e.saveSlots(old)
+	}
+}
+
+// remember the current type of name, before it is replaced
+func (c *Comp) undoType(name string) {
+	e := c.undoEntry()
+	if e == nil {
+		return
+	}
+	if _, saved := e.types[name]; saved {
+		return
+	}
+	if e.types == nil {
+		e.types = make(map[string]xr.Type)
+	}
+	e.types[name] = c.Types[name]
+}
+
+// remember the current source of name, before it is replaced
+func (c *Comp) undoSource(name string) {
+	e := c.undoEntry()
+	if e == nil {
+		return
+	}
+	if _, saved := e.sources[name]; saved {
+		return
+	}
+	if e.sources == nil {
+		e.sources = make(map[string]string)
+	}
+	e.sources[name] = c.Sources[name]
+}
+
+// save the content of the slots used by bind: redefinitions may reuse them
+func (e *undoEntry) saveSlots(bind *Bind) {
+	idx := bind.Desc.Index()
+	if idx < 0 {
+		return
+	}
+	env := e.env
+	switch bind.Desc.Class() {
+	case VarBind, FuncBind:
+		if idx < len(env.Vals) {
+			if e.vals == nil {
+				e.vals = make(map[int]r.Value)
+			}
+			e.vals[idx] = env.Vals[idx]
+		}
+	case IntBind:
+		n := 1
+		if bind.Type.Kind() == r.Complex128 {
+			n = 2
+		}
+		for i := idx; i < idx+n && i < len(env.Ints); i++ {
+			if e.ints == nil {
+				e.ints = make(map[int]uint64)
+			}
+			e.ints[i] = env.Ints[i]
+		}
+	}
+}
+
+// revert the declarations made by the last REPL input that declared something
+func (ir *Interp) cmdUndo(arg string, opt base.CmdOpt) (string, base.CmdOpt) {
+	g := ir.Comp.CompGlobals
+	n := len(g.undo)
+	if n == 0 {
+		g.Fprintf(g.Stdout, "// nothing to undo\n")
+		return "", opt
+	}
+	e := g.undo[n-1]
+	g.undo = g.undo[:n-1]
+	names := e.revert()
+	g.Fprintf(g.Stdout, "// undone: %s\n", strings.Join(names, " "))
+	return "", opt
+}
+
+// revert the declarations in e. return the names of reverted declarations
+func (e *undoEntry) revert() []string {
+	c := e.comp
+	seen := make(map[string]bool)
+	for name, old := range e.binds {
+		if bind := c.Binds[name]; bind != nil && bind != old {
+			releaseBind(e.env, bind)
+		}
+		if old == nil {
+			delete(c.Binds, name)
+		} else {
+			c.Binds[name] = old
+		}
+		seen[name] = true
+	}
+	for idx, val := range e.vals {
+		if idx < len(e.env.Vals) {
+			e.env.Vals[idx] = val
+		}
+	}
+	for idx, val := range e.ints {
+		if idx < len(e.env.Ints) {
+			e.env.Ints[idx] = val
+		}
+	}
+	for name, old := range e.types {
+		if old == nil {
+			delete(c.Types, name)
+		} else {
+			c.Types[name] = old
+		}
+		seen[name] = true
+	}
+	for name, old := range e.sources {
+		if len(old) == 0 {
+			delete(c.Sources, name)
+		} else {
+			c.Sources[name] = old
+		}
+	}
+	return sortedNames(seen)
+}
+
+// remove the named variables, functions, constants, types and macros declared at top level
+func (ir *Interp) cmdForget(arg string, opt base.CmdOpt) (string, base.CmdOpt) {
+	c := ir.Comp
+	g := &c.Globals
+	names := strings.Fields(arg)
+	if len(names) == 0 {
+		g.Fprintf(g.Stdout, "// missing NAME. usage: %cforget NAME...\n", g.ReplCmdChar)
+		return "", opt
+	}
+	for _, name := range names {
+		if !ir.forget(name) {
+			g.Fprintf(g.Stdout, "// not found: %s\n", name)
+		}
+	}
+	return "", opt
+}
+
+// remove the top-level declaration of name. return false if not found
+func (ir *Interp) forget(name string) bool {
+	c := ir.Comp
+	found := false
+	if bind := c.Binds[name]; bind != nil {
+		delete(c.Binds, name)
+		releaseBind(ir.env, bind)
+		found = true
+	}
+	if _, ok := c.Types[name]; ok {
+		delete(c.Types, name)
+		found = true
+	}
+	delete(c.Sources, name)
+	return found
+}
+
+// release the value of a variable or function that is no longer reachable by name.
+// the slot in env is not reused: already compiled code may still refer to it,
+// and reusing the slot for a different variable would change what such code sees.
+// For the same reason, the slot must keep a valid value of the same type
+func releaseBind(env *Env, bind *Bind) {
+	idx := bind.Desc.Index()
+	switch bind.Desc.Class() {
+	case VarBind:
+		if idx >= 0 && idx < len(env.Vals) {
+			// zero the variable in place: its address may have been taken
+			if v := env.Vals[idx]; v.IsValid() && v.CanSet() {
+				v.Set(r.Zero(v.Type()))
+			}
+		}
+	case FuncBind:
+		if idx >= 0 && idx < len(env.Vals) {
+			env.Vals[idx] = forgottenFunc(bind)
+		}
+	case IntBind:
+		// Env.Ints contains no pointers: there is nothing to release.
+		// do not zero it either: its address may have been taken
+	}
+}
+
+// return a function with the type of bind, which panics if called.
+// replaces forgotten functions that compiled code may still call
+func forgottenFunc(bind *Bind) r.Value {
+	name := bind.Name
+	return r.MakeFunc(bind.Type.ReflectType(), func([]r.Value) []r.Value {
+		output.Errorf("function %s was forgotten", name)
+		return nil
+	})
+}
+
+func sortedNames(set map[string]bool) []string {
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -27,6 +27,7 @@ import (
 
 type funcMaker struct {
 	Name      string
+	qualName  string
 	nbind     int
 	nintbind  int
 	Param     []*Bind
@@ -45,6 +46,10 @@ func (c *Comp) DeclFunc(funcdecl *ast.FuncDecl) {
 	if funcdecl.Recv != nil {
 		switch n := len(funcdecl.Recv.List); n {
 		case 0:
+			if isDefSyntax(funcdecl) {
+				c.DeclSyntax(funcdecl)
+				return
+			}
 			ismacro = true
 		case 1:
 			c.methodDecl(funcdecl)
@@ -106,15 +111,17 @@ func (c *Comp) DeclFunc(funcdecl *ast.FuncDecl) {
 
 		addr := &funcbind.Value
 		argnum := t.NumIn()
+		pos := funcdecl.Pos()
 		stmt = func(env *Env) (Stmt, *Env) {
 			fun := f(env)
-			*addr = Macro{fun, argnum}
+			*addr = Macro{fun, argnum, funcname, pos}
 			env.IP++
 			return env.Code[env.IP], env
 		}
 	} else {
 		// a function declaration is a statement:
 		// executing it creates the function in the runtime environment
+		funcbody = cf.traceFuncBody(funcname, nil, funcdecl.Pos(), info, funcbody)
 		f := cf.funcCreate(t, info, resultfuns, funcbody)
 
 		stmt = func(env *Env) (Stmt, *Env) {
@@ -185,6 +192,7 @@ func (c *Comp) methodDecl(funcdecl *ast.FuncDecl) {
 	}
 	// do NOT keep a reference to compile environment!
 	funcbody := cf.Code.Exec()
+	funcbody = cf.traceFuncBody(funcdecl.Name.Name, t.In(0), funcdecl.Pos(), info, funcbody)
 	f := cf.funcCreate(t, info, resultfuns, funcbody)
 
 	// a method declaration is a statement:
@@ -230,6 +238,7 @@ func (c *Comp) FuncLit(funclit *ast.FuncLit) *Expr {
 	}
 	// do NOT keep a reference to compile environment!
 	funcbody := cf.Code.Exec()
+	funcbody = cf.traceFuncBody("", nil, funclit.Pos(), info, funcbody)
 
 	f := cf.funcCreate(t, info, resultfuns, funcbody)
 
@@ -317,6 +326,7 @@ func (c *Comp) funcResultBinds(functype *ast.FuncType, t xr.Type, names []string
 func (c *Comp) funcMaker(info *FuncInfo, resultfuns []I, funcbody func(*Env)) *funcMaker {
 	m := &funcMaker{
 		Name:      info.Name,
+		qualName:  info.qualName,
 		nbind:     c.BindNum,
 		nintbind:  c.IntBindNum,
 		Param:     info.Param,
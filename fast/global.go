@@ -17,6 +17,7 @@
 package fast
 
 import (
+	"context"
 	"fmt"
 	"go/ast"
 	"go/constant"
@@ -209,6 +210,8 @@ type Function struct {
 type Macro struct {
 	closure func(args []r.Value) (results []r.Value)
 	argNum  int
+	name    string    // name used in the macro declaration
+	pos     token.Pos // position of the macro declaration
 }
 
 // ================================= BindClass =================================
@@ -458,6 +461,7 @@ type FuncInfo struct {
 	Param        []*Bind
 	Result       []*Bind
 	NamedResults bool
+	qualName     string // qualified name shown by :trace, pprof labels and :profile. set by traceFuncBody
 }
 
 const (
@@ -470,6 +474,7 @@ const (
 	EFStartDefer ExecFlags = 1 << iota // true next executed function body is a defer
 	EFDefer                            // function body being executed is a defer
 	EFDebug                            // function body is executed with debugging enabled
+	EFCatchPanic                       // intercept panics before unwinding the stack: to invoke the debugger or collect a stack trace
 )
 
 func (ef ExecFlags) StartDefer() bool {
@@ -484,6 +489,10 @@ func (ef ExecFlags) IsDebug() bool {
 	return ef&EFDebug != 0
 }
 
+func (ef ExecFlags) IsCatchPanic() bool {
+	return ef&EFCatchPanic != 0
+}
+
 func (ef *ExecFlags) SetDefer(flag bool) {
 	if flag {
 		(*ef) |= EFDefer
@@ -508,6 +517,14 @@ func (ef *ExecFlags) SetDebug(flag bool) {
 	}
 }
 
+func (ef *ExecFlags) SetCatchPanic(flag bool) {
+	if flag {
+		(*ef) |= EFCatchPanic
+	} else {
+		(*ef) &^= EFCatchPanic
+	}
+}
+
 type DebugOp struct {
 	// statements at env.CallDepth < Depth will be executed in single-stepping mode,
 	// i.e. invoking the debugger after every statement
@@ -528,10 +545,20 @@ type Debugger interface {
 	At(ir *Interp, env *Env) DebugOp
 }
 
+// PanicDebugger is implemented by debuggers that can be invoked
+// when interpreted code panics and OptPanicDebugger is set
+type PanicDebugger interface {
+	Debugger
+	// Panic is invoked before the stack is unwound. rec is the value passed to panic()
+	Panic(ir *Interp, env *Env, rec interface{}) DebugOp
+}
+
 // IrGlobals contains interpreter configuration
 type IrGlobals struct {
-	gls  map[uintptr]*Run
-	lock atomic.SpinLock
+	gls      map[uintptr]*Run
+	lock     atomic.SpinLock
+	profiler *profiler // created by Interp.StartProfile. accessed atomically
+	stdio    *stdio    // created by Interp.SetStdio
 	Globals
 }
 
@@ -542,11 +569,16 @@ type Run struct {
 	Interrupt    Stmt
 	Signals      Signals // set by defer, return, breakpoint, debugger and Run.interrupt(os.Signal)
 	ExecFlags    ExecFlags
-	CurrEnv      *Env        // caller of current function. used ONLY at function entry to build call stack
-	InstallDefer func()      // defer function to be installed
-	DeferOfFun   *Env        // function whose defer are running
-	PanicFun     *Env        // the currently panicking function
-	Panic        interface{} // current panic. needed for recover()
+	CurrEnv      *Env            // caller of current function. used ONLY at function entry to build call stack
+	InstallDefer func()          // defer function to be installed
+	DeferOfFun   *Env            // function whose defer are running
+	PanicFun     *Env            // the currently panicking function
+	Panic        interface{}     // current panic. needed for recover()
+	PanicStack   StackTrace      // interpreted stack trace of current panic. collected only if OptPanicStackTrace is set
+	caughtPanic  bool            // true if the current panic was already intercepted
+	labelCtx     context.Context // pprof labels of the current goroutine. used only if OptPprofLabels is set
+	constSteps   int             // remaining loop iterations and calls while evaluating a const func at compile time
+	constDepth   int             // maximum call depth while evaluating a const func at compile time
 	CmdOpt       CmdOpt
 	Debugger     Debugger
 	DebugDepth   int // depth of function to debug with single-step
@@ -558,9 +590,20 @@ type Run struct {
 type CompGlobals struct {
 	*IrGlobals
 	Universe     *xr.Universe
-	KnownImports map[string]*Import // map[path]*Import cache of known imports
-	interf2proxy map[r.Type]r.Type  // interface -> proxy
-	proxy2interf map[r.Type]xr.Type // proxy -> interface
+	KnownImports map[string]*Import   // map[path]*Import cache of known imports
+	interf2proxy map[r.Type]r.Type    // interface -> proxy
+	proxy2interf map[r.Type]xr.Type   // proxy -> interface
+	coverage     *coverage            // created when compiling with OptCoverage
+	tracer       *tracer              // functions and methods that can be traced
+	Literal      LiteralOptions       // options for GoLiteral and the REPL command :dump
+	History      ResultHistory        // results of expressions evaluated at the REPL
+	undo         []undoEntry          // how to revert the declarations of recent REPL inputs, see :undo
+	undoing      *undoEntry           // records the declarations of the REPL input being evaluated
+	renames      *hygieneRenames      // identifiers renamed by hygienic templates during the current macro call
+	syntax       *syntaxRule          // ~defsyntax rule whose template is being compiled
+	constFunc    *constFuncCheck      // const func whose body is being compiled
+	macroLibs    map[string]*macroLib // map[path]*macroLib cache of imported macro libraries
+	top          *Interp              // package "builtin", outer scope of macro libraries
 	Prompt       string
 }
 
@@ -583,7 +626,8 @@ type CompBinds struct {
 	// then switch and allocate them as VarBind instead (they are slower and each one allocates memory)
 	IntBindMax int
 	Types      map[string]xr.Type
-	Name       string // set by "package" directive
+	Sources    map[string]string // source code of top-level declarations, shown by :source and :edit
+	Name       string            // set by "package" directive
 	Path       string
 }
 
@@ -602,6 +646,7 @@ type Comp struct {
 	Labels    map[string]*int
 	Outer     *Comp
 	FuncMaker *funcMaker // used by debugger command 'backtrace' to obtain function name, type and binds for arguments and results
+	hygiene   *hygiene   // != nil when compiling a ~quasiquote template with OptMacroHygiene
 }
 
 // ================================= Env =================================
@@ -0,0 +1,212 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * history.go
+ *
+ *  Created on Oct 19, 2026
+ *      Author agent
+ */
+
+package fast
+
+import (
+	"fmt"
+	r "reflect"
+	"strconv"
+	"strings"
+
+	"github.com/cosmos72/gomacro/base"
+	"github.com/cosmos72/gomacro/base/reflect"
+	xr "github.com/cosmos72/gomacro/xreflect"
+)
+
+// ResultHistory keeps the results of expressions evaluated at the REPL
+// in the variables _1, _2 ... numbered in evaluation order.
+// Results with multiple values are kept in the variables _N_0, _N_1 ...
+// The last result is also available as __, or __0, __1 ... if it has multiple values.
+// Names already declared by the user are never overwritten: the corresponding results are not kept.
+type ResultHistory struct {
+	Size    int // maximum number of results to keep. zero disables the history
+	count   int // number of results recorded so far
+	entries []historyEntry
+	last    []string         // names of the variables holding the last result
+	binds   map[string]*Bind // variables declared by the history
+	warned  map[string]bool  // user-declared names already warned about
+}
+
+// DefaultResultHistorySize is the default maximum number of results kept by ResultHistory
+const DefaultResultHistorySize = 100
+
+type historyEntry struct {
+	comp   *Comp
+	env    *Env
+	names  []string
+	values []r.Value
+	types  []xr.Type
+}
+
+// record the results of an expression evaluated at the REPL
+func (ir *Interp) recordResults(values []r.Value, types []xr.Type) {
+	h := &ir.Comp.History
+	if h.Size <= 0 || len(values) == 0 || (len(values) == 1 && values[0] == reflect.None) {
+		return
+	}
+	h.count++
+	prefix := "_" + strconv.Itoa(h.count)
+	e := historyEntry{comp: ir.Comp, env: ir.env, values: values, types: types}
+	e.names = ir.declResults(prefix, "_", values, types)
+
+	// also declare __ or __0, __1 ... for the last result
+	for _, name := range h.last {
+		h.undecl(ir.Comp, name)
+	}
+	h.last = ir.declResults("__", "", values, types)
+
+	h.entries = append(h.entries, e)
+	for len(h.entries) > h.Size {
+		h.entries[0].forget(h)
+		h.entries = h.entries[1:]
+	}
+}
+
+// declare the variables prefix or prefix+sep+0, prefix+sep+1 ... holding values
+func (ir *Interp) declResults(prefix string, sep string, values []r.Value, types []xr.Type) []string {
+	names := make([]string, 0, len(values))
+	for i, v := range values {
+		name := prefix
+		if len(values) > 1 {
+			name = prefix + sep + strconv.Itoa(i)
+		}
+		var t xr.Type
+		if i < len(types) {
+			t = types[i]
+		}
+		if ir.declResult(name, v, t) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func (ir *Interp) declResult(name string, v r.Value, t xr.Type) (ok bool) {
+	c := ir.Comp
+	h := &c.History
+	if v == reflect.None || (v.IsValid() && !v.CanInterface()) {
+		return false
+	}
+	if bind := c.Binds[name]; bind != nil && bind != h.binds[name] {
+		// declared by the user: do not overwrite it
+		if !h.warned[name] {
+			if h.warned == nil {
+				h.warned = make(map[string]bool)
+			}
+			h.warned[name] = true
+			c.Warnf("not keeping results in %s: name already declared", name)
+		}
+		return false
+	}
+	defer func() {
+		// some values cannot be stored in a variable, for example types
+		if rec := recover(); rec != nil {
+			ok = false
+		}
+	}()
+	delete(c.Binds, name) // avoid warning "redefined identifier"
+	delete(h.binds, name)
+	if !v.IsValid() {
+		if t == nil {
+			return false
+		}
+		ir.DeclVar(name, t, nil)
+	} else if lit, isLit := v.Interface().(UntypedLit); isLit {
+		c.DeclConst0(name, nil, lit)
+	} else {
+		if t == nil {
+			t = c.TypeOf(v.Interface())
+		}
+		ir.DeclVar(name, t, v.Interface())
+	}
+	if h.binds == nil {
+		h.binds = make(map[string]*Bind)
+	}
+	h.binds[name] = c.Binds[name]
+	return true
+}
+
+// remove the variable name if it was declared by the history,
+// and return its bind
+func (h *ResultHistory) undecl(c *Comp, name string) *Bind {
+	bind := c.Binds[name]
+	if bind == nil || bind != h.binds[name] {
+		return nil
+	}
+	delete(c.Binds, name)
+	delete(h.binds, name)
+	return bind
+}
+
+// remove the variables holding an old result, and release their values
+func (e *historyEntry) forget(h *ResultHistory) {
+	for _, name := range e.names {
+		if bind := h.undecl(e.comp, name); bind != nil {
+			releaseBind(e.env, bind)
+		}
+	}
+}
+
+// show the kept results
+func (ir *Interp) cmdHistory(arg string, opt base.CmdOpt) (string, base.CmdOpt) {
+	g := &ir.Comp.Globals
+	h := &ir.Comp.History
+	if len(h.entries) == 0 {
+		if h.Size <= 0 {
+			g.Fprintf(g.Stdout, "// result history is disabled. enable it with %coptions History.Size=N\n", g.ReplCmdChar)
+		} else {
+			g.Fprintf(g.Stdout, "// no results yet\n")
+		}
+		return "", opt
+	}
+	for _, e := range h.entries {
+		for i, name := range e.names {
+			var t interface{}
+			if i < len(e.types) && e.types[i] != nil {
+				t = e.types[i]
+			} else {
+				t = reflect.Type(e.values[i])
+			}
+			g.Fprintf(g.Stdout, "%s = %s\t// %v\n", name, g.Pretty(e.values[i]), t)
+		}
+	}
+	return "", opt
+}
+
+// set the maximum number of kept results, from the setting History.Size=N,
+// and return the remaining words of arg
+func (h *ResultHistory) setSize(g *base.Globals, arg string) string {
+	var rest []string
+	for _, word := range strings.Fields(arg) {
+		if !strings.HasPrefix(word, "History.Size=") {
+			rest = append(rest, word)
+		} else if n, err := strconv.Atoi(word[len("History.Size="):]); err != nil || n < 0 {
+			g.Warnf("invalid value %q for History.Size: expecting a non-negative integer", word[len("History.Size="):])
+		} else {
+			h.Size = n
+			for len(h.entries) > h.Size {
+				h.entries[0].forget(h)
+				h.entries = h.entries[1:]
+			}
+		}
+	}
+	return strings.Join(rest, " ")
+}
+
+func (h *ResultHistory) String() string {
+	return fmt.Sprintf("History.Size=%d", h.Size)
+}
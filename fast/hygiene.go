@@ -0,0 +1,400 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * hygiene.go
+ *
+ *  Created on Oct 19, 2026
+ *      Author agent
+ */
+
+package fast
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	r "reflect"
+	"strings"
+	"unsafe"
+
+	. "github.com/cosmos72/gomacro/base"
+	mt "github.com/cosmos72/gomacro/token"
+)
+
+// Hygienic templates. With OptMacroHygiene, the identifiers that a ~quasiquote template
+// declares - variables, constants, types, functions, parameters and labels - are renamed
+// to fresh names starting with StrGensym each time the macro that evaluates the template is called.
+// Thus they cannot capture or shadow the identifiers written by the macro caller.
+//
+// The other identifiers in the template that name a global of the file where the template is compiled
+// are replaced by an alias of such global, so they refer to it even if the macro caller shadows it
+// or cannot see it, as when the macro is imported from a macro library.
+// The aliases are declared once, when compiling the template, in the outermost scope "builtin":
+// it is the outer scope of every file, thus the aliases are visible wherever the template is expanded.
+//
+// Identifiers inserted with ~unquote and ~unquote_splice are not affected,
+// neither are field names, method names and keys in composite literals.
+
+var rtypeOfIdent = r.TypeOf((*ast.Ident)(nil))
+
+type hygieneKind uint8
+
+const (
+	hygieneBound hygieneKind = iota + 1 // identifier declared by the template: rename it
+	hygieneRef                          // identifier used by the template: may refer to a global
+)
+
+// hygiene contains the identifiers of a ~quasiquote template that must be renamed
+type hygiene struct {
+	idents  map[*ast.Ident]hygieneKind
+	file    *Comp                    // where to look for globals
+	aliases map[string]*hygieneAlias // aliases of the globals used by the template
+}
+
+// hygieneAlias is an alias, declared in the outermost scope, of a global used by a hygienic template
+type hygieneAlias struct {
+	name   string // name of the alias
+	global string // name of the global
+	target *Bind  // bind of the global when the alias was declared. nil if the global is a type
+	bind   *Bind  // bind of the alias. nil if the global is a type
+}
+
+// return how ident must be renamed, or zero if it must not. h can be nil
+func (h *hygiene) kind(ident *ast.Ident) hygieneKind {
+	if h == nil {
+		return 0
+	}
+	return h.idents[ident]
+}
+
+// hygieneRenames contains the identifiers renamed during a macro call
+type hygieneRenames struct {
+	names map[string]string
+}
+
+// compile a ~quasiquote template with the given body, applying OptMacroHygiene if set
+func (c *Comp) quasiquoteHygienic(body ast.Node, compile func() *Expr) *Expr {
+	h := c.newHygiene(body)
+	if h == nil {
+		return compile()
+	}
+	saved := c.hygiene
+	c.hygiene = h
+	defer func() {
+		c.hygiene = saved
+	}()
+	expr := compile()
+	if expr == nil {
+		return nil
+	}
+	fun := expr.AsX1()
+	g := c.CompGlobals
+	return exprX1(expr.Type, func(env *Env) r.Value {
+		if g.renames == nil {
+			// template evaluated outside a macro call:
+			// renamed identifiers are shared only by this evaluation
+			g.renames = &hygieneRenames{}
+			defer func() {
+				g.renames = nil
+			}()
+		}
+		return fun(env)
+	})
+}
+
+// invoke a macro. The identifiers renamed by hygienic templates are shared
+// by all the templates evaluated during the same macro call
+func (g *CompGlobals) callMacro(macro Macro, args []r.Value) []r.Value {
+	saved := g.renames
+	g.renames = &hygieneRenames{}
+	defer func() {
+		g.renames = saved
+	}()
+	return macro.closure(args)
+}
+
+// compile an identifier of a hygienic template
+func (c *Comp) hygienicIdent(ident *ast.Ident, kind hygieneKind) *Expr {
+	g := c.CompGlobals
+	h := c.hygiene
+	name, pos := ident.Name, ident.NamePos
+	return exprX1(c.Universe.FromReflectType(rtypeOfIdent), func(env *Env) r.Value {
+		var newname string
+		if kind == hygieneRef {
+			newname = h.alias(g, env.FileEnv, name)
+		} else {
+			newname = g.hygienicName(name)
+		}
+		return r.ValueOf(&ast.Ident{NamePos: pos, Name: newname})
+	})
+}
+
+// return the name that a hygienic template must use for identifier name, declared by the template
+func (g *CompGlobals) hygienicName(name string) string {
+	h := g.renames
+	if newname, ok := h.names[name]; ok {
+		return newname
+	}
+	newname := fmt.Sprintf("%s%d%s", StrGensym, g.GensymN, name)
+	g.GensymN++
+	if h.names == nil {
+		h.names = make(map[string]string)
+	}
+	h.names[name] = newname
+	return newname
+}
+
+// return the alias of global name, updating the value of its slot from fileEnv,
+// i.e. the *Env of the file where the template was compiled.
+// if name is not a global, return name
+func (h *hygiene) alias(g *CompGlobals, fileEnv *Env, name string) string {
+	a := h.aliases[name]
+	if a == nil {
+		// name was not a global when the template was compiled.
+		// it may have been declared later: check again
+		if a = h.declareAlias(g, name); a == nil {
+			return name
+		}
+	}
+	a.update(g, h.file, fileEnv)
+	return a.name
+}
+
+// if name is a global declared in h.file, declare an alias for it in the outermost scope.
+// return nil if name is not a global
+func (h *hygiene) declareAlias(g *CompGlobals, name string) *hygieneAlias {
+	file := h.file
+	bind := file.Binds[name]
+	t, istype := file.Types[name]
+	if bind == nil && !istype {
+		return nil
+	}
+	a := &hygieneAlias{
+		name:   fmt.Sprintf("%s%s%d", StrGensym, name, g.GensymN),
+		global: name,
+	}
+	g.GensymN++
+	if bind != nil {
+		a.declareBind(g, bind)
+	} else {
+		g.top.Comp.declTypeAlias(a.name, t)
+	}
+	if h.aliases == nil {
+		h.aliases = make(map[string]*hygieneAlias)
+	}
+	h.aliases[name] = a
+	return a
+}
+
+// declare the alias of a global variable, function or constant in the outermost scope
+func (a *hygieneAlias) declareBind(g *CompGlobals, target *Bind) {
+	top := g.top.Comp
+	// the global may have been redefined: remove the previous alias
+	delete(top.Binds, a.name)
+	class := target.Desc.Class()
+	if class == IntBind {
+		// the alias will contain the address of the global's Env.Ints[index]
+		class = VarBind
+	}
+	// use top.CompBinds.NewBind() to prevent optimization VarBind -> IntBind
+	bind := top.CompBinds.NewBind(&top.Output, a.name, class, target.Type)
+	if bind.Desc.Index() == NoIndex {
+		// constants, imported packages, macros...
+		bind.Lit = target.Lit
+	}
+	a.target, a.bind = target, bind
+}
+
+// update the alias: the global may have been redefined, and the value
+// of variables and functions is only available at runtime, in fileEnv
+func (a *hygieneAlias) update(g *CompGlobals, file *Comp, fileEnv *Env) {
+	target := file.Binds[a.global]
+	if target == nil {
+		if t, ok := file.Types[a.global]; ok {
+			g.top.Comp.Types[a.name] = t
+		}
+		return
+	}
+	if target != a.target {
+		a.declareBind(g, target)
+	}
+	idx, tidx := a.bind.Desc.Index(), target.Desc.Index()
+	if idx == NoIndex || fileEnv == nil {
+		return
+	}
+	topEnv := g.top.prepareEnv(16, 0)
+	switch target.Desc.Class() {
+	case IntBind:
+		if tidx < len(fileEnv.Ints) {
+			fileEnv.IntAddressTaken = true
+			topEnv.Vals[idx] = r.NewAt(target.Type.ReflectType(), unsafe.Pointer(&fileEnv.Ints[tidx])).Elem()
+		}
+	default:
+		if tidx < len(fileEnv.Vals) {
+			topEnv.Vals[idx] = fileEnv.Vals[tidx]
+		}
+	}
+}
+
+// return true if name is an alias declared by a hygienic template in c
+func (c *Comp) isHygieneAlias(name string) bool {
+	return c.Outer == nil && strings.HasPrefix(name, StrGensym)
+}
+
+// find the identifiers to rename in a ~quasiquote template.
+// return nil if OptMacroHygiene is not set or there is nothing to rename
+func (c *Comp) newHygiene(body ast.Node) *hygiene {
+	if body == nil || c.Options&OptMacroHygiene == 0 {
+		return nil
+	}
+	w := hygieneWalker{
+		bound: make(map[string]bool),
+		skip:  make(map[*ast.Ident]bool),
+	}
+	w.walk(body, 1)
+	h := &hygiene{
+		idents: make(map[*ast.Ident]hygieneKind),
+		file:   c.FileComp(),
+	}
+	for _, ident := range w.idents {
+		if w.skip[ident] {
+			continue
+		} else if w.bound[ident.Name] {
+			h.idents[ident] = hygieneBound
+		} else {
+			h.idents[ident] = hygieneRef
+			if _, ok := h.aliases[ident.Name]; !ok {
+				h.declareAlias(c.CompGlobals, ident.Name)
+			}
+		}
+	}
+	if len(h.idents) == 0 {
+		return nil
+	}
+	return h
+}
+
+type hygieneWalker struct {
+	idents []*ast.Ident        // identifiers in the template, outside ~unquote
+	bound  map[string]bool     // names declared by the template
+	skip   map[*ast.Ident]bool // field names, method names and composite literal keys
+}
+
+func (w *hygieneWalker) walk(node ast.Node, depth int) {
+	ast.Inspect(node, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.UnaryExpr:
+			inner := depth
+			switch node.Op {
+			case mt.QUASIQUOTE:
+				inner++
+			case mt.UNQUOTE, mt.UNQUOTE_SPLICE:
+				inner--
+			case mt.QUOTE:
+			default:
+				return true
+			}
+			if lit, ok := node.X.(*ast.FuncLit); ok && inner > 0 {
+				w.walk(lit.Body, inner)
+			}
+			return false
+		case *ast.Ident:
+			if depth == 1 && node.Name != "_" {
+				w.idents = append(w.idents, node)
+			}
+		case *ast.AssignStmt:
+			if node.Tok == token.DEFINE {
+				w.bindExprs(node.Lhs...)
+			}
+		case *ast.RangeStmt:
+			if node.Tok == token.DEFINE {
+				w.bindExprs(node.Key, node.Value)
+			}
+		case *ast.ValueSpec:
+			w.bind(node.Names...)
+		case *ast.TypeSpec:
+			w.bind(node.Name)
+		case *ast.FuncDecl:
+			if node.Recv != nil {
+				w.bindFields(node.Recv)
+				w.skip[node.Name] = true // method name
+			} else {
+				w.bind(node.Name)
+			}
+		case *ast.FuncType:
+			w.bindFields(node.Params)
+			w.bindFields(node.Results)
+		case *ast.LabeledStmt:
+			w.bind(node.Label)
+		case *ast.SelectorExpr:
+			w.skip[node.Sel] = true
+		case *ast.StructType:
+			w.skipFields(node.Fields, true)
+		case *ast.InterfaceType:
+			w.skipFields(node.Methods, false)
+		case *ast.CompositeLit:
+			for _, elt := range node.Elts {
+				if kv, ok := elt.(*ast.KeyValueExpr); ok {
+					if key, ok := kv.Key.(*ast.Ident); ok {
+						w.skip[key] = true
+					}
+				}
+			}
+		}
+		return true
+	})
+}
+
+func (w *hygieneWalker) bind(idents ...*ast.Ident) {
+	for _, ident := range idents {
+		if ident != nil {
+			w.bound[ident.Name] = true
+		}
+	}
+}
+
+func (w *hygieneWalker) bindExprs(exprs ...ast.Expr) {
+	for _, expr := range exprs {
+		if ident, ok := expr.(*ast.Ident); ok {
+			w.bind(ident)
+		}
+	}
+}
+
+func (w *hygieneWalker) bindFields(list *ast.FieldList) {
+	if list != nil {
+		for _, field := range list.List {
+			w.bind(field.Names...)
+		}
+	}
+}
+
+// mark field names as not to be renamed.
+// the type of embedded struct fields is also its name: do not rename it either
+func (w *hygieneWalker) skipFields(list *ast.FieldList, embedded bool) {
+	if list == nil {
+		return
+	}
+	for _, field := range list.List {
+		for _, ident := range field.Names {
+			w.skip[ident] = true
+		}
+		if len(field.Names) != 0 || !embedded {
+			continue
+		}
+		typ := field.Type
+		if star, ok := typ.(*ast.StarExpr); ok {
+			typ = star.X
+		}
+		if ident, ok := typ.(*ast.Ident); ok {
+			w.skip[ident] = true
+		}
+	}
+}
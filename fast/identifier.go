@@ -38,9 +38,16 @@ func (c *Comp) TryResolve(name string) *Symbol {
 
 func (c *Comp) tryResolve(name string) (*Symbol, *Comp) {
 	upn := 0
+	// while compiling the body of a const func, check the symbols declared outside it
+	check := c.CompGlobals.constFunc
+	outside := false
 	for ; c != nil; c = c.Outer {
+		outside = outside || (check != nil && c == check.comp)
 		if bind, ok := c.Binds[name]; ok {
 			// c.Debugf("TryResolve: %s is upn=%d %v", name, upn, bind)
+			if outside {
+				check.checkBind(c, name, bind)
+			}
 			return bind.AsSymbol(upn), c
 		}
 		upn += c.UpCost // c.UpCost is zero if *Comp has no local variables/functions so it will NOT have a corresponding *Env at runtime
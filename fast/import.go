@@ -29,6 +29,7 @@ import (
 	"github.com/cosmos72/gomacro/base/paths"
 	"github.com/cosmos72/gomacro/base/reflect"
 	"github.com/cosmos72/gomacro/base/untyped"
+	mt "github.com/cosmos72/gomacro/token"
 	xr "github.com/cosmos72/gomacro/xreflect"
 )
 
@@ -37,6 +38,9 @@ import (
 // remove package 'path' from the list of known packages.
 // later attempts to import it again will trigger a recompile.
 func (cg *CompGlobals) UnloadPackage(path string) {
+	if cg.unloadMacroLib(path) {
+		return
+	}
 	cg.Globals.UnloadPackage(path)
 	delete(cg.KnownImports, path)
 }
@@ -126,6 +130,11 @@ func (c *Comp) Import(node ast.Spec) {
 		if node.Name != nil {
 			name = node.Name.Name
 		}
+		if name == mt.MACRO.String() {
+			// import ~"path" or //gomacro:macros
+			c.ImportMacros(path)
+			return
+		}
 		// yes, we support local imports
 		// i.e. a function or block can import packages
 		c.ImportPackage(name, path)
@@ -203,6 +212,7 @@ func (c *Comp) declDotImport0(imp *Import) {
 		if t, exists := c.Types[name]; exists {
 			c.Warnf("redefined type: %v", t)
 		}
+		c.undoType(name)
 		c.Types[name] = typ
 	}
 
@@ -219,6 +229,7 @@ func (c *Comp) declDotImport0(imp *Import) {
 		if class == IntBind {
 			class = VarBind
 		}
+		c.undoBind(name)
 		cbind := c.CompBinds.NewBind(&c.Output, name, class, bind.Type)
 		cidx := cbind.Desc.Index()
 		switch bind.Desc.Class() {
@@ -308,7 +319,7 @@ func (imp *Import) loadBinds(g *CompGlobals, pkgref *genimport.PackageRef) {
 			copy(tmp, vals)
 			vals = tmp
 		}
-		vals[idx] = val
+		vals[idx] = g.stdio.bind(pkgref.Path, name, val)
 	}
 	imp.Vals = vals
 }
@@ -17,9 +17,13 @@
 package fast
 
 import (
+	"fmt"
+	"go/ast"
 	r "reflect"
 
+	. "github.com/cosmos72/gomacro/ast2"
 	. "github.com/cosmos72/gomacro/base"
+	xr "github.com/cosmos72/gomacro/xreflect"
 )
 
 func (ir *Interp) Inspect(src string) {
@@ -31,7 +35,16 @@ func (ir *Interp) Inspect(src string) {
 		return
 	}
 	// not ir.Compile because it only macroexpands if OptMacroExpandOnly is set
-	val, xtyp := ir.RunExpr1(c.Compile(c.Parse(src)))
+	form := c.Parse(src)
+	var val r.Value
+	var xtyp xr.Type
+	// if possible, inspect an addressable value: the inspector can then modify it
+	if addr := c.inspectAddress(form); addr != nil {
+		val, xtyp = ir.RunExpr1(addr)
+		val, xtyp = val.Elem(), xtyp.Elem()
+	} else {
+		val, xtyp = ir.RunExpr1(c.Compile(form))
+	}
 	var typ r.Type
 	if xtyp != nil {
 		typ = xtyp.ReflectType()
@@ -42,5 +55,37 @@ func (ir *Interp) Inspect(src string) {
 		}
 		typ = val.Type()
 	}
+	if inspector, ok := inspector.(InspectorWithEval); ok {
+		inspector.SetEval(ir.inspectEval)
+	}
 	inspector.Inspect(src, val, typ, xtyp, &ir.Comp.Globals)
 }
+
+// compile the address of form, if it is an addressable expression. otherwise return nil
+func (c *Comp) inspectAddress(form Ast) (addr *Expr) {
+	expr, ok := form.Interface().(ast.Expr)
+	if !ok {
+		return nil
+	}
+	defer func() {
+		if recover() != nil {
+			addr = nil
+		}
+	}()
+	return c.addressOf(expr, nil)
+}
+
+// evaluate an expression for the inspector, converting panics to errors
+func (ir *Interp) inspectEval(src string) (val r.Value, xtyp xr.Type, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			if e, ok := rec.(error); ok {
+				err = e
+			} else {
+				err = fmt.Errorf("%v", rec)
+			}
+		}
+	}()
+	val, xtyp = ir.Eval1(src)
+	return val, xtyp, nil
+}
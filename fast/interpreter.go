@@ -60,6 +60,7 @@ func newTopInterp(path string) *Interp {
 		KnownImports: make(map[string]*Import),
 		interf2proxy: make(map[r.Type]r.Type),
 		proxy2interf: make(map[r.Type]xr.Type),
+		History:      ResultHistory{Size: DefaultResultHistorySize},
 		Prompt:       "gomacro> ",
 	}
 	goid := gls.GoID()
@@ -83,13 +84,14 @@ func newTopInterp(path string) *Interp {
 			Run:   run,
 		},
 	}
+	cg.top = ir
 	// tell xreflect about our packages "fast" and "main"
 	universe.CachePackage(types.NewPackage("fast", "fast"))
 	universe.CachePackage(types.NewPackage("main", "main"))
 
-	// no need to scavenge for Builtin, Function, Macro, *Import, *TemplateFunc, *TemplateType and UntypedLit fields and methods.
+	// no need to scavenge for Builtin, Function, Macro, *ConstFunc, *Import, *TemplateFunc, *TemplateType and UntypedLit fields and methods.
 	// actually, making them opaque helps securing against malicious interpreted code.
-	for _, rtype := range []r.Type{rtypeOfBuiltin, rtypeOfFunction, rtypeOfMacro, rtypeOfPtrImport, rtypeOfPtrTemplateFunc, rtypeOfPtrTemplateType} {
+	for _, rtype := range []r.Type{rtypeOfBuiltin, rtypeOfFunction, rtypeOfMacro, rtypeOfPtrConstFunc, rtypeOfPtrImport, rtypeOfPtrTemplateFunc, rtypeOfPtrTemplateType} {
 		cg.opaqueType(rtype, "fast")
 	}
 	cg.opaqueType(rtypeOfUntypedLit, "untyped")
@@ -288,6 +290,10 @@ func (ir *Interp) EvalReader(src io.Reader) (comments string, err error) {
 		g.Readline = savein
 		g.Options = saveopts
 		if rec := recover(); rec != nil {
+			if stack := ir.env.Run.PanicStack; len(stack) != 0 {
+				err = &PanicError{rec, stack}
+				return
+			}
 			switch rec := rec.(type) {
 			case error:
 				err = rec
@@ -0,0 +1,221 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * json.go
+ *
+ *  Created on Oct 19, 2026
+ *      Author agent
+ */
+
+package fast
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	r "reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/cosmos72/gomacro/base"
+	"github.com/cosmos72/gomacro/base/output"
+	"github.com/cosmos72/gomacro/base/reflect"
+	"github.com/cosmos72/gomacro/scanner"
+	xr "github.com/cosmos72/gomacro/xreflect"
+)
+
+// JSONResult describes the evaluation of one input in JSON mode. See Interp.ReplJSON
+type JSONResult struct {
+	Id       int         `json:"id"`                 // sequence number of the input, starting from 1
+	Values   []JSONValue `json:"values"`             // results
+	Stdout   string      `json:"stdout,omitempty"`   // output written to os.Stdout or to the interpreter's Stdout
+	Stderr   string      `json:"stderr,omitempty"`   // output written to os.Stderr or to the interpreter's Stderr, except warnings
+	Warnings []string    `json:"warnings,omitempty"` // warnings issued by the interpreter
+	Error    *JSONError  `json:"error,omitempty"`    // error or panic, if any
+	Elapsed  float64     `json:"elapsed"`            // evaluation time, in seconds
+}
+
+// JSONValue is a result of evaluating one input in JSON mode
+type JSONValue struct {
+	Value string `json:"value"`          // string form of the value, as shown by the REPL
+	Type  string `json:"type"`           // type of the value
+	Mime  string `json:"mime,omitempty"` // MIME type of data, if the value has a display hook that returns rich output
+	Data  []byte `json:"data,omitempty"` // rich output, encoded as base64 by encoding/json
+}
+
+// JSONError is an error or panic that occurred while evaluating one input in JSON mode
+type JSONError struct {
+	Message string `json:"message"`
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+}
+
+// ReplJSON reads inputs from in, evaluates them and writes to out
+// one JSON object per line for each input: see JSONResult.
+// Output written by evaluated code to os.Stdout and os.Stderr, or to the interpreter's Stdout and Stderr,
+// is captured and included in the JSON objects: ReplJSON redirects them with SetStdio
+// and on return it calls SetStdio(nil, nil, nil), discarding any previous redirection.
+func (ir *Interp) ReplJSON(in *bufio.Reader, out io.Writer) {
+	g := ir.Comp.CompGlobals
+
+	// written only by the goroutines that forward redirected os.Stdout and os.Stderr.
+	// to preserve ordering, the interpreter's Stdout and Stderr are also redirected to them
+	var stdout, stderr bytes.Buffer
+	saveout, saveerr := g.Stdout, g.Stderr
+	ir.SetStdio(nil, &stdout, &stderr)
+	g.Stdout, g.Stderr = g.stdio.file(1), g.stdio.file(2)
+	defer func() {
+		g.Stdout, g.Stderr = saveout, saveerr
+		ir.SetStdio(nil, nil, nil)
+	}()
+
+	rd := MakeBufReadline(in, ioutil.Discard)
+
+	ch := StartSignalHandler(ir.Interrupt)
+	defer StopSignalHandler(ch)
+
+	savetty, saveopts := g.Readline, g.Options
+	g.Readline = rd
+	g.Options &^= OptShowPrompt
+	defer func() {
+		g.Readline = savetty
+		g.Options = (g.Options &^ OptShowPrompt) | (saveopts & OptShowPrompt)
+	}()
+
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+
+	for id := 1; ; {
+		src, firstToken := ir.Read()
+		if firstToken < 0 {
+			if len(src) == 0 {
+				break // EOF or other error
+			}
+			continue // comment-only input
+		}
+		res, callAgain := ir.evalJSON(src, &stdout, &stderr)
+		res.Id = id
+		id++
+		if err := enc.Encode(&res); err != nil {
+			g.Fprintf(saveerr, "// error writing JSON result: %v\n", err)
+			break
+		}
+		if !callAgain {
+			break
+		}
+	}
+}
+
+// evaluate src, capturing its output and any panic into a JSONResult.
+// stdout and stderr must be the destinations of redirected os.Stdout and os.Stderr
+func (ir *Interp) evalJSON(src string, stdout, stderr *bytes.Buffer) (res JSONResult, callAgain bool) {
+	g := ir.Comp.CompGlobals
+
+	callAgain = true
+	t1 := time.Now()
+	defer func() {
+		// fill res even if parseEval panicked.
+		// wait for pending output, including the output of compile-time warnings
+		g.stdio.sync()
+		if rec := recover(); rec != nil {
+			res.Error = makeJSONError(rec)
+		}
+		if res.Values == nil {
+			res.Values = []JSONValue{}
+		}
+		res.Elapsed = time.Since(t1).Seconds()
+		// some warnings are written to stdout
+		var warnings []string
+		res.Stdout, res.Warnings = splitWarnings(stdout.String())
+		res.Stderr, warnings = splitWarnings(stderr.String())
+		res.Warnings = append(res.Warnings, warnings...)
+		stdout.Reset()
+		stderr.Reset()
+		g.IncLine(src)
+	}()
+
+	values, types, callAgain := ir.parseEval(src)
+	res.Values = ir.jsonValues(values, types)
+	ir.recordResults(values, types)
+	return res, callAgain
+}
+
+func (ir *Interp) jsonValues(values []r.Value, types []xr.Type) []JSONValue {
+	g := &ir.Comp.Globals
+	g.Limits.Multiline = g.Options&OptPrintMultiline != 0
+	ret := make([]JSONValue, len(values))
+	for i, v := range values {
+		var xt xr.Type
+		if i < len(types) {
+			xt = types[i]
+		}
+		var t interface{} = xt
+		if xt == nil {
+			t = reflect.Type(v)
+		}
+		jv := &ret[i]
+		jv.Type = g.Sprintf("%v", t)
+		if mime, data, ok := g.Display(v, xt); ok {
+			if output.IsMimeText(mime) {
+				jv.Value = string(data)
+				continue
+			}
+			jv.Mime, jv.Data = mime, data
+		}
+		jv.Value = g.Pretty(v)
+	}
+	return ret
+}
+
+func makeJSONError(rec interface{}) *JSONError {
+	if list, ok := rec.(scanner.ErrorList); ok && len(list) != 0 {
+		pos := list[0].Pos
+		return &JSONError{Message: list[0].Msg, File: pos.Filename, Line: pos.Line, Column: pos.Column}
+	}
+	msg := fmt.Sprint(rec)
+	err := JSONError{Message: msg}
+	// most errors start with their position, as FILE:LINE:COLUMN: MESSAGE
+	if i := strings.Index(msg, ": "); i > 0 {
+		parts := strings.Split(msg[:i], ":")
+		if n := len(parts); n >= 3 {
+			line, err1 := strconv.Atoi(parts[n-2])
+			col, err2 := strconv.Atoi(parts[n-1])
+			if err1 == nil && err2 == nil {
+				err.File = strings.Join(parts[:n-2], ":")
+				err.Line, err.Column = line, col
+				err.Message = msg[i+2:]
+			}
+		}
+	}
+	return &err
+}
+
+// separate warnings from the rest of captured output
+func splitWarnings(out string) (string, []string) {
+	const prefix = "// warning: "
+	if !strings.Contains(out, prefix) {
+		return out, nil
+	}
+	var buf bytes.Buffer
+	var warnings []string
+	for _, line := range strings.SplitAfter(out, "\n") {
+		if strings.HasPrefix(line, prefix) {
+			warnings = append(warnings, strings.TrimSuffix(line[len(prefix):], "\n"))
+		} else {
+			buf.WriteString(line)
+		}
+	}
+	return buf.String(), warnings
+}
@@ -0,0 +1,57 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * labels.go
+ *
+ *  Created on Oct 19, 2026
+ *      Author agent
+ */
+
+package fast
+
+import (
+	"context"
+	"runtime/pprof"
+	"runtime/trace"
+)
+
+// pprof label keys applied when entering interpreted functions, if OptPprofLabels is set.
+// Use them with 'go tool pprof -tagfocus', for example -tagfocus=gomacro.func=main.fib
+const (
+	LabelFunc = "gomacro.func" // qualified function name. closures are named PKGNAME.func@FILE:LINE
+	LabelPos  = "gomacro.pos"  // FILE:LINE of function declaration
+)
+
+// execute funcbody with pprof labels and inside a runtime/trace region
+// describing the interpreted function f
+func (f *traceFunc) label(env *Env, funcbody func(*Env)) {
+	run := env.Run
+	ctx := run.labelCtx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	defer func() {
+		run.labelCtx = ctx
+	}()
+	pprof.Do(ctx, pprof.Labels(LabelFunc, f.Name, LabelPos, f.Pos), func(ctx context.Context) {
+		run.labelCtx = ctx
+		defer trace.StartRegion(ctx, f.Name).End()
+		f.trace(env, funcbody)
+	})
+}
+
+// SetLabelContext sets the context used as parent of pprof labels
+// when interpreted functions are entered and OptPprofLabels is set.
+// A host application that applies its own labels with pprof.Do
+// should pass the context received from pprof.Do, otherwise its labels
+// are removed while executing interpreted functions.
+func (ir *Interp) SetLabelContext(ctx context.Context) {
+	ir.env.Run.labelCtx = ctx
+}
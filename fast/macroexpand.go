@@ -45,10 +45,12 @@ func (c *Comp) MacroExpandNodeCodewalk(in ast.Node) (out ast.Node, anythingExpan
 // and replaces each node with the result of MacroExpand(node).
 // It implements the macroexpansion phase
 func (c *Comp) MacroExpandCodewalk(in Ast) (out Ast, anythingExpanded bool) {
-	return c.macroExpandCodewalk(in, 0)
+	return c.macroExpandCodewalk(in, 0, false)
 }
 
-func (c *Comp) macroExpandCodewalk(in Ast, quasiquoteDepth int) (out Ast, anythingExpanded bool) {
+// if once is true, stop after the first macroexpansion.
+// used to show each step of the macroexpansion, see :macroexpand-all
+func (c *Comp) macroExpandCodewalk(in Ast, quasiquoteDepth int, once bool) (out Ast, anythingExpanded bool) {
 	if in == nil || in.Size() == 0 {
 		return in, false
 	}
@@ -57,7 +59,14 @@ func (c *Comp) macroExpandCodewalk(in Ast, quasiquoteDepth int) (out Ast, anythi
 		if debug {
 			c.Debugf("MacroExpandCodewalk: qq = %d, macroexpanding %v", quasiquoteDepth, in.Interface())
 		}
-		in, anythingExpanded = c.MacroExpand(in)
+		if once {
+			in, anythingExpanded = c.MacroExpand1(in)
+			if anythingExpanded {
+				return in, true
+			}
+		} else {
+			in, anythingExpanded = c.MacroExpand(in)
+		}
 	}
 	if in != nil {
 		in = UnwrapTrivialAst(in)
@@ -87,7 +96,7 @@ func (c *Comp) macroExpandCodewalk(in Ast, quasiquoteDepth int) (out Ast, anythi
 			goto Recurse
 		}
 		inChild := UnwrapTrivialAst(in.Get(0).Get(1))
-		outChild, expanded := c.macroExpandCodewalk(inChild, quasiquoteDepth)
+		outChild, expanded := c.macroExpandCodewalk(inChild, quasiquoteDepth, once)
 		if op == mt.MACRO {
 			return outChild, expanded
 		}
@@ -114,14 +123,19 @@ Recurse:
 		out = outSlice
 	}
 	for i := 0; i < n; i++ {
-		child := UnwrapTrivialAst(in.Get(i))
+		orig := in.Get(i)
+		child := UnwrapTrivialAst(orig)
 		if child != nil {
 			expanded := false
-			if child.Size() != 0 {
-				child, expanded = c.macroExpandCodewalk(child, quasiquoteDepth)
+			if child.Size() != 0 && !(once && anythingExpanded) {
+				child, expanded = c.macroExpandCodewalk(child, quasiquoteDepth, once)
 			}
 			if expanded {
 				anythingExpanded = true
+				child = keepBody(in, orig, child)
+			} else {
+				// keep the original child: rebuilding and unwrapping it loses source positions
+				child = orig
 			}
 		}
 		out.Set(i, child)
@@ -132,6 +146,24 @@ Recurse:
 	return out, anythingExpanded
 }
 
+// if orig is the body of function parent and its macroexpansion child is not a block,
+// wrap child in a block with the same braces as orig: keeps the source position of the braces
+func keepBody(parent Ast, orig Ast, child Ast) Ast {
+	switch parent.(type) {
+	case FuncDecl, FuncLit:
+	default:
+		return child
+	}
+	block, ok := orig.(BlockStmt)
+	if !ok || child == nil {
+		return child
+	}
+	if _, ok := child.(BlockStmt); ok {
+		return child
+	}
+	return BlockStmt{X: &ast.BlockStmt{Lbrace: block.X.Lbrace, List: []ast.Stmt{ToStmt(child)}, Rbrace: block.X.Rbrace}}
+}
+
 // MacroExpandNode repeatedly invokes MacroExpandNode1
 // as long as the node represents a macro call.
 // it returns the resulting node.
@@ -247,7 +279,7 @@ func (c *Comp) MacroExpand1(in Ast) (out Ast, expanded bool) {
 			args[j] = r.ValueOf(ToNode(ins.Get(i + j + 1)))
 		}
 		// invoke the macro
-		results := macro.closure(args)
+		results := c.callMacro(macro, args)
 		if debug {
 			c.Debugf("MacroExpand1: macro expanded to: %v", results)
 		}
@@ -269,7 +301,18 @@ func (c *Comp) MacroExpand1(in Ast) (out Ast, expanded bool) {
 			// do not insert nil nodes... they would wreak havok, convert them to the identifier nil
 			out = Ident{&ast.Ident{Name: "nil"}}
 		}
-		outs = outs.Append(out)
+		out = c.markExpansion(macro, elt, args, out)
+		_, isnode := out.(AstWithNode)
+		_, islist := out.(AstWithSlice)
+		if islist && !isnode {
+			// macro returned a list, as []ast.Stmt or []ast.Decl: splice it.
+			// allows a macro to declare multiple types, functions, variables... in the caller's scope
+			for j, outn := 0, out.Size(); j < outn; j++ {
+				outs = outs.Append(out.Get(j))
+			}
+		} else {
+			outs = outs.Append(out)
+		}
 		i += argn
 		expanded = true
 	}
@@ -0,0 +1,188 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * macrolib.go
+ *
+ *  Created on Oct 19, 2026
+ *      Author agent
+ */
+
+package fast
+
+import (
+	"go/ast"
+	"go/build"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	. "github.com/cosmos72/gomacro/ast2"
+	. "github.com/cosmos72/gomacro/base"
+	"github.com/cosmos72/gomacro/base/paths"
+	bstrings "github.com/cosmos72/gomacro/base/strings"
+)
+
+// Macro libraries. A macro library is a package whose *.gomacro source files are evaluated
+// by the interpreter when the package is imported with one of
+//
+//	import ~"path/to/library"
+//	import ~macro "path/to/library"
+//
+//	//gomacro:macros
+//	import "path/to/library"
+//
+// All the macros declared by the library are then available in the importing scope,
+// and the library itself is available with its package name, as a normal import.
+// Libraries are cached, and evaluated again only if their source files change.
+
+// macroLib is an imported macro library
+type macroLib struct {
+	*Import
+	files map[string]time.Time // source files and their modification time
+}
+
+// ImportMacros imports the macro library 'path'.
+// The macros it contains are declared in c, and the library itself
+// is declared with its package name.
+func (c *Comp) ImportMacros(path string) {
+	lib := c.loadMacroLib(path)
+	c.declImport0(lib.Name, lib.Import)
+	// declare the macros after the library: they win if one of them has the same name
+	for name, bind := range lib.Binds {
+		if macro, ok := bind.Value.(Macro); ok && bind.Desc.Class() == ConstBind {
+			mbind := c.NewBind(name, ConstBind, c.TypeOfMacro())
+			mbind.Value = macro
+		}
+	}
+}
+
+// return the macro library 'path', loading it if not cached or if its source files changed
+func (c *Comp) loadMacroLib(path string) *macroLib {
+	g := c.CompGlobals
+	files := macroLibFiles(g, path)
+	if lib := g.macroLibs[path]; lib != nil && !lib.stale(files) {
+		return lib
+	}
+	// evaluate the library even if preprocessing with OptMacroExpandOnly:
+	// its macros are needed to macroexpand the importing code
+	const todisable = OptMacroExpandOnly | OptCollectDeclarations | OptCollectStatements
+	saveopts := g.Options
+	g.Options &^= todisable
+	defer func() {
+		g.Options = saveopts&todisable | g.Options&^todisable
+	}()
+
+	ir := NewInnerInterp(g.top, "", path)
+	for _, filename := range sortedFileNames(files) {
+		ir.evalMacroFile(filename)
+	}
+	lib := &macroLib{
+		Import: ir.asImport(),
+		files:  files,
+	}
+	if g.macroLibs == nil {
+		g.macroLibs = make(map[string]*macroLib)
+	}
+	g.macroLibs[path] = lib
+	return lib
+}
+
+// remove the macro library 'path' from the cache. return false if it was not cached
+func (g *CompGlobals) unloadMacroLib(path string) bool {
+	path = strings.Trim(path, `"`)
+	if g.macroLibs[path] == nil {
+		return false
+	}
+	delete(g.macroLibs, path)
+	return true
+}
+
+// return true if the source files of lib changed since it was loaded
+func (lib *macroLib) stale(files map[string]time.Time) bool {
+	if len(files) != len(lib.files) {
+		return true
+	}
+	for name, modtime := range files {
+		if t, ok := lib.files[name]; !ok || !t.Equal(modtime) {
+			return true
+		}
+	}
+	return false
+}
+
+// return the *.gomacro source files of macro library 'path' and their modification time
+func macroLibFiles(g *CompGlobals, path string) map[string]time.Time {
+	wd, _ := os.Getwd()
+	pkg, err := build.Import(path, wd, build.FindOnly)
+	if err != nil {
+		g.Errorf("cannot find macro library %q: %v", path, err)
+	}
+	infos, err := ioutil.ReadDir(pkg.Dir)
+	if err != nil {
+		g.Errorf("cannot read macro library %q: %v", path, err)
+	}
+	files := make(map[string]time.Time)
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() || !strings.HasSuffix(name, ".gomacro") || strings.HasSuffix(name, "_test.gomacro") {
+			continue
+		}
+		files[paths.Subdir(pkg.Dir, name)] = info.ModTime()
+	}
+	if len(files) == 0 {
+		g.Errorf("invalid macro library %q: no .gomacro files in %s", path, pkg.Dir)
+	}
+	return files
+}
+
+// parse, macroexpand, compile and execute each declaration and statement in file
+func (ir *Interp) evalMacroFile(filename string) {
+	c := ir.Comp
+	g := c.CompGlobals
+	src, err := ioutil.ReadFile(filename)
+	if err != nil {
+		g.Errorf("cannot read macro library file %s: %v", filename, err)
+	}
+	savepath, saveline := g.Filepath, g.Line
+	g.Filepath, g.Line = filename, 0
+	defer func() {
+		g.Filepath, g.Line = savepath, saveline
+	}()
+	for _, node := range c.ParseBytes(src) {
+		if decl, ok := node.(*ast.GenDecl); ok && decl.Tok == token.PACKAGE {
+			c.macroLibName(decl)
+			continue
+		}
+		form, _ := c.MacroExpandCodewalk(ToAst(node))
+		ir.RunExpr(c.Compile(form))
+	}
+}
+
+// use the package clause of a macro library file as the library name
+func (c *Comp) macroLibName(decl *ast.GenDecl) {
+	if len(decl.Specs) != 1 {
+		return
+	}
+	if spec, ok := decl.Specs[0].(*ast.ValueSpec); ok && len(spec.Values) == 1 {
+		if lit, ok := spec.Values[0].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+			c.Name = paths.FileName(bstrings.MaybeUnescapeString(lit.Value))
+		}
+	}
+}
+
+func sortedFileNames(files map[string]time.Time) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	return sortUnique(names)
+}
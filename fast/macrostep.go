@@ -0,0 +1,205 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * macrostep.go
+ *
+ *  Created on Oct 19, 2026
+ *      Author agent
+ */
+
+package fast
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	. "github.com/cosmos72/gomacro/ast2"
+	"github.com/cosmos72/gomacro/base"
+	bstrings "github.com/cosmos72/gomacro/base/strings"
+)
+
+// number of unchanged lines shown around each change by :macroexpand -diff
+const diffContext = 3
+
+func (ir *Interp) cmdMacroExpand(arg string, opt base.CmdOpt) (string, base.CmdOpt) {
+	ir.showMacroExpand("macroexpand", arg, base.CMacroExpand)
+	return "", opt
+}
+
+func (ir *Interp) cmdMacroExpandAll(arg string, opt base.CmdOpt) (string, base.CmdOpt) {
+	ir.showMacroExpand("macroexpand-all", arg, base.CMacroExpandCodewalk)
+	return "", opt
+}
+
+func (ir *Interp) cmdMacroExpand1(arg string, opt base.CmdOpt) (string, base.CmdOpt) {
+	ir.showMacroExpand("macroexpand1", arg, base.CMacroExpand1)
+	return "", opt
+}
+
+// parse code without macroexpanding it, then show each step of its macroexpansion
+// as Go source code, or as unified diffs between consecutive steps if code starts with -diff
+func (ir *Interp) showMacroExpand(cmd string, arg string, which base.WhichMacroExpand) {
+	c := ir.Comp
+	g := &c.Globals
+	arg = strings.TrimSpace(arg)
+	diff := false
+	if flag, rest := bstrings.Split2(arg, ' '); flag == "-diff" {
+		diff = true
+		arg = strings.TrimSpace(rest)
+	}
+	if len(arg) == 0 {
+		g.Fprintf(g.Stdout, "// %s: missing argument\n", cmd)
+		return
+	}
+	form := anyToAst(c.ParseBytes([]byte(arg)), cmd)
+	text := ir.macroStepText(form)
+	for step := 1; ; step++ {
+		var expanded bool
+		if which == base.CMacroExpandCodewalk {
+			form, expanded = c.macroExpandCodewalk(form, 0, true)
+		} else {
+			form, expanded = c.MacroExpand1(form)
+		}
+		if !expanded {
+			if step == 1 {
+				g.Fprintf(g.Stdout, "// %s: nothing to expand\n", cmd)
+			}
+			return
+		}
+		next := ir.macroStepText(form)
+		if diff {
+			g.Fprintf(g.Stdout, "%s", unifiedDiff(
+				fmt.Sprintf("step %d", step-1), fmt.Sprintf("step %d", step),
+				strings.Split(text, "\n"), strings.Split(next, "\n"), diffContext))
+		} else {
+			g.Fprintf(g.Stdout, "// step %d\n%s\n", step, next)
+		}
+		if which == base.CMacroExpand1 {
+			return
+		}
+		text = next
+	}
+}
+
+// format form as Go source code, one top-level node per line
+func (ir *Interp) macroStepText(form Ast) string {
+	g := &ir.Comp.Globals
+	if form == nil {
+		return ""
+	}
+	if form, ok := form.(AstWithNode); ok {
+		return g.Sprintf("%v", form.Node())
+	}
+	n := form.Size()
+	list := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		if elt := form.Get(i); elt != nil {
+			list = append(list, ir.macroStepText(elt))
+		}
+	}
+	return strings.Join(list, "\n")
+}
+
+// a line of a unified diff
+type diffLine struct {
+	op   byte // ' ' unchanged, '-' removed, '+' added
+	text string
+	a, b int // index of the line in the old and new text
+}
+
+// return the unified diff from lines a to lines b, with n lines of context around each change
+func unifiedDiff(nameA, nameB string, a, b []string, n int) string {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	na, nb := len(a), len(b)
+	lcs := make([][]int, na+1)
+	for i := range lcs {
+		lcs[i] = make([]int, nb+1)
+	}
+	for i := na - 1; i >= 0; i-- {
+		for j := nb - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var lines []diffLine
+	for i, j := 0, 0; i < na || j < nb; {
+		switch {
+		case i < na && j < nb && a[i] == b[j]:
+			lines = append(lines, diffLine{' ', a[i], i, j})
+			i++
+			j++
+		case i < na && (j == nb || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, diffLine{'-', a[i], i, j})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', b[j], i, j})
+			j++
+		}
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "--- %s\n+++ %s\n", nameA, nameB)
+	for start, end := 0, len(lines); start < end; {
+		for start < end && lines[start].op == ' ' {
+			start++
+		}
+		if start == end {
+			break
+		}
+		// merge changes separated by at most 2*n unchanged lines into a single hunk
+		last := start
+		for k := start; k < end && k-last <= 2*n; k++ {
+			if lines[k].op != ' ' {
+				last = k
+			}
+		}
+		lo, hi := start-n, last+n+1
+		if lo < 0 {
+			lo = 0
+		}
+		if hi > end {
+			hi = end
+		}
+		countA, countB := 0, 0
+		for _, line := range lines[lo:hi] {
+			if line.op != '+' {
+				countA++
+			}
+			if line.op != '-' {
+				countB++
+			}
+		}
+		fmt.Fprintf(&buf, "@@ -%s +%s @@\n", diffRange(lines[lo].a, countA), diffRange(lines[lo].b, countB))
+		for _, line := range lines[lo:hi] {
+			buf.WriteByte(line.op)
+			buf.WriteString(line.text)
+			buf.WriteByte('\n')
+		}
+		start = hi
+	}
+	return buf.String()
+}
+
+// format the line range of a unified diff hunk
+func diffRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprint(start + 1)
+	default:
+		return fmt.Sprintf("%d,%d", start+1, count)
+	}
+}
@@ -33,6 +33,10 @@ func (b Builtin) String() string {
 	return fmt.Sprintf("%p", b.Compile)
 }
 
+func (m Macro) String() string {
+	return fmt.Sprintf("macro %s/%d", m.name, m.argNum)
+}
+
 func (imp *Import) String() string {
 	return fmt.Sprintf("{%s %q, %d binds, %d types}", imp.Name, imp.Path, len(imp.Binds), len(imp.Types))
 }
@@ -90,11 +94,11 @@ func (ir *Interp) ShowAsPackage() {
 	if binds := c.Binds; len(binds) > 0 {
 		output.ShowPackageHeader(out, c.Name, c.Path, "binds")
 
-		keys := make([]string, len(binds))
-		i := 0
+		keys := make([]string, 0, len(binds))
 		for k := range binds {
-			keys[i] = k
-			i++
+			if !c.isHygieneAlias(k) {
+				keys = append(keys, k)
+			}
 		}
 		sort.Strings(keys)
 		for _, k := range keys {
@@ -105,7 +109,16 @@ func (ir *Interp) ShowAsPackage() {
 		}
 		fmt.Fprintln(out)
 	}
-	showTypes(out, c.Name, c.Path, c.Types, stringer)
+	types := c.Types
+	if c.Outer == nil {
+		types = make(map[string]xr.Type, len(c.Types))
+		for k, t := range c.Types {
+			if !c.isHygieneAlias(k) {
+				types[k] = t
+			}
+		}
+	}
+	showTypes(out, c.Name, c.Path, types, stringer)
 }
 
 func (ir *Interp) ShowImportedPackage(name string) {
@@ -0,0 +1,232 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * profile.go
+ *
+ *  Created on Oct 19, 2026
+ *      Author agent
+ */
+
+package fast
+
+import (
+	"errors"
+	"go/token"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	. "github.com/cosmos72/gomacro/base"
+	"github.com/cosmos72/gomacro/base/profile"
+)
+
+// ProfilePeriod is the sampling period of the profiler
+const ProfilePeriod = 10 * time.Millisecond
+
+// profiler is a sampling profiler for interpreted code.
+// every ProfilePeriod it sends SigProfile to all goroutines executing interpreted code:
+// each one records its interpreted call stack at the next safe point,
+// i.e. between two statements.
+type profiler struct {
+	lock  sync.Mutex
+	build *profile.Builder // nil if not profiling
+	out   io.Writer
+	file  *os.File      // opened by StartProfileFile, closed by StopProfile
+	stop  chan struct{} // closed by StopProfile. nil if not profiling or already stopping
+	done  chan struct{} // closed by loop() when it returns
+}
+
+// StartProfile starts profiling interpreted code.
+// The profile is written to out in pprof format when StopProfile is called.
+// Function names are known only for functions compiled while OptDebugger is set,
+// the others are shown as "???"
+func (ir *Interp) StartProfile(out io.Writer) error {
+	g := ir.env.Run.IrGlobals
+	p := g.loadProfiler()
+	if p == nil {
+		atomic.CompareAndSwapPointer(g.profilerAddr(), nil, unsafe.Pointer(&profiler{}))
+		p = g.loadProfiler()
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.build != nil {
+		return errors.New("profiling already started")
+	}
+	p.build = profile.NewBuilder(
+		[]profile.ValueType{
+			{Type: "samples", Unit: "count"},
+			{Type: "cpu", Unit: "nanoseconds"},
+		},
+		profile.ValueType{Type: "cpu", Unit: "nanoseconds"},
+		int64(ProfilePeriod))
+	p.out = out
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+	go p.loop(g, p.stop, p.done)
+	return nil
+}
+
+// StartProfileFile starts profiling interpreted code.
+// The profile is written to the file 'filename' when StopProfile is called.
+func (ir *Interp) StartProfileFile(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err = ir.StartProfile(f); err != nil {
+		f.Close()
+		return err
+	}
+	p := ir.env.Run.loadProfiler()
+	p.lock.Lock()
+	p.file = f
+	p.lock.Unlock()
+	return nil
+}
+
+// StopProfile stops profiling interpreted code and writes the profile
+func (ir *Interp) StopProfile() error {
+	p := ir.env.Run.loadProfiler()
+	if p == nil {
+		return errors.New("profiling not started")
+	}
+	// only one of concurrent StopProfile calls finds p.stop != nil
+	p.lock.Lock()
+	stop, done := p.stop, p.done
+	p.stop = nil
+	p.lock.Unlock()
+	if stop == nil {
+		return errors.New("profiling not started")
+	}
+	close(stop)
+	<-done
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	err := p.build.Write(p.out)
+	if p.file != nil {
+		if err1 := p.file.Close(); err == nil {
+			err = err1
+		}
+	}
+	p.build, p.out, p.file, p.done = nil, nil, nil, nil
+	return err
+}
+
+// IsProfiling returns true if profiling was started with StartProfile or StartProfileFile
+func (ir *Interp) IsProfiling() bool {
+	p := ir.env.Run.loadProfiler()
+	return p != nil && p.active()
+}
+
+// g.profiler is accessed atomically: it is read by all goroutines executing interpreted code
+func (g *IrGlobals) profilerAddr() *unsafe.Pointer {
+	return (*unsafe.Pointer)(unsafe.Pointer(&g.profiler))
+}
+
+func (g *IrGlobals) loadProfiler() *profiler {
+	return (*profiler)(atomic.LoadPointer(g.profilerAddr()))
+}
+
+func (p *profiler) active() bool {
+	p.lock.Lock()
+	ret := p.build != nil
+	p.lock.Unlock()
+	return ret
+}
+
+// goroutine that periodically sends SigProfile
+func (p *profiler) loop(g *IrGlobals, stop <-chan struct{}, done chan<- struct{}) {
+	ticker := time.NewTicker(ProfilePeriod)
+	defer ticker.Stop()
+	defer close(done)
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			g.profileTick()
+		}
+	}
+}
+
+// send SigProfile to all goroutines executing interpreted code
+func (g *IrGlobals) profileTick() {
+	g.lock.Lock()
+	for _, run := range g.gls {
+		// do not overwrite other signals, as SigInterrupt
+		run.Signals.CompareAndSwapAsync(SigNone, SigProfile)
+	}
+	g.lock.Unlock()
+}
+
+// record a sample. invoked by applyAsyncSignal(SigProfile)
+func (run *Run) profileSample() {
+	p := run.loadProfiler()
+	if p == nil {
+		return
+	}
+	stack := run.profileStack()
+	p.lock.Lock()
+	if p.build != nil {
+		p.build.Add(stack, 1, int64(ProfilePeriod))
+	}
+	p.lock.Unlock()
+}
+
+// return the interpreted call stack of run, most recent call first
+func (run *Run) profileStack() []profile.Frame {
+	var stack []profile.Frame
+	top := walkStack(run.CurrEnv, func(at *Env, fun *Env) {
+		stack = append(stack, run.profileFrame(fun.funcName(), at.stmtPos()))
+	})
+	// top-level code has no function body: use a fake one.
+	// the position of its statement is accurate only if no function is executing
+	pos := token.NoPos
+	if len(stack) == 0 && top != nil {
+		pos = top.stmtPos()
+	}
+	return append(stack, run.profileFrame("(top-level)", pos))
+}
+
+func (run *Run) profileFrame(name string, pos token.Pos) profile.Frame {
+	frame := profile.Frame{Func: name}
+	if pos != token.NoPos && run.Fileset != nil {
+		position := run.Fileset.Position(pos)
+		frame.File = position.Filename
+		frame.Line = position.Line
+	}
+	return frame
+}
+
+// return the qualified name of function body env, as "PKGNAME.NAME":
+// the same name shown by :trace and used in pprof labels.
+// to obtain it, the function must be compiled with OptDebugger
+func (env *Env) funcName() string {
+	c := env.DebugComp
+	if c == nil || c.FuncMaker == nil {
+		return "???"
+	}
+	if name := c.FuncMaker.qualName; len(name) != 0 {
+		return name
+	}
+	// macros are not wrapped by traceFuncBody
+	name := c.FuncMaker.Name
+	if len(name) == 0 {
+		name = "func"
+	}
+	if pkg := c.FileComp().Name; len(pkg) != 0 {
+		name = pkg + "." + name
+	}
+	return name
+}
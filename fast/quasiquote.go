@@ -64,13 +64,17 @@ func (c *Comp) quasiquoteUnary(unary *ast.UnaryExpr) *Expr {
 	return c.quasiquote1(ToAst(node), 1, true)
 }
 
-// Quasiquote expands and compiles ~quasiquote, if Ast starts with it
+// Quasiquote expands and compiles ~quasiquote, if Ast starts with it.
+// With OptMacroHygiene, the identifiers declared by the template are renamed
+// each time a macro evaluates it: see hygiene.go for details
 func (c *Comp) Quasiquote(in Ast) *Expr {
 	switch form := in.(type) {
 	case UnaryExpr:
 		if form.Op() == mt.QUASIQUOTE {
 			body := form.X.X.(*ast.FuncLit).Body
-			return c.quasiquote1(ToAst(body), 1, true)
+			return c.quasiquoteHygienic(body, func() *Expr {
+				return c.quasiquote1(ToAst(body), 1, true)
+			})
 		}
 	}
 	return c.Compile(in)
@@ -236,6 +240,13 @@ func (c *Comp) quasiquote(in Ast, depth int, canSplice bool) (*Expr, bool) {
 				return r.ValueOf(ret)
 			}), false
 		}
+	case Ident:
+		if expr, splice, ok := c.syntaxVarExpr(in.X, depth, canSplice); ok {
+			return expr, splice
+		}
+		if kind := c.hygiene.kind(in.X); kind != 0 {
+			return c.hygienicIdent(in.X, kind), false
+		}
 	}
 
 	// Ast can still be a tree: just not a resizeable one, so support ~unquote but not ~unquote_splice
@@ -32,6 +32,8 @@ import (
 	"github.com/cosmos72/gomacro/base/paths"
 	"github.com/cosmos72/gomacro/base/reflect"
 	bstrings "github.com/cosmos72/gomacro/base/strings"
+	mp "github.com/cosmos72/gomacro/parser"
+	mt "github.com/cosmos72/gomacro/token"
 	xr "github.com/cosmos72/gomacro/xreflect"
 )
 
@@ -44,11 +46,15 @@ func (ir *Interp) Read() (string, int) {
 	if g.Options&OptShowPrompt != 0 {
 		opts |= ReadOptShowPrompt
 	}
+	if g.ParserMode&mp.ParseComments != 0 {
+		// keep comments together with the following declaration
+		opts |= ReadOptCollectAllComments
+	}
 	src, firstToken := g.ReadMultiline(opts, ir.Comp.Prompt)
 	if firstToken < 0 {
+		// comment-only input is not evaluated: count its lines here.
+		// comments before the first token are counted when evaluating src
 		g.IncLine(src)
-	} else if firstToken > 0 {
-		g.IncLine(src[0:firstToken])
 	}
 	return src, firstToken
 }
@@ -87,6 +93,9 @@ func (ir *Interp) CompileAst(form ast2.Ast) *Expr {
 	g := c.CompGlobals
 
 	if g.Options&OptMacroExpandOnly != 0 {
+		// macro declarations and macro imports are needed
+		// to macroexpand the following code: evaluate them anyway
+		ir.evalMacroDecls(form)
 		x := form.Interface()
 		return c.exprValue(c.TypeOf(x), x)
 	}
@@ -103,6 +112,45 @@ func (ir *Interp) CompileAst(form ast2.Ast) *Expr {
 	return expr
 }
 
+// compile and execute the macro declarations and macro imports contained in form
+func (ir *Interp) evalMacroDecls(form ast2.Ast) {
+	switch form := form.(type) {
+	case ast2.AstWithNode:
+		var decl ast.Node
+		switch node := form.Node().(type) {
+		case *ast.FuncDecl:
+			if node.Recv != nil && len(node.Recv.List) == 0 {
+				decl = node
+			}
+		case *ast.GenDecl:
+			if node.Tok == token.IMPORT {
+				decl = macroImports(node)
+			}
+		}
+		if decl != nil {
+			ir.RunExpr(ir.Comp.Compile(ast2.ToAst(decl)))
+		}
+	case ast2.AstWithSlice:
+		for i, n := 0, form.Size(); i < n; i++ {
+			ir.evalMacroDecls(form.Get(i))
+		}
+	}
+}
+
+// return an import declaration containing only the macro imports of decl, or nil if there are none
+func macroImports(decl *ast.GenDecl) ast.Node {
+	var specs []ast.Spec
+	for _, spec := range decl.Specs {
+		if spec, ok := spec.(*ast.ImportSpec); ok && spec.Name != nil && spec.Name.Name == mt.MACRO.String() {
+			specs = append(specs, spec)
+		}
+	}
+	if len(specs) == 0 {
+		return nil
+	}
+	return &ast.GenDecl{TokPos: decl.TokPos, Tok: token.IMPORT, Specs: specs}
+}
+
 // run without debugging. to execute with single-step debugging, use Interp.DebugExpr() instead
 func (ir *Interp) RunExpr1(e *Expr) (r.Value, xr.Type) {
 	if e == nil {
@@ -126,8 +174,15 @@ func (ir *Interp) RunExpr(e *Expr) ([]r.Value, []xr.Type) {
 	}
 	run := env.Run
 	run.applyDebugOp(DebugOpContinue)
+	if run.stdio != nil {
+		defer run.stdio.sync()
+	}
 
 	defer run.setCurrEnv(run.setCurrEnv(env))
+	if run.ExecFlags.IsCatchPanic() {
+		// catch panics in expressions evaluated without executing any statement
+		defer run.catchPanic()
+	}
 
 	fun := e.AsXV(COptKeepUntyped)
 	v, vs := fun(env)
@@ -157,7 +212,13 @@ func (ir *Interp) DebugExpr(e *Expr) ([]r.Value, []xr.Type) {
 	}
 	run := env.Run
 	run.applyDebugOp(DebugOpStep)
+	if run.stdio != nil {
+		defer run.stdio.sync()
+	}
 	defer run.setCurrEnv(run.setCurrEnv(env))
+	if run.ExecFlags.IsCatchPanic() {
+		defer run.catchPanic()
+	}
 
 	fun := e.AsXV(COptKeepUntyped)
 	v, vs := fun(env)
@@ -243,6 +304,9 @@ func (ir *Interp) prepareEnv(minValDelta int, minIntDelta int) *Env {
 	// in case we received a SigInterrupt in the meantime
 	g.Signals.Sync = SigNone
 	g.Signals.Async = SigNone
+	g.UpdateCatchPanic()
+	g.PanicStack = nil
+	g.caughtPanic = false
 	if g.Options&OptDebugger != 0 {
 		// for debugger
 		env.DebugComp = c
@@ -323,12 +387,23 @@ func (ir *Interp) ParseEvalPrint(src string) (callAgain bool) {
 	t1, trap, duration := ir.beforeEval()
 	defer ir.afterEval(src, &callAgain, &trap, t1, duration)
 
+	values, types, callAgain := ir.parseEval(src)
+
+	// print phase
+	ir.Comp.Globals.Print(values, types)
+	ir.recordResults(values, types)
+
+	trap = false // no panic happened
+	return callAgain
+}
+
+// execute REPL commands, then parse, macroexpand, compile and run the remaining source code
+func (ir *Interp) parseEval(src string) (values []r.Value, types []xr.Type, callAgain bool) {
 	src, opt := ir.Cmd(src)
 
 	callAgain = opt&CmdOptQuit == 0
 	if len(src) == 0 || !callAgain {
-		trap = false // no panic happened
-		return callAgain
+		return nil, nil, callAgain
 	}
 
 	g := &ir.Comp.Globals
@@ -340,6 +415,10 @@ func (ir *Interp) ParseEvalPrint(src string) (callAgain bool) {
 
 	ir.env.Run.CmdOpt = opt // store options where Interp.Interrupt() can find them
 
+	// remember how to revert the declarations made by src, even if it fails midway
+	ir.beginUndo()
+	defer ir.endUndo()
+
 	// parse + macroexpansion
 	form := ir.Parse(src)
 
@@ -347,13 +426,8 @@ func (ir *Interp) ParseEvalPrint(src string) (callAgain bool) {
 	expr := ir.CompileAst(form)
 
 	// run expression
-	values, types := ir.RunExpr(expr)
-
-	// print phase
-	g.Print(values, types)
-
-	trap = false // no panic happened
-	return callAgain
+	values, types = ir.RunExpr(expr)
+	return values, types, callAgain
 }
 
 func (ir *Interp) beforeEval() (t1 time.Time, trap bool, duration bool) {
@@ -372,7 +446,11 @@ func (ir *Interp) afterEval(src string, callAgain *bool, trap *bool, t1 time.Tim
 	if *trap {
 		rec := recover()
 		if g.Options&OptPanicStackTrace != 0 {
-			g.Fprintf(g.Stderr, "%v\n%s", rec, debug.Stack())
+			if stack := ir.env.Run.PanicStack; len(stack) != 0 {
+				g.Fprintf(g.Stderr, "%v\n%s", rec, stack)
+			} else {
+				g.Fprintf(g.Stderr, "%v\n%s", rec, debug.Stack())
+			}
 		} else {
 			g.Fprintf(g.Stderr, "%v\n", rec)
 		}
@@ -36,6 +36,9 @@ func (c *Comp) SelectorExpr(node *ast.SelectorExpr) *Expr {
 	if t.Kind() == r.Ptr && t.ReflectType() == rtypeOfPtrImport && e.Const() {
 		// access symbol from imported package, for example fmt.Printf
 		imp := e.Value.(*Import)
+		if check := c.CompGlobals.constFunc; check != nil {
+			check.checkImport(c, imp)
+		}
 		return imp.selector(name, &c.Stringer)
 	}
 	if t.Kind() == r.Ptr && t.Elem().Kind() == r.Struct {
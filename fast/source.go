@@ -0,0 +1,194 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * source.go
+ *
+ *  Created on Oct 19, 2026
+ *      Author agent
+ */
+
+package fast
+
+import (
+	"go/ast"
+	"go/token"
+	"io/ioutil"
+	"os"
+	osexec "os/exec"
+	"sort"
+	"strings"
+
+	"github.com/cosmos72/gomacro/base"
+	"github.com/cosmos72/gomacro/base/dep"
+)
+
+// record the source code of a top-level declaration, for the REPL commands :source and :edit
+func (c *Comp) recordSource(kind dep.Kind, name string, node ast.Node) {
+	if node == nil || c.FileComp() != c {
+		return
+	}
+	var names []string
+	var text string
+	switch kind {
+	case dep.Func, dep.Macro, dep.Method:
+		if node, ok := node.(*ast.FuncDecl); ok {
+			names, text = []string{name}, c.sourceText("", node)
+		}
+	case dep.Type:
+		if node, ok := node.(*ast.TypeSpec); ok {
+			names, text = []string{name}, c.sourceText("type ", node)
+		}
+	case dep.Const, dep.Var, dep.VarMulti:
+		keyword := "var "
+		if kind == dep.Const {
+			keyword = "const "
+		}
+		spec, ok := node.(*ast.ValueSpec)
+		if decl, isDecl := node.(*ast.GenDecl); isDecl && len(decl.Specs) == 1 {
+			spec, ok = decl.Specs[0].(*ast.ValueSpec)
+			keyword = ""
+		}
+		if ok {
+			for _, ident := range spec.Names {
+				if ident.Name != "_" {
+					names = append(names, ident.Name)
+				}
+			}
+			text = c.sourceText(keyword, node)
+		}
+	default:
+		// also record variables declared at top level with NAME := EXPR
+		if node, ok := node.(*ast.AssignStmt); ok && node.Tok == token.DEFINE {
+			for _, lhs := range node.Lhs {
+				if ident, ok := lhs.(*ast.Ident); ok && ident.Name != "_" {
+					names = append(names, ident.Name)
+				}
+			}
+			text = c.sourceText("", node)
+		}
+	}
+	if len(names) == 0 || len(text) == 0 {
+		return
+	}
+	if c.Sources == nil {
+		c.Sources = make(map[string]string)
+	}
+	for _, name := range names {
+		c.undoSource(name)
+		c.Sources[name] = text
+	}
+}
+
+// return the source code of node, prefixed by keyword.
+// uses the original text if available, otherwise formats node
+func (c *Comp) sourceText(keyword string, node ast.Node) string {
+	if text, ok := c.Fileset.SourceRange(node.Pos(), node.End()); ok {
+		return keyword + text
+	}
+	var out ast.Node = node
+	switch node := node.(type) {
+	case *ast.TypeSpec:
+		out = &ast.GenDecl{Tok: token.TYPE, Specs: []ast.Spec{node}}
+	case *ast.ValueSpec:
+		tok := token.VAR
+		if keyword == "const " {
+			tok = token.CONST
+		}
+		out = &ast.GenDecl{Tok: tok, Specs: []ast.Spec{node}}
+	}
+	return c.Sprintf("%v", out)
+}
+
+// show the source code of a top-level declaration
+func (ir *Interp) cmdSource(arg string, opt base.CmdOpt) (string, base.CmdOpt) {
+	c := ir.Comp
+	g := &c.Globals
+	name := strings.TrimSpace(arg)
+	if len(name) == 0 {
+		names := make([]string, 0, len(c.Sources))
+		for name := range c.Sources {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		g.Fprintf(g.Stdout, "// declarations with known source: %s\n", strings.Join(names, " "))
+		return "", opt
+	}
+	if text, ok := ir.source(name); ok {
+		g.Fprintf(g.Stdout, "%s\n", text)
+	}
+	return "", opt
+}
+
+// edit the source code of a top-level declaration with $EDITOR, then evaluate it
+func (ir *Interp) cmdEdit(arg string, opt base.CmdOpt) (string, base.CmdOpt) {
+	g := &ir.Comp.Globals
+	name := strings.TrimSpace(arg)
+	text, ok := ir.source(name)
+	if !ok {
+		return "", opt
+	}
+	edited, err := editText(text)
+	if err != nil {
+		g.Fprintf(g.Stdout, "// cannot edit %s: %v\n", name, err)
+		return "", opt
+	} else if strings.TrimSpace(edited) == strings.TrimSpace(text) {
+		g.Fprintf(g.Stdout, "// %s unchanged\n", name)
+		return "", opt
+	}
+	// evaluate the edited source
+	return edited, opt
+}
+
+// return the source code of a top-level declaration. print an error if not available
+func (ir *Interp) source(name string) (string, bool) {
+	c := ir.Comp
+	g := &c.Globals
+	if len(name) == 0 {
+		g.Fprintf(g.Stdout, "// missing NAME. usage: %csource NAME or %cedit NAME\n", g.ReplCmdChar, g.ReplCmdChar)
+		return "", false
+	}
+	text, ok := c.Sources[name]
+	if !ok {
+		if c.TryResolve(name) != nil || c.TryResolveType(name) != nil {
+			g.Fprintf(g.Stdout, "// source of %s is not available: it was not declared at the REPL\n", name)
+		} else {
+			g.Fprintf(g.Stdout, "// not found: %s\n", name)
+		}
+	}
+	return text, ok
+}
+
+// open text in $EDITOR and return the edited text
+func editText(text string) (string, error) {
+	file, err := ioutil.TempFile("", "gomacro-*.go")
+	if err != nil {
+		return "", err
+	}
+	filename := file.Name()
+	defer os.Remove(filename)
+	_, err = file.WriteString(text + "\n")
+	if err1 := file.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		return "", err
+	}
+	editor := strings.Fields(os.Getenv("EDITOR"))
+	if len(editor) == 0 {
+		editor = []string{"vi"}
+	}
+	cmd := osexec.Command(editor[0], append(editor[1:], filename)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err = cmd.Run(); err != nil {
+		return "", err
+	}
+	edited, err := ioutil.ReadFile(filename)
+	return string(edited), err
+}
@@ -0,0 +1,166 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * stacktrace.go
+ *
+ *  Created on Oct 19, 2026
+ *      Author agent
+ */
+
+package fast
+
+import (
+	"bytes"
+	"fmt"
+	"go/token"
+	r "reflect"
+
+	mt "github.com/cosmos72/gomacro/token"
+)
+
+// StackFrame describes an interpreted function call
+type StackFrame struct {
+	Name   string         // function name. empty for closures
+	Pos    token.Position // position of the statement being executed
+	Macro  string         // macro expansion that generated the statement being executed, if any
+	Params []*Bind        // nil if unknown, i.e. if OptDebugger was not set when compiling the function
+	Args   []r.Value      // values of Params
+	Env    *Env           // function body
+}
+
+// StackTrace is an interpreted call stack. innermost call is first
+type StackTrace []StackFrame
+
+// PanicError wraps a panic raised by interpreted code,
+// adding the interpreted stack trace at the point of panic
+type PanicError struct {
+	Value interface{}
+	Stack StackTrace
+}
+
+func (err *PanicError) Error() string {
+	if e, ok := err.Value.(error); ok {
+		return e.Error()
+	}
+	return fmt.Sprint(err.Value)
+}
+
+// StackTrace returns the interpreted call stack of env.
+// to obtain function names and arguments, functions must be compiled with OptDebugger
+func (env *Env) StackTrace() StackTrace {
+	var fset *mt.FileSet
+	if env != nil && env.Run != nil {
+		fset = env.Run.Fileset
+	}
+	var trace StackTrace
+	walkStack(env, func(at *Env, fun *Env) {
+		trace = append(trace, makeStackFrame(fset, at, fun))
+	})
+	return trace
+}
+
+// walkStack calls visit(at, fun) for each function body 'fun' in the call stack of env,
+// most recent call first. 'at' is the *Env containing the statement being executed by 'fun'.
+// returns the *Env containing the statement being executed by top-level code
+func walkStack(env *Env, visit func(at *Env, fun *Env)) *Env {
+	at := env
+	for env != nil {
+		if env.Caller != nil {
+			// function body
+			visit(at, env)
+			env = env.Caller
+			at = env
+		} else {
+			// nested env
+			env = env.Outer
+		}
+	}
+	return at
+}
+
+// return the position of the statement being executed in env
+func (env *Env) stmtPos() token.Pos {
+	if ip := env.IP; ip >= 0 && ip < len(env.DebugPos) {
+		return env.DebugPos[ip]
+	}
+	return token.NoPos
+}
+
+// create the StackFrame of function body 'fun', currently executing a statement in 'at'
+func makeStackFrame(fset *mt.FileSet, at *Env, fun *Env) StackFrame {
+	frame := StackFrame{Env: fun}
+	if pos := at.stmtPos(); fset != nil && pos != token.NoPos {
+		frame.Pos = fset.Position(pos)
+		frame.Macro = fset.ExpansionString(pos)
+	}
+	if fun.DebugComp == nil || fun.DebugComp.FuncMaker == nil {
+		return frame
+	}
+	m := fun.DebugComp.FuncMaker
+	frame.Name = m.Name
+	frame.Params = m.Param
+	frame.Args = make([]r.Value, len(m.Param))
+	for i, bind := range m.Param {
+		if bind.Desc.Index() != NoIndex {
+			frame.Args[i] = bind.RuntimeValue(fun)
+		}
+	}
+	return frame
+}
+
+func (frame *StackFrame) String() string {
+	var buf bytes.Buffer
+	frame.write(&buf)
+	return buf.String()
+}
+
+func (frame *StackFrame) write(buf *bytes.Buffer) {
+	if frame.Params == nil {
+		buf.WriteString("func ???\n")
+	} else {
+		name := frame.Name
+		if len(name) == 0 {
+			name = "func"
+		}
+		buf.WriteString(name)
+		buf.WriteByte('(')
+		for i, bind := range frame.Params {
+			if i != 0 {
+				buf.WriteString(", ")
+			}
+			var value interface{} = "_"
+			if v := frame.Args[i]; v.IsValid() && v.CanInterface() {
+				value = v.Interface()
+			}
+			if len(bind.Name) != 0 {
+				fmt.Fprintf(buf, "%s=%v <%v>", bind.Name, value, bind.Type)
+			} else {
+				fmt.Fprintf(buf, "%v <%v>", value, bind.Type)
+			}
+		}
+		buf.WriteString(")\n")
+	}
+	if frame.Pos.IsValid() && len(frame.Macro) != 0 {
+		fmt.Fprintf(buf, "\t%s (%s)\n", frame.Pos, frame.Macro)
+	} else if frame.Pos.IsValid() {
+		fmt.Fprintf(buf, "\t%s\n", frame.Pos)
+	} else {
+		buf.WriteString("\t???\n")
+	}
+}
+
+func (trace StackTrace) String() string {
+	var buf bytes.Buffer
+	buf.WriteString("// interpreted stack trace, most recent call first:\n")
+	for i := range trace {
+		trace[i].write(&buf)
+	}
+	return buf.String()
+}
@@ -49,6 +49,9 @@ func (c *Comp) Stmt(in ast.Stmt) {
 				c.append(c.breakpoint())
 				break
 			}
+			if c.Options&OptCoverage != 0 {
+				c.coverStmt(in)
+			}
 		}
 		switch node := in.(type) {
 		case nil:
@@ -268,6 +271,7 @@ func (c *Comp) Goto(node *ast.BranchStmt) {
 	// do not cross function boundaries
 	for o := c; o != nil && o.Func == nil; o = o.Outer {
 		if ip := o.Labels[label]; ip != nil {
+			c.appendConstFuncStep(node.Pos())
 			// only keep a reference to the jump target, NOT TO THE WHOLE *Comp!
 			c.jumpOut(upn, ip)
 			return
@@ -384,6 +388,7 @@ func (c *Comp) For(node *ast.ForStmt, labels []string) {
 
 	// compile the condition, if not a constant
 	jump.Cond = c.Code.Len()
+	c.appendConstFuncStep(node.Pos())
 	if fun != nil {
 		stmt := func(env *Env) (Stmt, *Env) {
 			var ip int
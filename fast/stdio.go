@@ -0,0 +1,400 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * stdio.go
+ *
+ *  Created on Oct 19, 2026
+ *      Author agent
+ */
+
+package fast
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	r "reflect"
+	"sync"
+
+	"github.com/cosmos72/gomacro/imports"
+)
+
+// stdio contains the standard streams seen by interpreted code. See Interp.SetStdio
+type stdio struct {
+	lock   sync.Mutex
+	files  [3]*os.File // os.Stdin, os.Stdout and os.Stderr as seen by interpreted code
+	pipes  [3]*stdioPipe
+	stdin  *stdinPump // feeds files[0] if os.Stdin is redirected to an io.Reader that is not an *os.File
+	logger *log.Logger
+	active bool // true if some stream is redirected
+}
+
+// SetStdio redirects the standard streams seen by interpreted code:
+// os.Stdin, os.Stdout, os.Stderr, the functions fmt.Print*, fmt.Scan* and log.*
+// and the builtins print and println. The host process streams are not affected.
+// A nil argument means the corresponding stream of the host process.
+//
+// Redirection applies to code compiled after the call:
+// code compiled earlier that uses the packages "os", "fmt" or "log" keeps using the host process streams.
+// What interpreted code writes is delivered to stdout and stderr from a separate goroutine,
+// and it is guaranteed to be delivered when each evaluation returns.
+// SetStdio(nil, nil, nil) stops such goroutines and releases the pipes they use.
+func (ir *Interp) SetStdio(stdin io.Reader, stdout io.Writer, stderr io.Writer) {
+	g := ir.Comp.CompGlobals
+	s := g.stdio
+	if s == nil {
+		if stdin == nil && stdout == nil && stderr == nil {
+			return
+		}
+		s = &stdio{files: [3]*os.File{os.Stdin, os.Stdout, os.Stderr}}
+		g.stdio = s
+	}
+	s.sync()
+	s.lock.Lock()
+	s.setInput(stdin)
+	s.setOutput(1, stdout)
+	s.setOutput(2, stderr)
+	s.active = stdin != nil || stdout != nil || stderr != nil
+	s.lock.Unlock()
+
+	// update the bindings of already imported packages
+	for _, path := range []string{"fmt", "log", "os"} {
+		if imp := g.KnownImports[path]; imp != nil {
+			g.rebindStdio(imp, path)
+		}
+	}
+}
+
+func (s *stdio) setInput(in io.Reader) {
+	if s.stdin != nil {
+		s.stdin.close()
+		s.stdin = nil
+	}
+	switch in := in.(type) {
+	case nil:
+		s.files[0] = os.Stdin
+		return
+	case *os.File:
+		s.files[0] = in
+		return
+	}
+	s.stdin = newStdinPump(in)
+	s.files[0] = s.stdin.rd
+}
+
+func (s *stdio) setOutput(fd int, out io.Writer) {
+	p := s.pipes[fd]
+	switch out := out.(type) {
+	case nil:
+		if fd == 1 {
+			s.files[fd] = os.Stdout
+		} else {
+			s.files[fd] = os.Stderr
+		}
+		s.releasePipe(fd)
+		return
+	case *os.File:
+		s.files[fd] = out
+		s.releasePipe(fd)
+		return
+	}
+	if p != nil && p.closed() {
+		// interpreted code closed its os.Stdout or os.Stderr
+		s.releasePipe(fd)
+		p = nil
+	}
+	if p == nil {
+		p = newStdioPipe()
+		s.pipes[fd] = p
+	}
+	p.setDst(out)
+	s.files[fd] = p.w
+}
+
+// wait until what interpreted code wrote to redirected os.Stdout and os.Stderr is delivered.
+// only pipes currently seen by interpreted code can have pending output:
+// SetStdio syncs them before replacing them
+func (s *stdio) sync() {
+	if s == nil {
+		return
+	}
+	var pending [3]*stdioPipe
+	s.lock.Lock()
+	for fd, p := range s.pipes {
+		if p != nil && s.files[fd] == p.w {
+			pending[fd] = p
+		}
+	}
+	s.lock.Unlock()
+	for _, p := range pending {
+		if p != nil {
+			p.sync()
+		}
+	}
+}
+
+// close the pipe used to redirect output fd, if any
+func (s *stdio) releasePipe(fd int) {
+	if p := s.pipes[fd]; p != nil {
+		p.close()
+		s.pipes[fd] = nil
+	}
+}
+
+func (s *stdio) file(fd int) *os.File {
+	s.lock.Lock()
+	f := s.files[fd]
+	s.lock.Unlock()
+	return f
+}
+
+// stdioWriter writes to os.Stdout or os.Stderr as seen by interpreted code
+type stdioWriter struct {
+	s  *stdio
+	fd int
+}
+
+func (w stdioWriter) Write(data []byte) (int, error) {
+	return w.s.file(w.fd).Write(data)
+}
+
+// stdioReader reads from os.Stdin as seen by interpreted code
+type stdioReader struct {
+	s *stdio
+}
+
+func (rd stdioReader) Read(data []byte) (int, error) {
+	return rd.s.file(0).Read(data)
+}
+
+// replace the bindings of imp that refer to the standard streams
+func (g *CompGlobals) rebindStdio(imp *Import, path string) {
+	pkg := imports.Packages[path]
+	for name, bind := range imp.Binds {
+		idx := bind.Desc.Index()
+		if idx == NoIndex || idx >= len(imp.Vals) {
+			continue
+		}
+		if val, ok := pkg.Binds[name]; ok {
+			imp.Vals[idx] = g.stdio.bind(path, name, val)
+		}
+	}
+}
+
+// return the value that interpreted code should see for the symbol path.name, whose original value is val
+func (s *stdio) bind(path string, name string, val r.Value) r.Value {
+	if s == nil || !s.active {
+		return val
+	}
+	var ret interface{}
+	switch path {
+	case "fmt":
+		ret = s.bindFmt(name)
+	case "log":
+		if s.logger == nil {
+			s.logger = log.New(stdioWriter{s, 2}, log.Prefix(), log.Flags())
+		}
+		if name == "Default" {
+			ret = func() *log.Logger { return s.logger }
+		} else if mtd := r.ValueOf(s.logger).MethodByName(name); mtd.IsValid() && mtd.Type() == val.Type() {
+			return mtd
+		}
+	case "os":
+		switch name {
+		case "Stdin":
+			return r.ValueOf(&s.files[0]).Elem()
+		case "Stdout":
+			return r.ValueOf(&s.files[1]).Elem()
+		case "Stderr":
+			return r.ValueOf(&s.files[2]).Elem()
+		}
+	}
+	if ret == nil {
+		return val
+	}
+	return r.ValueOf(ret)
+}
+
+func (s *stdio) bindFmt(name string) interface{} {
+	out, in := stdioWriter{s, 1}, stdioReader{s}
+	switch name {
+	case "Print":
+		return func(a ...interface{}) (int, error) { return fmt.Fprint(out, a...) }
+	case "Printf":
+		return func(format string, a ...interface{}) (int, error) { return fmt.Fprintf(out, format, a...) }
+	case "Println":
+		return func(a ...interface{}) (int, error) { return fmt.Fprintln(out, a...) }
+	case "Scan":
+		return func(a ...interface{}) (int, error) { return fmt.Fscan(in, a...) }
+	case "Scanf":
+		return func(format string, a ...interface{}) (int, error) { return fmt.Fscanf(in, format, a...) }
+	case "Scanln":
+		return func(a ...interface{}) (int, error) { return fmt.Fscanln(in, a...) }
+	}
+	return nil
+}
+
+// stdinPump copies an io.Reader to the pipe seen by interpreted code as os.Stdin
+type stdinPump struct {
+	rd   *os.File // read end
+	w    *os.File // write end
+	done chan struct{}
+}
+
+func newStdinPump(in io.Reader) *stdinPump {
+	rd, w, err := os.Pipe()
+	if err != nil {
+		panic(err)
+	}
+	p := &stdinPump{rd: rd, w: w, done: make(chan struct{})}
+	go p.copy(in)
+	return p
+}
+
+func (p *stdinPump) copy(in io.Reader) {
+	defer p.w.Close()
+	buf := make([]byte, 4096)
+	for {
+		n, err := in.Read(buf)
+		select {
+		case <-p.done:
+			// closed while reading: data is discarded, nobody reads the pipe anymore
+			return
+		default:
+		}
+		if n > 0 {
+			if _, werr := p.w.Write(buf[:n]); werr != nil {
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// stop the pump: its goroutine exits as soon as the pending Read returns
+func (p *stdinPump) close() {
+	close(p.done)
+	// also unblocks a pending Write to a full pipe
+	p.rd.Close()
+}
+
+// stdioPipe forwards to an io.Writer what is written to an *os.File
+type stdioPipe struct {
+	w      *os.File // write end
+	lock   sync.Mutex
+	dst    io.Writer
+	mark   []byte // written by sync(), recognized and removed by forward()
+	synced chan struct{}
+	done   chan struct{} // closed when forward() returns
+}
+
+func newStdioPipe() *stdioPipe {
+	rd, w, err := os.Pipe()
+	if err != nil {
+		panic(err)
+	}
+	mark := make([]byte, 16)
+	rand.Read(mark)
+	p := &stdioPipe{w: w, mark: mark, synced: make(chan struct{}), done: make(chan struct{})}
+	go p.forward(rd)
+	return p
+}
+
+func (p *stdioPipe) setDst(dst io.Writer) {
+	p.lock.Lock()
+	p.dst = dst
+	p.lock.Unlock()
+}
+
+func (p *stdioPipe) write(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	p.lock.Lock()
+	dst := p.dst
+	p.lock.Unlock()
+	dst.Write(data)
+}
+
+// wait until what was written to the pipe is forwarded
+func (p *stdioPipe) sync() {
+	if _, err := p.w.Write(p.mark); err != nil {
+		// write end closed, possibly by interpreted code: forward() delivers what is left and returns
+		<-p.done
+		return
+	}
+	select {
+	case <-p.synced:
+	case <-p.done:
+	}
+}
+
+// return true if forward() returned
+func (p *stdioPipe) closed() bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// close the write end and wait until forward() delivers what is left and returns
+func (p *stdioPipe) close() {
+	p.w.Close()
+	<-p.done
+}
+
+func (p *stdioPipe) forward(rd *os.File) {
+	defer close(p.done)
+	buf := make([]byte, 4096)
+	var pending []byte
+	for {
+		n, err := rd.Read(buf)
+		data := append(pending, buf[:n]...)
+		for {
+			i := bytes.Index(data, p.mark)
+			if i < 0 {
+				break
+			}
+			p.write(data[:i])
+			data = data[i+len(p.mark):]
+			p.synced <- struct{}{}
+		}
+		// keep the bytes that may be the beginning of a mark
+		k := partialSuffix(data, p.mark)
+		p.write(data[:len(data)-k])
+		pending = append([]byte(nil), data[len(data)-k:]...)
+		if err != nil {
+			p.write(pending)
+			rd.Close()
+			return
+		}
+	}
+}
+
+// return the length of the longest suffix of data that is a proper prefix of mark
+func partialSuffix(data []byte, mark []byte) int {
+	n := len(mark) - 1
+	if n > len(data) {
+		n = len(data)
+	}
+	for ; n > 0; n-- {
+		if bytes.HasPrefix(mark, data[len(data)-n:]) {
+			return n
+		}
+	}
+	return 0
+}
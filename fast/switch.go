@@ -282,6 +282,9 @@ func (c *Comp) switchCase(node *ast.CaseClause, tagnode ast.Expr, tag *Expr, can
 		}
 	}
 	c.Append(stmt, node.Pos())
+	if c.Options&OptCoverage != 0 {
+		c.coverClause(node)
+	}
 	c.switchCaseBody(node.Body, canfallthrough)
 	// we finally know where to jump if match fails
 	iend = c.Code.Len()
@@ -299,6 +302,9 @@ func (c *Comp) switchDefault(node *ast.CaseClause, canfallthrough bool) {
 		env.IP = ip
 		return env.Code[ip], env
 	}, node.Pos())
+	if c.Options&OptCoverage != 0 {
+		c.coverClause(node)
+	}
 	c.switchCaseBody(node.Body, canfallthrough)
 	// we finally know where to jump if match fails
 	iend = c.Code.Len()
@@ -24,6 +24,7 @@ import (
 	"sort"
 	"unsafe"
 
+	"github.com/cosmos72/gomacro/base"
 	"github.com/cosmos72/gomacro/typeutil"
 	xr "github.com/cosmos72/gomacro/xreflect"
 )
@@ -407,6 +408,9 @@ func (c *Comp) typeswitchCase(node *ast.CaseClause, varname string, bind *Bind,
 	}
 	c.Pos = node.Pos()
 	c.append(stmt)
+	if c.Options&base.OptCoverage != 0 {
+		c.coverClause(node)
+	}
 	var t xr.Type
 	if len(ts) == 1 {
 		t = ts[0]
@@ -427,6 +431,9 @@ func (c *Comp) typeswitchDefault(node *ast.CaseClause, varname string, bind *Bin
 	}
 	c.Pos = node.Pos()
 	c.append(stmt)
+	if c.Options&base.OptCoverage != 0 {
+		c.coverClause(node)
+	}
 	c.typeswitchBody(node.Body, varname, nil, bind)
 	iend = c.Code.Len()
 }
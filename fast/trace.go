@@ -0,0 +1,264 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * trace.go
+ *
+ *  Created on Oct 19, 2026
+ *      Author agent
+ */
+
+package fast
+
+import (
+	"bytes"
+	"fmt"
+	"go/token"
+	"path"
+	r "reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/cosmos72/gomacro/base"
+	xr "github.com/cosmos72/gomacro/xreflect"
+)
+
+// tracer contains the functions and methods that can be traced,
+// and the patterns of the ones currently traced
+type tracer struct {
+	lock     sync.Mutex
+	funcs    map[string]*traceFunc // qualified name -> function
+	patterns []string
+}
+
+// traceFunc is a function or method that can be traced
+type traceFunc struct {
+	enabled uint32 // updated atomically
+	Name    string // qualified name, i.e. PKGNAME.FUNC or PKGNAME.TYPE.METHOD
+	Pos     string // FILE:LINE of function declaration
+	Param   []*Bind
+	Result  []*Bind
+}
+
+func (c *Comp) tracer() *tracer {
+	tr := c.CompGlobals.tracer
+	if tr == nil {
+		tr = &tracer{funcs: make(map[string]*traceFunc)}
+		c.CompGlobals.tracer = tr
+	}
+	return tr
+}
+
+// traceFuncBody wraps the body of a function, method or closure declared at pos:
+// calls and returns are printed if the function is traced with Interp.Trace(),
+// and pprof labels are applied if OptPprofLabels is set.
+// both can be enabled and disabled without recompiling the function.
+// closures, i.e. functions with empty name, cannot be traced
+// and their pprof label is "PKGNAME.func@FILE:LINE"
+func (c *Comp) traceFuncBody(name string, trecv xr.Type, pos token.Pos, info *FuncInfo, funcbody func(*Env)) func(*Env) {
+	if funcbody == nil {
+		return nil
+	}
+	closure := len(name) == 0
+	if closure {
+		name = "func"
+	} else if trecv != nil {
+		if trecv.Kind() == r.Ptr && !trecv.Named() {
+			trecv = trecv.Elem()
+		}
+		name = trecv.Name() + "." + name
+	}
+	if pkg := c.FileComp().Name; len(pkg) != 0 {
+		name = pkg + "." + name
+	}
+	f := &traceFunc{Name: name, Param: info.Param, Result: info.Result}
+	if c.Fileset != nil && pos != token.NoPos {
+		position := c.Fileset.Position(pos)
+		f.Pos = fmt.Sprintf("%s:%d", position.Filename, position.Line)
+	}
+	if closure && len(f.Pos) != 0 {
+		// distinguish closures in pprof labels, for example main.func@repl.go:3
+		f.Name += "@" + f.Pos
+	}
+	info.qualName = f.Name
+	if !closure {
+		tr := c.tracer()
+		tr.lock.Lock()
+		tr.funcs[name] = f
+		if tr.match(name) {
+			f.enabled = 1
+		}
+		tr.lock.Unlock()
+	}
+	return func(env *Env) {
+		if atomic.LoadUint32(&f.enabled) == 0 && env.Run.Options&OptPprofLabels == 0 {
+			funcbody(env)
+		} else {
+			f.call(env, funcbody)
+		}
+	}
+}
+
+// execute funcbody, applying pprof labels and/or printing the call and the return
+func (f *traceFunc) call(env *Env, funcbody func(*Env)) {
+	if env.Run.Options&OptPprofLabels != 0 {
+		f.label(env, funcbody)
+	} else {
+		f.trace(env, funcbody)
+	}
+}
+
+// execute funcbody, printing the call and the return if f is traced
+func (f *traceFunc) trace(env *Env, funcbody func(*Env)) {
+	if atomic.LoadUint32(&f.enabled) == 0 {
+		funcbody(env)
+		return
+	}
+	run := env.Run
+	indent := strings.Repeat("  ", env.CallDepth-1)
+	run.Fprintf(run.Stdout, "%s-> %s(%s)\n", indent, f.Name, traceArgs(env, f.Param, true))
+
+	start := time.Now()
+	returned := false
+	defer func() {
+		if !returned {
+			run.Fprintf(run.Stdout, "%s<- %s panicking [%v]\n", indent, f.Name, time.Since(start))
+		}
+	}()
+	funcbody(env)
+	returned = true
+
+	elapsed := time.Since(start)
+	switch len(f.Result) {
+	case 0:
+		run.Fprintf(run.Stdout, "%s<- %s [%v]\n", indent, f.Name, elapsed)
+	case 1:
+		run.Fprintf(run.Stdout, "%s<- %s = %s [%v]\n", indent, f.Name, traceArgs(env, f.Result, false), elapsed)
+	default:
+		run.Fprintf(run.Stdout, "%s<- %s = (%s) [%v]\n", indent, f.Name, traceArgs(env, f.Result, false), elapsed)
+	}
+}
+
+// format the current values of binds
+func traceArgs(env *Env, binds []*Bind, withNames bool) string {
+	var buf bytes.Buffer
+	for i, bind := range binds {
+		if i != 0 {
+			buf.WriteString(", ")
+		}
+		if withNames && len(bind.Name) != 0 {
+			buf.WriteString(bind.Name)
+			buf.WriteByte('=')
+		}
+		var value interface{} = "_"
+		if bind.Desc.Index() != NoIndex {
+			if v := bind.RuntimeValue(env); v.IsValid() && v.CanInterface() {
+				value = v.Interface()
+			}
+		}
+		fmt.Fprintf(&buf, "%v", value)
+	}
+	return buf.String()
+}
+
+// return true if qualified name matches one of the traced patterns.
+// tr.lock must be held
+func (tr *tracer) match(name string) bool {
+	for _, pattern := range tr.patterns {
+		if traceMatch(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// pattern can match either the qualified name PKGNAME.FUNC
+// or the unqualified name FUNC. the same applies to methods TYPE.METHOD
+func traceMatch(pattern string, name string) bool {
+	if ok, _ := path.Match(pattern, name); ok {
+		return true
+	}
+	if dot := strings.IndexByte(name, '.'); dot >= 0 {
+		ok, _ := path.Match(pattern, name[dot+1:])
+		return ok
+	}
+	return false
+}
+
+// enable or disable tracing of each function, according to current patterns.
+// tr.lock must be held
+func (tr *tracer) update() {
+	for name, f := range tr.funcs {
+		var enabled uint32
+		if tr.match(name) {
+			enabled = 1
+		}
+		atomic.StoreUint32(&f.enabled, enabled)
+	}
+}
+
+// Trace starts printing calls and returns of interpreted functions and methods
+// whose name matches pattern, which can be qualified as PKGNAME.FUNC or PKGNAME.TYPE.METHOD.
+// Pattern syntax is the same as path.Match, for example Trace("main.*") or Trace("fib")
+// Only functions and methods declared with 'func' are traced, not closures.
+// Functions declared later and matching pattern are traced too.
+func (ir *Interp) Trace(pattern string) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return err
+	}
+	tr := ir.Comp.tracer()
+	tr.lock.Lock()
+	defer tr.lock.Unlock()
+	for _, p := range tr.patterns {
+		if p == pattern {
+			return nil
+		}
+	}
+	tr.patterns = append(tr.patterns, pattern)
+	tr.update()
+	return nil
+}
+
+// Untrace stops tracing the functions and methods traced with Trace(pattern).
+// If pattern is empty, stops tracing all functions and methods.
+func (ir *Interp) Untrace(pattern string) {
+	tr := ir.Comp.tracer()
+	tr.lock.Lock()
+	defer tr.lock.Unlock()
+	if len(pattern) == 0 {
+		tr.patterns = nil
+	} else {
+		patterns := tr.patterns[:0]
+		for _, p := range tr.patterns {
+			if p != pattern {
+				patterns = append(patterns, p)
+			}
+		}
+		tr.patterns = patterns
+	}
+	tr.update()
+}
+
+// Traced returns the patterns currently traced, and the names of the functions and methods that match them
+func (ir *Interp) Traced() (patterns []string, names []string) {
+	tr := ir.Comp.tracer()
+	tr.lock.Lock()
+	defer tr.lock.Unlock()
+	patterns = append(patterns, tr.patterns...)
+	for name, f := range tr.funcs {
+		if atomic.LoadUint32(&f.enabled) != 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return patterns, names
+}
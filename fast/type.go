@@ -81,6 +81,7 @@ func (c *Comp) DeclTypeAlias(name string, t xr.Type) xr.Type {
 	} else if c.Types == nil {
 		c.Types = make(map[string]xr.Type)
 	}
+	c.undoType(name)
 	c.Types[name] = t
 	return t
 }
@@ -97,6 +98,7 @@ func (c *Comp) declTypeAlias(alias string, t xr.Type) xr.Type {
 	} else if c.Types == nil {
 		c.Types = make(map[string]xr.Type)
 	}
+	c.undoType(alias)
 	c.Types[alias] = t
 	return t
 }
@@ -123,6 +125,7 @@ func (c *Comp) DeclNamedType(name string) xr.Type {
 		c.Types = make(map[string]xr.Type)
 	}
 	t := c.Universe.NamedOf(name, c.FileComp().Path, r.Invalid /*kind not yet known*/)
+	c.undoType(name)
 	c.Types[name] = t
 	return t
 }
@@ -247,6 +250,10 @@ func (c *Comp) compileType2(node ast.Expr, allowEllipsis bool) (t xr.Type, ellip
 		name = node.Sel.Name
 		t, ok = imp.Types[name]
 		if !ok || t == nil {
+			if check := c.CompGlobals.constFunc; check != nil {
+				// report why a const func cannot use imp.NAME, instead of "not a type"
+				check.checkImport(c, imp)
+			}
 			c.Errorf("not a type: %v <%v>", node, r.TypeOf(node))
 		}
 		if !ast.IsExported(name) {
@@ -849,6 +856,7 @@ var (
 	rtypeOfBuiltin         = r.TypeOf(Builtin{})
 	rtypeOfFunction        = r.TypeOf(Function{})
 	rtypeOfMacro           = r.TypeOf(Macro{})
+	rtypeOfPtrConstFunc    = r.TypeOf((*ConstFunc)(nil))
 	rtypeOfPtrImport       = r.TypeOf((*Import)(nil))
 	rtypeOfPtrTemplateFunc = r.TypeOf((*TemplateFunc)(nil))
 	rtypeOfPtrTemplateType = r.TypeOf((*TemplateType)(nil))
@@ -870,6 +878,10 @@ func (g *CompGlobals) TypeOfMacro() xr.Type {
 	return g.Universe.ReflectTypes[rtypeOfMacro]
 }
 
+func (g *CompGlobals) TypeOfPtrConstFunc() xr.Type {
+	return g.Universe.ReflectTypes[rtypeOfPtrConstFunc]
+}
+
 func (g *CompGlobals) TypeOfPtrImport() xr.Type {
 	return g.Universe.ReflectTypes[rtypeOfPtrImport]
 }
@@ -34,7 +34,9 @@ func (c *Comp) UnaryExpr(node *ast.UnaryExpr) *Expr {
 		return c.exprValue(nil, node)
 
 	case mt.QUASIQUOTE:
-		return c.quasiquoteUnary(node)
+		return c.quasiquoteHygienic(node.X.(*ast.FuncLit).Body, func() *Expr {
+			return c.quasiquoteUnary(node)
+		})
 
 	case mt.UNQUOTE, mt.UNQUOTE_SPLICE:
 		c.Errorf("invalid %s outside %s: %v", mt.String(node.Op), mt.String(mt.QUASIQUOTE), node)
@@ -0,0 +1,550 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * z_test.go
+ *
+ *  Created on Oct 19, 2026
+ *      Author agent
+ */
+
+package fast
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime/pprof"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cosmos72/gomacro/base"
+	"github.com/cosmos72/gomacro/base/output"
+)
+
+func TestReplJSON(t *testing.T) {
+	ir := New()
+	var errbuf bytes.Buffer
+	ir.Comp.Stderr = &errbuf
+	input := strings.Join([]string{
+		`1 + 2`,
+		`println("hello")`,
+		`var json_x int; var json_x string`,
+		`json_undefined`,
+		`panic("boom")`,
+		`3`,
+	}, "\n") + "\n"
+	var out bytes.Buffer
+	ir.ReplJSON(bufio.NewReader(strings.NewReader(input)), &out)
+
+	var results []JSONResult
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var res JSONResult
+		if err := dec.Decode(&res); err != nil {
+			t.Fatalf("invalid JSON output: %v", err)
+		}
+		res.Elapsed = 0
+		results = append(results, res)
+	}
+	expect := []JSONResult{
+		{Id: 1, Values: []JSONValue{{Value: "3", Type: "int"}}},
+		{Id: 2, Values: []JSONValue{}, Stdout: "hello\n"},
+		{Id: 3, Values: []JSONValue{}, Warnings: []string{"redefined identifier: json_x"}},
+		{Id: 4, Values: []JSONValue{}, Error: &JSONError{Message: "undefined identifier: json_undefined", File: "repl.go", Line: 4, Column: 1}},
+		{Id: 5, Values: []JSONValue{}, Error: &JSONError{Message: "boom"}},
+		{Id: 6, Values: []JSONValue{{Value: "3", Type: "int"}}},
+	}
+	if !reflect.DeepEqual(results, expect) {
+		t.Errorf("expecting %+v, found %+v", expect, results)
+	}
+	if errbuf.Len() != 0 {
+		t.Errorf("unexpected output on interpreter Stderr: %q", errbuf.String())
+	}
+}
+
+// output written to os.Stdout and os.Stderr must not corrupt the JSON stream
+func TestReplJSONStdio(t *testing.T) {
+	ir := New()
+	input := strings.Join([]string{
+		`import ("fmt"; "os")`,
+		`fmt.Println("hi"); println("there"); fmt.Fprint(os.Stderr, "oops")`,
+		`os.Stdout.WriteString("raw\n")`,
+	}, "\n") + "\n"
+	var out bytes.Buffer
+	ir.ReplJSON(bufio.NewReader(strings.NewReader(input)), &out)
+
+	var results []JSONResult
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var res JSONResult
+		if err := dec.Decode(&res); err != nil {
+			t.Fatalf("invalid JSON output: %v", err)
+		}
+		results = append(results, res)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expecting 3 results, found %+v", results)
+	}
+	if res := results[1]; res.Stdout != "hi\nthere\n" || res.Stderr != "oops" {
+		t.Errorf("expecting Stdout %q and Stderr %q, found %+v", "hi\nthere\n", "oops", res)
+	}
+	if res := results[2]; res.Stdout != "raw\n" || res.Error != nil {
+		t.Errorf("expecting Stdout %q, found %+v", "raw\n", res)
+	}
+	if s := ir.Comp.CompGlobals.stdio; s.active {
+		t.Errorf("ReplJSON did not restore os.Stdout and os.Stderr")
+	}
+}
+
+type failWriter struct{}
+
+func (failWriter) Write([]byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestReplJSONWriteError(t *testing.T) {
+	ir := New()
+	var errbuf bytes.Buffer
+	ir.Comp.Stderr = &errbuf
+	ir.ReplJSON(bufio.NewReader(strings.NewReader("1\n2\n")), failWriter{})
+	if expect, actual := "// error writing JSON result: write failed\n", errbuf.String(); actual != expect {
+		t.Errorf("expecting %q, found %q", expect, actual)
+	}
+}
+
+func TestMakeJSONError(t *testing.T) {
+	tests := []struct {
+		rec    interface{}
+		expect JSONError
+	}{
+		{"boom", JSONError{Message: "boom"}},
+		{"repl.go:2:5: undefined identifier: x", JSONError{Message: "undefined identifier: x", File: "repl.go", Line: 2, Column: 5}},
+		{`C:\dir\a.go:3:1: syntax error`, JSONError{Message: "syntax error", File: `C:\dir\a.go`, Line: 3, Column: 1}},
+		{"a:b: not a position", JSONError{Message: "a:b: not a position"}},
+	}
+	for _, test := range tests {
+		if actual := makeJSONError(test.rec); *actual != test.expect {
+			t.Errorf("makeJSONError(%q): expecting %+v, found %+v", test.rec, test.expect, *actual)
+		}
+	}
+}
+
+func TestSplitWarnings(t *testing.T) {
+	out, warnings := splitWarnings("a\n// warning: w1\nb\n// warning: w2\n")
+	if out != "a\nb\n" || !reflect.DeepEqual(warnings, []string{"w1", "w2"}) {
+		t.Errorf("unexpected result %q %q", out, warnings)
+	}
+	out, warnings = splitWarnings("no warnings\n")
+	if out != "no warnings\n" || warnings != nil {
+		t.Errorf("unexpected result %q %q", out, warnings)
+	}
+}
+
+func TestImportMacros(t *testing.T) {
+	// macro libraries are found with go/build: create a package in a temporary GOPATH
+	gopath, err := ioutil.TempDir("", "gomacro_macrolib")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gopath)
+	path := "mlib"
+	dir := filepath.Join(gopath, "src", path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	saveGopath := build.Default.GOPATH
+	saveModule, hasModule := os.LookupEnv("GO111MODULE")
+	build.Default.GOPATH = gopath
+	os.Setenv("GO111MODULE", "off")
+	defer func() {
+		build.Default.GOPATH = saveGopath
+		if hasModule {
+			os.Setenv("GO111MODULE", saveModule)
+		} else {
+			os.Unsetenv("GO111MODULE")
+		}
+	}()
+	write := func(name string, src string, modtime int64) {
+		filename := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(filename, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+		// modification times may have a coarse resolution: set them explicitly
+		mtime := time.Unix(modtime, 0)
+		if err := os.Chtimes(filename, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("lib.gomacro", "package mlib\n\n~macro mlib_twice(x interface{}) interface{} { return ~\"{ ~,x * 2 } }\n", 1000)
+
+	ir := New()
+	var buf bytes.Buffer
+	ir.Comp.Stdout, ir.Comp.Stderr = &buf, &buf
+	eval := func(src string) interface{} {
+		v, _ := ir.Eval1(src)
+		return v.Interface()
+	}
+	eval(`import ~"` + path + `"`)
+	lib := ir.Comp.macroLibs[path]
+	if lib == nil {
+		t.Fatalf("macro library %q not cached", path)
+	}
+	if v := eval("mlib_twice; 3"); v != 6 {
+		t.Errorf("mlib_twice; 3 returned %v, expecting 6", v)
+	}
+
+	// library macros are shown by :env and completed
+	ir.ParseEvalPrint(":env")
+	if !strings.Contains(buf.String(), "mlib_twice") {
+		t.Errorf(":env does not show the library macro mlib_twice: %q", buf.String())
+	}
+	if _, completions, _ := ir.CompleteWords("mlib_tw", 7); !reflect.DeepEqual(completions, []string{"mlib_twice"}) {
+		t.Errorf("completing mlib_tw returned %q, expecting [mlib_twice]", completions)
+	}
+
+	// unchanged library is not evaluated again
+	eval(`import ~"` + path + `"`)
+	if ir.Comp.macroLibs[path] != lib {
+		t.Errorf("unchanged macro library %q was evaluated again", path)
+	}
+
+	// modified file makes the library stale
+	write("lib.gomacro", "package mlib\n\n~macro mlib_twice(x interface{}) interface{} { return ~\"{ ~,x * 3 } }\n", 2000)
+	eval(`import ~"` + path + `"`)
+	if ir.Comp.macroLibs[path] == lib {
+		t.Errorf("modified macro library %q was not evaluated again", path)
+	}
+	if v := eval("mlib_twice; 3"); v != 9 {
+		t.Errorf("mlib_twice; 3 returned %v after modifying the library, expecting 9", v)
+	}
+
+	// added file makes the library stale
+	lib = ir.Comp.macroLibs[path]
+	write("more.gomacro", "package mlib\n\n~macro mlib_neg(x interface{}) interface{} { return ~\"{ -~,x } }\n", 2000)
+	eval(`import ~"` + path + `"`)
+	if ir.Comp.macroLibs[path] == lib {
+		t.Errorf("macro library %q with an added file was not evaluated again", path)
+	}
+	if v := eval("mlib_neg; 3"); v != -3 {
+		t.Errorf("mlib_neg; 3 returned %v, expecting -3", v)
+	}
+}
+
+func TestPprofLabels(t *testing.T) {
+	ir := New()
+	// compiled before setting base.OptPprofLabels
+	ir.Eval(`func label_f(cb func()) { cb() }`)
+	ir.Eval(`func label_g(cb func()) {
+		func() { cb() }()
+	}`)
+	labels := func(fun string) (name string, pos string, host string) {
+		ir.ValueOf(fun).Interface().(func(func()))(func() {
+			ctx := ir.env.Run.labelCtx
+			if ctx == nil {
+				return
+			}
+			name, _ = pprof.Label(ctx, LabelFunc)
+			pos, _ = pprof.Label(ctx, LabelPos)
+			host, _ = pprof.Label(ctx, "host")
+		})
+		return name, pos, host
+	}
+	if name, _, _ := labels("label_f"); name != "" {
+		t.Errorf("unexpected label %s=%q without base.OptPprofLabels", LabelFunc, name)
+	}
+
+	ir.Comp.Options |= base.OptPprofLabels
+	defer func() {
+		ir.Comp.Options &^= base.OptPprofLabels
+	}()
+	pprof.Do(context.Background(), pprof.Labels("host", "test"), func(ctx context.Context) {
+		ir.SetLabelContext(ctx)
+	})
+	if name, pos, host := labels("label_f"); name != "main.label_f" || pos != "repl.go:1" || host != "test" {
+		t.Errorf("label_f: unexpected labels %q %q %q", name, pos, host)
+	}
+	if name, pos, _ := labels("label_g"); name != "main.func@repl.go:2" || pos != "repl.go:2" {
+		t.Errorf("closure in label_g: unexpected labels %q %q", name, pos)
+	}
+	// labels of the caller are restored on return
+	if ctx := ir.env.Run.labelCtx; ctx != nil {
+		if name, ok := pprof.Label(ctx, LabelFunc); ok {
+			t.Errorf("label %s=%q not removed on return", LabelFunc, name)
+		}
+	}
+}
+
+func TestProfileFuncNames(t *testing.T) {
+	ir := New()
+	ir.Comp.Options |= base.OptDebugger // needed to find function names
+	ir.Eval(`type prof_T struct{}`)
+	ir.Eval(`func (prof_T) method(cb func()) {
+		func() { cb() }()
+	}`)
+	ir.Eval(`func prof_f(cb func()) { var x prof_T; x.method(cb) }`)
+	var stack []string
+	ir.DeclVar("prof_cb", nil, func() {
+		for _, frame := range ir.env.Run.profileStack() {
+			stack = append(stack, frame.Func)
+		}
+	})
+	ir.Eval("prof_f(prof_cb)")
+	// same names shown by :trace and used in pprof labels
+	expect := []string{"main.func@repl.go:2", "main.prof_T.method", "main.prof_f", "(top-level)"}
+	if !reflect.DeepEqual(stack, expect) {
+		t.Errorf("expecting stack %q, found %q", expect, stack)
+	}
+}
+
+func TestStopProfileConcurrent(t *testing.T) {
+	ir := New()
+	var buf bytes.Buffer
+	if err := ir.StartProfile(&buf); err != nil {
+		t.Fatal(err)
+	}
+	errs := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			errs <- ir.StopProfile()
+		}()
+	}
+	nerr := 0
+	for i := 0; i < 3; i++ {
+		if err := <-errs; err != nil {
+			nerr++
+		}
+	}
+	if nerr != 2 || ir.IsProfiling() || buf.Len() == 0 {
+		t.Errorf("expecting exactly one successful StopProfile, found %d failed, IsProfiling() = %v, profile size = %d",
+			nerr, ir.IsProfiling(), buf.Len())
+	}
+	if err := ir.StartProfile(&buf); err != nil {
+		t.Errorf("cannot restart profiling: %v", err)
+	} else if err = ir.StopProfile(); err != nil {
+		t.Errorf("cannot stop restarted profiling: %v", err)
+	}
+}
+
+func TestSetStdio(t *testing.T) {
+	ir := New()
+	var out, errout bytes.Buffer
+	ir.SetStdio(strings.NewReader("7 abc\n"), &out, &errout)
+	ir.Eval(`import ("fmt"; "os")`)
+	ir.Eval(`fmt.Println("fmt"); os.Stdout.WriteString("os\n"); println("builtin"); fmt.Fprintln(os.Stderr, "err")`)
+	if got, want := out.String(), "fmt\nos\nbuiltin\n"; got != want {
+		t.Errorf("stdout: expecting %q, found %q", want, got)
+	}
+	if got, want := errout.String(), "err\n"; got != want {
+		t.Errorf("stderr: expecting %q, found %q", want, got)
+	}
+	vals, _ := ir.Eval(`var stdio_n int; var stdio_s string; fmt.Scan(&stdio_n, &stdio_s); fmt.Sprintf("%d %s", stdio_n, stdio_s)`)
+	if len(vals) != 1 || vals[0].String() != "7 abc" {
+		t.Errorf("stdin: expecting %q, found %v", "7 abc", vals)
+	}
+	ir.SetStdio(nil, nil, nil)
+	s := ir.Comp.CompGlobals.stdio
+	if s.stdin != nil || s.pipes != [3]*stdioPipe{} || s.active {
+		t.Errorf("SetStdio(nil, nil, nil) did not release pipes: %+v", s)
+	}
+}
+
+// interpreted code closing its os.Stdout must not hang the interpreter
+func TestSetStdioClose(t *testing.T) {
+	ir := New()
+	var out bytes.Buffer
+	ir.SetStdio(nil, &out, nil)
+	ir.Eval(`import "os"`)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ir.Eval(`os.Stdout.WriteString("bye\n"); os.Stdout.Close()`)
+		// output is redirected again to a new pipe
+		ir.SetStdio(nil, &out, nil)
+		ir.Eval(`os.Stdout.WriteString("again\n")`)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Eval hangs after interpreted code closed os.Stdout")
+	}
+	if got, want := out.String(), "bye\nagain\n"; got != want {
+		t.Errorf("expecting %q, found %q", want, got)
+	}
+	ir.SetStdio(nil, nil, nil)
+}
+
+type dumpHost struct {
+	Name string
+	when time.Time
+}
+
+// unexported fields of compiled types must be omitted, not rendered or accessed
+func TestGoLiteralUnexported(t *testing.T) {
+	ir := New()
+	tests := []struct {
+		val  interface{}
+		want string
+	}{
+		{dumpHost{"x", time.Now()}, `fast.dumpHost{
+	Name: "x",
+}`},
+		{*bytes.NewBufferString("hi"), `bytes.Buffer{}`},
+		{time.Date(2018, time.April, 20, 0, 0, 0, 0, time.UTC), `time.Date(2018, time.April, 20, 0, 0, 0, 0, time.UTC)`},
+	}
+	for _, test := range tests {
+		expr, fset := ir.GoLiteral(reflect.ValueOf(test.val), nil)
+		st := output.Stringer{Fileset: fset}
+		got := st.Sprintf("%v", expr)
+		if got != test.want {
+			t.Errorf("GoLiteral(%T): expecting %q, found %q", test.val, test.want, got)
+		}
+	}
+}
+
+const coverSource = `package main
+
+func covf(n int) int {
+	s := 0
+	for i := 0; i < n; i++ {
+		if i%2 == 0 {
+			s += i
+		}
+	}
+	switch n {
+	case 0:
+		return -1
+	default:
+		return s
+	}
+}
+`
+
+// check WriteCoverage output against the format read by 'go tool cover'
+func TestWriteCoverage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gomacro_cover")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "cover.gomacro")
+	if err := ioutil.WriteFile(file, []byte(coverSource), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ir := New()
+	ir.Comp.Options |= base.OptCoverage
+	if _, err := ir.EvalFile(file); err != nil {
+		t.Fatal(err)
+	}
+	ir.Comp.Options &^= base.OptCoverage
+	ir.Eval("covf(4)")
+
+	var buf bytes.Buffer
+	if err := ir.WriteCoverage(&buf); err != nil {
+		t.Fatal(err)
+	}
+	expect := "mode: count\n" + strings.Replace(`FILE:4.2,4.8 1 1
+FILE:5.2,5.24 1 1
+FILE:6.3,6.14 1 4
+FILE:7.4,7.10 1 2
+FILE:10.2,10.10 1 1
+FILE:11.2,11.9 1 0
+FILE:12.3,12.12 1 0
+FILE:13.2,13.10 1 1
+FILE:14.3,14.11 1 1
+`, "FILE", file, -1)
+	if actual := buf.String(); actual != expect {
+		t.Errorf("expecting:\n%s\nfound:\n%s", expect, actual)
+	}
+}
+
+const panicSource = `
+func panic_inner(n int) {
+	panic("boom")
+}
+func panic_outer() {
+	panic_inner(3)
+}
+panic_outer()
+`
+
+func TestPanicStackTrace(t *testing.T) {
+	ir := New()
+	ir.Comp.Options |= base.OptDebugger | base.OptPanicStackTrace
+	// let EvalReader return panics as errors
+	ir.Comp.Options &^= base.OptTrapPanic
+	_, err := ir.EvalReader(strings.NewReader(panicSource))
+	perr, ok := err.(*PanicError)
+	if !ok {
+		t.Fatalf("expecting *PanicError, found %T: %v", err, err)
+	}
+	if perr.Value != "boom" || perr.Error() != "boom" {
+		t.Errorf("expecting panic value %q, found %v", "boom", perr.Value)
+	}
+	stack := perr.Stack
+	if len(stack) != 2 {
+		t.Fatalf("expecting 2 stack frames, found %d:\n%v", len(stack), stack)
+	}
+	if f := stack[0]; f.Name != "panic_inner" || len(f.Args) != 1 || f.Args[0].Int() != 3 || f.Pos.Line != 3 {
+		t.Errorf("unexpected innermost stack frame: %v", f.String())
+	}
+	if f := stack[1]; f.Name != "panic_outer" || f.Pos.Line != 6 {
+		t.Errorf("unexpected outer stack frame: %v", f.String())
+	}
+	expect := "// interpreted stack trace, most recent call first:\npanic_inner(n=3 <int>)\n"
+	if s := stack.String(); !strings.HasPrefix(s, expect) {
+		t.Errorf("expecting stack trace to start with %q, found %q", expect, s)
+	}
+}
+
+// panicDebugger records the panics it is invoked on
+type panicDebugger struct {
+	stubDebugger
+	recs  []interface{}
+	stack StackTrace
+}
+
+func (d *panicDebugger) Panic(ir *Interp, env *Env, rec interface{}) DebugOp {
+	d.recs = append(d.recs, rec)
+	d.stack = env.StackTrace()
+	return DebugOpContinue
+}
+
+func TestPanicDebugger(t *testing.T) {
+	ir := New()
+	var d panicDebugger
+	ir.SetDebugger(&d)
+	// no OptPanicDebugger: the debugger must not be invoked
+	ir.Comp.Options |= base.OptDebugger
+	ir.Comp.Options &^= base.OptTrapPanic
+	ir.EvalReader(strings.NewReader(panicSource))
+	if len(d.recs) != 0 {
+		t.Errorf("debugger invoked on panic without OptPanicDebugger: %v", d.recs)
+	}
+	ir.Comp.Options |= base.OptPanicDebugger
+	_, err := ir.EvalReader(strings.NewReader("panic_outer()\n"))
+	if err == nil || err.Error() != "boom" {
+		t.Errorf("expecting panic to resume unwinding after the debugger, found %v", err)
+	}
+	if len(d.recs) != 1 || d.recs[0] != "boom" {
+		t.Fatalf("expecting the debugger to be invoked once on panic, found %v", d.recs)
+	}
+	// the debugger runs before the stack is unwound
+	if len(d.stack) != 2 || d.stack[0].Name != "panic_inner" || d.stack[1].Name != "panic_outer" {
+		t.Errorf("unexpected stack when entering the debugger:\n%v", d.stack)
+	}
+}
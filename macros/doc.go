@@ -0,0 +1,73 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * doc.go
+ *
+ *  Created on Oct 19, 2026
+ *      Author agent
+ */
+
+// Package macros is the standard macro library of gomacro.
+//
+// It is a macro library: its macros are written in the *.gomacro files of this directory,
+// and are loaded by the interpreter with
+//
+//	import ~"github.com/cosmos72/gomacro/macros"
+//
+// both at the REPL and when preprocessing files with gomacro -m -w.
+// As any other macro, they are invoked by writing the macro name followed by its arguments,
+// each one separated by ';' or by a newline. The expansion of each macro is ordinary Go code.
+//
+//	when; COND; { STATEMENTS }
+//		executes STATEMENTS if COND is true
+//
+//	unless; COND; { STATEMENTS }
+//		executes STATEMENTS if COND is false
+//
+//	assert; COND
+//		panics with the message "assertion failed: COND" if COND is false
+//
+//	must; CALL
+//	must; X, Y := CALL
+//	must; X, Y = CALL
+//		calls a function whose last result is an error, and panics if the error is not nil.
+//		The other results are assigned to X, Y ... while the form 'must; CALL'
+//		requires a function that returns only an error
+//
+//	try; CALL
+//	try; X, Y := CALL
+//	try; X, Y = CALL
+//		calls a function whose last result is an error, and returns early if the error is not nil.
+//		As for must, the form 'try; CALL' requires a function that returns only an error.
+//		The calling function must have named results, the last one being 'err error':
+//		the error is stored in err before returning
+//
+//	defer_err; MESSAGE
+//		when the calling function returns a non-nil error, prefixes it with MESSAGE + ": ".
+//		MESSAGE is a string expression, evaluated only if the error is not nil.
+//		The calling function must have named results, the last one being 'err error'
+//
+//	timeit; STATEMENT
+//		executes STATEMENT and prints how long it took
+//
+//	enum; NAME; { VALUE1; VALUE2; ... }
+//		declares the type NAME int and the constants VALUE1 = iota, VALUE2 ...
+//		plus the method NAME.String(), the function ParseNAME(string) (NAME, error)
+//		and the method (*NAME).Set(string) error, which implements flag.Value
+//
+//	foreach; X; { ELEMENT1; ELEMENT2; ... }; { STATEMENTS }
+//	foreach; X; []T{ELEMENT1, ELEMENT2, ...}; { STATEMENTS }
+//		inserts a copy of STATEMENTS for each ELEMENT, replacing the identifier X with ELEMENT.
+//		The elements can be any expression or type, and are substituted at compile time.
+//		The copies are inserted in the caller's scope, so they can declare types, variables and constants
+//
+// The expansions of defer_err, enum and timeit use the package "fmt",
+// and the expansion of timeit also uses the package "time": the caller must import them.
+package macros
@@ -20,6 +20,7 @@ import (
 	"os"
 
 	"github.com/cosmos72/gomacro/cmd"
+	"github.com/cosmos72/gomacro/fast"
 )
 
 func main() {
@@ -31,5 +32,8 @@ func main() {
 	if err != nil {
 		g := cmd.Interp.Comp.Globals
 		g.Fprintf(g.Stderr, "%s\n", err)
+		if perr, ok := err.(*fast.PanicError); ok {
+			g.Fprintf(g.Stderr, "%s", perr.Stack)
+		}
 	}
 }
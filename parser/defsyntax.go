@@ -0,0 +1,120 @@
+// Copyright 2009 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package parser implements a parser for Go source files. Input may be
+// provided in a variety of forms (see the various Parse* functions); the
+// output is an abstract syntax tree (AST) representing the Go source. The
+// parser is invoked through one of the Parse* functions.
+//
+// The parser accepts a larger language than is syntactically permitted by
+// the Go spec, for simplicity, and for improved robustness in the presence
+// of syntax errors. For instance, in method declarations, the receiver is
+// treated like an ordinary parameter list and thus may contain multiple
+// entries where the spec permits exactly one. Consequently, the corresponding
+// field in the AST (ast.FuncDecl.Recv) field is not restricted to one entry.
+package parser
+
+import (
+	"go/ast"
+	"go/token"
+
+	mt "github.com/cosmos72/gomacro/token"
+)
+
+// patch: parse a pattern-based macro declaration
+//
+//	~defsyntax NAME {
+//		~pattern { ITEM, ... } => { STATEMENTS }
+//		...
+//	}
+//
+// it is represented as a macro declaration, i.e. an *ast.FuncDecl with a zero-length receiver list,
+// whose body contains one *ast.CaseClause per ~pattern:
+// the clause List contains a single ~quote{ITEM; ...}
+// and the clause Body contains a single ~quasiquote{STATEMENTS}
+func (p *parser) parseDefSyntax() *ast.FuncDecl {
+	if p.trace {
+		defer un(trace(p, "DefSyntax"))
+	}
+	doc := p.leadComment
+	pos := p.expect(mt.DEFSYNTAX)
+	ident := p.parseIdent()
+
+	lbrace := p.expect(token.LBRACE)
+	var list []ast.Stmt
+	for p.tok == mt.PATTERN {
+		list = append(list, p.parsePatternClause())
+		if p.tok != token.RBRACE {
+			p.expectSemi()
+		}
+	}
+	if len(list) == 0 {
+		p.errorExpected(p.pos, "'"+mt.String(mt.PATTERN)+"'")
+	}
+	rbrace := p.expect(token.RBRACE)
+	p.expectSemi()
+
+	decl := &ast.FuncDecl{
+		Doc: doc,
+		// add zero-length receiver list, to mark decl as a macro
+		Recv: &ast.FieldList{List: []*ast.Field{}},
+		Name: ident,
+		Type: &ast.FuncType{
+			Func:   pos,
+			Params: &ast.FieldList{},
+		},
+		Body: &ast.BlockStmt{Lbrace: lbrace, List: list, Rbrace: rbrace},
+	}
+	p.declare(decl, nil, p.pkgScope, ast.Fun, ident)
+	return decl
+}
+
+// parse ~pattern { ITEM, ... } => { STATEMENTS }
+// each ITEM is either an expression or a block { STATEMENTS }
+func (p *parser) parsePatternClause() *ast.CaseClause {
+	if p.trace {
+		defer un(trace(p, "PatternClause"))
+	}
+	// metavariables are scanned only inside ~pattern { ITEM, ... } => { STATEMENTS }.
+	// the scanner is one token ahead of the parser: enable them before consuming ~pattern
+	metavars := p.scanner.MetaVariables
+	p.scanner.MetaVariables = true
+	defer func() {
+		p.scanner.MetaVariables = metavars
+	}()
+	pos := p.expect(mt.PATTERN)
+	lbrace := p.expect(token.LBRACE)
+	var items []ast.Stmt
+	for p.tok != token.RBRACE && p.tok != token.EOF {
+		if p.tok == token.LBRACE {
+			items = append(items, p.parseBlockStmtQuoted())
+		} else {
+			items = append(items, &ast.ExprStmt{X: p.parseRhs()})
+		}
+		if p.tok != token.COMMA {
+			break
+		}
+		p.next()
+	}
+	rbrace := p.expect(token.RBRACE)
+
+	// '=>' is scanned as '=' followed by '>': they must be adjacent
+	arrow := p.expect(token.ASSIGN)
+	if p.tok == token.GTR && p.pos != arrow+1 {
+		p.error(arrow, "expecting '=>', found '= >'")
+	}
+	p.expect(token.GTR)
+	body := p.parseBlockStmtQuoted()
+
+	// quote the items: they must not be macroexpanded
+	pattern, _ := MakeQuote(p, mt.QUOTE, pos, &ast.BlockStmt{Lbrace: lbrace, List: items, Rbrace: rbrace})
+	template, _ := MakeQuote(p, mt.QUASIQUOTE, arrow, body)
+
+	return &ast.CaseClause{
+		Case:  pos,
+		List:  []ast.Expr{pattern},
+		Colon: arrow,
+		Body:  []ast.Stmt{&ast.ExprStmt{X: template}},
+	}
+}
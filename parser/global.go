@@ -28,6 +28,7 @@ const (
 	DeclarationErrors                              // report declaration errors
 	SpuriousErrors                                 // same as AllErrors, for backward-compatibility
 	CopySources                                    // copy source code to FileSet
+	ParseMetaVars                                  // parse metavariables $name, $name:kind and $name... everywhere, as patterns do
 	AllErrors         = SpuriousErrors             // report all errors (not just the first 10 on different lines)
 
 )
@@ -45,6 +46,12 @@ func (p *parser) Init(fileset *mt.FileSet, filename string, lineOffset int, src
 	p.init(fileset, filename, lineOffset, src, p.mode)
 }
 
+// Comments returns the comments found by the last call to Parse.
+// They are collected only if the parser mode contains ParseComments
+func (p *parser) Comments() []*ast.CommentGroup {
+	return p.comments
+}
+
 func (p *parser) Parse() (list []ast.Node, err error) {
 	if p.file == nil || p.pkgScope == nil {
 		panic("Parser.Parse(): parser is not initialized, call Parser.Init() first")
@@ -100,7 +107,7 @@ func (p *parser) parseAny() ast.Node {
 		node = p.parsePackage()
 	case token.IMPORT:
 		node = p.parseGenDecl(token.IMPORT, p.parseImportSpec)
-	case token.CONST, token.TYPE, token.VAR, token.FUNC, mt.MACRO, mt.FUNCTION, mt.TEMPLATE:
+	case token.CONST, token.TYPE, token.VAR, token.FUNC, mt.MACRO, mt.DEFSYNTAX, mt.FUNCTION, mt.TEMPLATE:
 		// a "func" at top level can be either a function declaration: func foo(args) /*...*/
 		// or a method declaration: func (receiver) foo(args) /*...*/
 		// or a function literal, i.e. a closure: func(args) /*...*/
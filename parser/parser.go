@@ -69,6 +69,8 @@ type parser struct {
 	unresolved []*ast.Ident      // unresolved identifiers
 	imports    []*ast.ImportSpec // list of imports
 
+	macroImports bool // patch: parsing an import declaration marked //gomacro:macros
+
 	// Label scopes
 	// (maintained by open/close LabelScope)
 	labelScope  *ast.Scope     // label scope for current function
@@ -95,6 +97,7 @@ func (p *parser) init(fset *mt.FileSet, filename string, lineOffset int, src []b
 	}
 	eh := func(pos token.Position, msg string) { p.errors.Add(pos, msg) }
 	p.scanner.Init(p.file, src, eh, m, p.macroChar)
+	p.scanner.MetaVariables = mode&ParseMetaVars != 0
 
 	p.mode = mode
 	p.trace = mode&Trace != 0 // for convenience (p.trace is used frequently)
@@ -2349,6 +2352,18 @@ func (p *parser) parseImportSpec(doc *ast.CommentGroup, _ token.Token, _ int) as
 		p.next()
 	case token.IDENT:
 		ident = p.parseIdent()
+		if p.tok == mt.MACRO {
+			p.error(p.pos, "cannot rename a macro import")
+			p.next()
+		}
+	case mt.MACRO:
+		// patch: macro import ~"path" or ~macro "path"
+		ident = &ast.Ident{NamePos: p.pos, Name: mt.MACRO.String()}
+		p.next()
+	}
+	if ident == nil && (p.macroImports || p.tok == token.STRING && p.scanner.MacroDirective) {
+		// patch: macro import marked //gomacro:macros
+		ident = &ast.Ident{NamePos: p.pos, Name: mt.MACRO.String()}
 	}
 
 	pos := p.pos
@@ -2454,10 +2469,21 @@ func (p *parser) parseGenDecl(keyword token.Token, f parseSpecFunction) *ast.Gen
 	}
 
 	doc := p.leadComment
+	if keyword == token.IMPORT {
+		// patch: //gomacro:macros before 'import' marks all its specs as macro imports
+		saved := p.macroImports
+		p.macroImports = p.tok == token.IMPORT && p.scanner.MacroDirective
+		defer func() {
+			p.macroImports = saved
+		}()
+	}
 	pos := p.expect(keyword)
 	var lparen, rparen token.Pos
 	var list []ast.Spec
-	if p.tok == token.LPAREN {
+	if keyword == token.CONST && p.tok == token.FUNC {
+		// patch: parse const func declaration
+		list = append(list, p.parseConstFuncSpec())
+	} else if p.tok == token.LPAREN {
 		lparen = p.pos
 		p.next()
 		for iota := 0; p.tok != token.RPAREN && p.tok != token.EOF; iota++ {
@@ -2479,6 +2505,23 @@ func (p *parser) parseGenDecl(keyword token.Token, f parseSpecFunction) *ast.Gen
 	}
 }
 
+// patch: parse const func NAME(PARAMS) RESULT { BODY }
+// as the constant declaration const NAME = func(PARAMS) RESULT { BODY }
+// which is not valid Go, thus cannot be confused with anything else
+func (p *parser) parseConstFuncSpec() *ast.ValueSpec {
+	if p.trace {
+		defer un(trace(p, "ConstFuncSpec"))
+	}
+	decl := p.parseFuncOrMacroDecl(token.FUNC)
+	if decl.Recv != nil {
+		p.error(decl.Recv.Pos(), "const func cannot have a receiver")
+	}
+	return &ast.ValueSpec{
+		Names:  []*ast.Ident{decl.Name},
+		Values: []ast.Expr{&ast.FuncLit{Type: decl.Type, Body: decl.Body}},
+	}
+}
+
 func (p *parser) parseFuncDecl(tok token.Token) *ast.FuncDecl {
 	if p.trace {
 		defer un(trace(p, "FunctionDecl"))
@@ -2572,6 +2615,9 @@ func (p *parser) parseDecl(sync func(*parser)) ast.Decl {
 	case mt.MACRO: // patch: parse a macro declaration
 		return p.parseMacroDecl()
 
+	case mt.DEFSYNTAX: // patch: parse a pattern-based macro declaration
+		return p.parseDefSyntax()
+
 	case mt.TEMPLATE:
 		return p.parseTemplateDecl(sync)
 
@@ -48,10 +48,30 @@ type Scanner struct {
 	lineOffset int  // current line offset
 	insertSemi bool // insert a semicolon before next newline
 
+	// patch: support macro imports
+	importing importState // position inside an import declaration
+	directive bool        // a //gomacro:macros comment was found after the previous token
+
 	// public state - ok to modify
 	ErrorCount int // number of errors encountered
+
+	// patch: true if the last scanned token is preceded by a //gomacro:macros comment
+	MacroDirective bool
+
+	// patch: if true, scan the metavariables $name, $name:kind and $name... used by patterns.
+	// ok to modify while scanning
+	MetaVariables bool
 }
 
+// patch: support macro imports
+type importState uint8
+
+const (
+	importNone  importState = iota
+	importSpec              // after 'import'
+	importGroup             // inside 'import ( ... )'
+)
+
 const bom = 0xFEFF // byte order mark, only permitted as very first character
 
 // Read the next Unicode char into s.ch.
@@ -131,7 +151,11 @@ func (s *Scanner) Init(file *mt.File, src []byte, err ErrorHandler, mode Mode, m
 	s.rdOffset = 0
 	s.lineOffset = 0
 	s.insertSemi = false
+	s.importing = importNone
+	s.directive = false
 	s.ErrorCount = 0
+	s.MacroDirective = false
+	s.MetaVariables = false
 
 	s.next()
 	if s.ch == bom {
@@ -148,6 +172,9 @@ func (s *Scanner) error(offs int, msg string) {
 
 var prefix = []byte("//line ")
 
+// patch: directive that marks an import declaration or spec as a macro library import
+var macrosDirective = []byte("//gomacro:macros")
+
 func (s *Scanner) interpretLineComment(text []byte) {
 	if bytes.HasPrefix(text, prefix) {
 		// get filename and line number, if any
@@ -187,6 +214,9 @@ func (s *Scanner) scanComment() string {
 			// comment starts at the beginning of the current line
 			s.interpretLineComment(s.src[offs:s.offset])
 		}
+		if text := bytes.TrimSpace(s.src[offs:s.offset]); bytes.Equal(text, macrosDirective) {
+			s.directive = true
+		}
 		goto exit
 	}
 
@@ -275,6 +305,37 @@ func (s *Scanner) scanIdentifier() string {
 	return string(s.src[offs:s.offset])
 }
 
+// kinds of metavariables accepted by ~defsyntax: $name:kind
+var metaKinds = []string{"expr", "ident", "stmt", "type"}
+
+// patch: scan a metavariable $name, $name:kind or $name... used by patterns.
+// initial '$' already consumed
+func (s *Scanner) scanMetaVariable(offs int) string {
+	s.scanIdentifier()
+	if s.ch == ':' {
+		rest := s.src[s.rdOffset:]
+		for _, kind := range metaKinds {
+			if !bytes.HasPrefix(rest, []byte(kind)) {
+				continue
+			}
+			if len(rest) > len(kind) {
+				if ch, _ := utf8.DecodeRune(rest[len(kind):]); isLetter(ch) || isDigit(ch) {
+					continue
+				}
+			}
+			s.next() // consume ':'
+			s.scanIdentifier()
+			break
+		}
+	}
+	if bytes.HasPrefix(s.src[s.offset:], []byte("...")) {
+		s.next()
+		s.next()
+		s.next()
+	}
+	return string(s.src[offs:s.offset])
+}
+
 func digitVal(ch rune) int {
 	switch {
 	case '0' <= ch && ch <= '9':
@@ -605,6 +666,26 @@ func (s *Scanner) switch4(tok0, tok1 token.Token, ch2 rune, tok2, tok3 token.Tok
 // and thus relative to the file set.
 //
 func (s *Scanner) Scan() (pos token.Pos, tok token.Token, lit string) {
+	pos, tok, lit = s.scan()
+	// patch: support macro imports
+	if tok != token.COMMENT {
+		s.MacroDirective = s.directive
+		s.directive = false
+	}
+	switch {
+	case tok == token.IMPORT:
+		s.importing = importSpec
+	case s.importing == importSpec && tok == token.LPAREN:
+		s.importing = importGroup
+	case s.importing == importSpec && tok == token.SEMICOLON,
+		s.importing == importGroup && tok == token.RPAREN,
+		tok == token.EOF:
+		s.importing = importNone
+	}
+	return pos, tok, lit
+}
+
+func (s *Scanner) scan() (pos token.Pos, tok token.Token, lit string) {
 scanAgain:
 	s.skipWhitespace()
 
@@ -768,6 +849,22 @@ scanAgain:
 		case '@':
 			// patch: support macro, quote and friends
 			tok = mt.SPLICE
+		case '$':
+			// patch: support metavariables $name, $name:kind and $name... used by patterns
+			if s.MetaVariables && isLetter(s.ch) {
+				insertSemi = true
+				tok = token.IDENT
+				lit = s.scanMetaVariable(s.file.Offset(pos))
+				break
+			}
+			if s.MetaVariables {
+				s.error(s.file.Offset(pos), "expecting metavariable name after '$'")
+			} else {
+				s.error(s.file.Offset(pos), fmt.Sprintf("illegal character %#U", ch))
+			}
+			insertSemi = s.insertSemi // preserve insertSemi info
+			tok = token.ILLEGAL
+			lit = "$"
 		case s.macroChar:
 			// patch: support macro, quote and friends. s.macroChar is configurable, default is '~'
 			// quote           macroChar '
@@ -778,7 +875,14 @@ scanAgain:
 			case '\'':
 				s.next()
 				tok = mt.QUOTE
-			case '`', '"': // accept both ~` and ~" as ~quasiquote, because ~` confuses syntax hilighting in IDEs
+			case '"':
+				if s.importing != importNone {
+					// macro import: ~"path" is the same as ~macro "path". do not consume '"'
+					tok = mt.MACRO
+					break
+				}
+				fallthrough
+			case '`': // accept both ~` and ~" as ~quasiquote, because ~` confuses syntax hilighting in IDEs
 				s.next()
 				tok = mt.QUASIQUOTE
 			case ',':
@@ -6,6 +6,7 @@ package token
 
 import (
 	"go/token"
+	"strings"
 	"sync"
 )
 
@@ -60,6 +61,32 @@ func (f *File) Source(p token.Pos) (line string, pos token.Position) {
 	return "", pos
 }
 
+// SourceRange returns the source code between the file positions pos and end, if available.
+//
+func (f *File) SourceRange(pos, end token.Pos) (string, bool) {
+	if pos == token.NoPos || end <= pos || int(end) > f.Base()+f.Size() {
+		return "", false
+	}
+	// ignore //line comments and the starting line offset: we need the line and column in f.source
+	start, stop := f.File.PositionFor(pos, false), f.File.PositionFor(end, false)
+	f.mutex.Lock()
+	source := f.source
+	f.mutex.Unlock()
+	if start.Line <= 0 || stop.Line > len(source) || stop.Line < start.Line {
+		return "", false
+	}
+	lines := make([]string, stop.Line-start.Line+1)
+	copy(lines, source[start.Line-1:stop.Line])
+	last := len(lines) - 1
+	if col := stop.Column - 1; col <= len(lines[last]) {
+		lines[last] = lines[last][:col]
+	}
+	if col := start.Column - 1; col <= len(lines[0]) {
+		lines[0] = lines[0][col:]
+	}
+	return strings.Join(lines, "\n"), true
+}
+
 // SetSource sets the source code for the given file.
 //
 func (f *File) SetSource(source []string) {
@@ -95,7 +122,18 @@ func (f *File) SetSourceForContent(content []byte) {
 //
 type FileSet struct {
 	token.FileSet
-	filemap map[*token.File]*File
+	filemap    map[*token.File]*File
+	expansions map[token.Pos]*Expansion
+}
+
+// Expansion describes a macro expansion.
+// The code generated by a macro expansion has the position of the macro call,
+// except for the macro arguments, which keep their original position
+//
+type Expansion struct {
+	Macro string    // name of the expanded macro
+	Call  token.Pos // position of the macro call
+	Def   token.Pos // position of the macro definition, or NoPos if unknown
 }
 
 // NewFileSet creates a new file set.
@@ -145,6 +183,15 @@ func (s *FileSet) Position(p token.Pos) (pos token.Position) {
 	return s.PositionFor(p, true)
 }
 
+// SourceRange returns the source code between the positions pos and end, if available.
+//
+func (s *FileSet) SourceRange(pos, end token.Pos) (string, bool) {
+	if f := s.File(pos); f != nil {
+		return f.SourceRange(pos, end)
+	}
+	return "", false
+}
+
 // Source converts a Pos p in the fileset into a line of source code (if available) and a Position value.
 //
 func (s *FileSet) Source(p token.Pos) (line string, pos token.Position) {
@@ -153,3 +200,43 @@ func (s *FileSet) Source(p token.Pos) (line string, pos token.Position) {
 	}
 	return
 }
+
+// AddExpansion records the macro expansion e.
+// If the code at e.Call was already generated by another expansion, e is ignored:
+// only the outermost expansion, i.e. the one written in the source code, is kept
+//
+func (s *FileSet) AddExpansion(e *Expansion) {
+	if e.Call == token.NoPos {
+		return
+	}
+	if s.expansions == nil {
+		s.expansions = make(map[token.Pos]*Expansion)
+	} else if s.expansions[e.Call] != nil {
+		return
+	}
+	s.expansions[e.Call] = e
+}
+
+// Expansion returns the macro expansion that generated the code at position p, or nil if none.
+//
+func (s *FileSet) Expansion(p token.Pos) *Expansion {
+	if s == nil || p == token.NoPos {
+		return nil
+	}
+	return s.expansions[p]
+}
+
+// ExpansionString describes the macro expansion that generated the code at position p,
+// as "in expansion of macro NAME at FILE:LINE:COLUMN". returns "" if no expansion generated it.
+//
+func (s *FileSet) ExpansionString(p token.Pos) string {
+	e := s.Expansion(p)
+	if e == nil {
+		return ""
+	}
+	str := "in expansion of macro " + e.Macro + " at " + s.Position(e.Call).String()
+	if def := s.Position(e.Def); def.IsValid() {
+		str += ", defined at " + def.String()
+	}
+	return str
+}
@@ -24,6 +24,8 @@ const (
 	TYPECASE
 	TEMPLATE // template
 	HASH     // #
+	DEFSYNTAX
+	PATTERN
 )
 
 var tokens map[base.Token]string
@@ -41,6 +43,8 @@ func init() {
 		FUNCTION:       "~func",
 		LAMBDA:         "~lambda",
 		TYPECASE:       "~typecase",
+		DEFSYNTAX:      "~defsyntax",
+		PATTERN:        "~pattern",
 	}
 
 	keywords = make(map[string]base.Token)
@@ -13,6 +13,7 @@ import (
 func init() {
 	imports.Packages["github.com/cosmos72/gomacro/token"] = imports.Package{
 		Binds: map[string]r.Value{
+			"DEFSYNTAX":      r.ValueOf(DEFSYNTAX),
 			"FUNCTION":       r.ValueOf(FUNCTION),
 			"IsKeyword":      r.ValueOf(IsKeyword),
 			"IsLiteral":      r.ValueOf(IsLiteral),
@@ -23,6 +24,7 @@ func init() {
 			"LookupSpecial":  r.ValueOf(LookupSpecial),
 			"MACRO":          r.ValueOf(MACRO),
 			"NewFileSet":     r.ValueOf(NewFileSet),
+			"PATTERN":        r.ValueOf(PATTERN),
 			"QUASIQUOTE":     r.ValueOf(QUASIQUOTE),
 			"QUOTE":          r.ValueOf(QUOTE),
 			"SPLICE":         r.ValueOf(SPLICE),
@@ -32,8 +34,9 @@ func init() {
 			"UNQUOTE_SPLICE": r.ValueOf(UNQUOTE_SPLICE),
 		},
 		Types: map[string]r.Type{
-			"File":    r.TypeOf((*File)(nil)).Elem(),
-			"FileSet": r.TypeOf((*FileSet)(nil)).Elem(),
+			"Expansion": r.TypeOf((*Expansion)(nil)).Elem(),
+			"File":      r.TypeOf((*File)(nil)).Elem(),
+			"FileSet":   r.TypeOf((*FileSet)(nil)).Elem(),
 		},
 		Proxies: map[string]r.Type{}}
 }